@@ -189,7 +189,7 @@ export function StepAccount() {
             </p>
           )}
           <p className="text-xs text-muted-foreground">
-            Must contain uppercase, lowercase, number, and special character
+            At least 8 characters. A few uncommon words make a strong password
           </p>
         </div>
 
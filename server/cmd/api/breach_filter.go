@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"tyk-registration-server/internal/validator"
+)
+
+// buildBreachFilter converts a downloaded HIBP corpus (one "HASH:COUNT" file, or a directory
+// of range files) into the bloom filter BREACHED_PASSWORDS_PATH can point at
+func buildBreachFilter(args []string) error {
+	fs := flag.NewFlagSet("build-breach-filter", flag.ContinueOnError)
+	in := fs.String("in", "", "HIBP SHA-1 hash file or range directory")
+	out := fs.String("out", "", "bloom filter file to write")
+	fpRate := fs.Float64("fp-rate", 0.001, "target false positive rate")
+	minCount := fs.Int("min-count", 1, "skip passwords seen fewer times than this")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" || *out == "" {
+		fs.Usage()
+		return errors.New("-in and -out are required")
+	}
+
+	// The filter is sized up front, so count the entries in a first pass
+	var n uint64
+	if err := validator.ForEachBreachHash(*in, *minCount, func(validator.BreachHash) error {
+		n++
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to read %s: %w", *in, err)
+	}
+
+	filter, err := validator.NewBloomFilter(n, *fpRate)
+	if err != nil {
+		return err
+	}
+	if err := validator.ForEachBreachHash(*in, *minCount, func(hash validator.BreachHash) error {
+		filter.Add(hash)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to read %s: %w", *in, err)
+	}
+
+	// Write next to the target and rename, so a running server never reads a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(*out), ".breach-filter-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	size, err := filter.WriteTo(w)
+	if err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", *out, err)
+	}
+	if err := os.Rename(tmp.Name(), *out); err != nil {
+		return err
+	}
+
+	log.Printf("wrote %s: %d passwords, %d bytes", *out, n, size)
+	return nil
+}
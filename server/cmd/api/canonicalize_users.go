@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"tyk-registration-server/internal/config"
+	"tyk-registration-server/internal/db"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/pii"
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/internal/utils"
+)
+
+// canonicalizeUsers recomputes the canonical email, username and username skeleton of every
+// account with the application's rules. Run it after migrating a database whose canonical
+// forms were backfilled in SQL, and after changing EMAIL_PROVIDERS_FILE or
+// EMAIL_FOLD_LOCAL_CASE. Accounts whose new form another account already holds are left
+// unchanged and listed; the command then fails so they can be resolved by hand and it can
+// be re-run.
+func canonicalizeUsers(args []string) error {
+	fs := flag.NewFlagSet("canonicalize-users", flag.ContinueOnError)
+	batch := fs.Int("batch", 500, "accounts to read per query")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *batch < 1 {
+		return errors.New("-batch must be positive")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := db.RunMigrations(cfg.DSN); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	pool, err := db.NewPool(cfg.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+	cipher, err := pii.Load(ctx, cfg.PIIMasterKeyFile, repositories.NewPIIKeyRepository(pool))
+	if err != nil {
+		return err
+	}
+	users, err := userRepository(cfg, pool, cipher)
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	var collisions []models.CanonicalCollision
+	for after := uuid.Nil; ; {
+		last, n, c, err := users.CanonicalizeUsers(ctx, after, *batch)
+		total += n
+		collisions = append(collisions, c...)
+		if err != nil {
+			return fmt.Errorf("stopped after updating %d users: %w", total, err)
+		}
+		if last == uuid.Nil {
+			break
+		}
+		after = last
+	}
+	log.Printf("done: updated %d users", total)
+
+	if len(collisions) > 0 {
+		lines := make([]string, len(collisions))
+		for i, c := range collisions {
+			lines[i] = fmt.Sprintf("%s (%s)", c.UserID, c.Field)
+		}
+		return fmt.Errorf("%d users collide with another account and were left unchanged: %s",
+			len(collisions), strings.Join(lines, ", "))
+	}
+	return nil
+}
+
+// userRepository builds the user repository the server uses, for maintenance commands
+func userRepository(cfg *config.Config, pool *pgxpool.Pool, cipher *pii.Cipher) (repositories.UserRepository, error) {
+	emailRules, err := utils.LoadEmailRules(cfg.EmailProvidersFile, cfg.EmailFoldLocalCase)
+	if err != nil {
+		return nil, err
+	}
+	return repositories.NewUserRepository(pool, cipher, repositories.UserRepositoryOptions{
+		ReserveDeleted: cfg.DeletedIdentifiers == models.DeletedIdentifiersReserve,
+		EmailRules:     emailRules,
+	}), nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+
+	"tyk-registration-server/internal/config"
+	"tyk-registration-server/internal/db"
+	"tyk-registration-server/internal/pii"
+	"tyk-registration-server/internal/repositories"
+)
+
+// encryptPII encrypts the PII of users, and the webhook signing secrets, stored before
+// PII_MASTER_KEY_FILE was set. It can run while the server is up and be re-run after an
+// interruption; rows already encrypted are skipped.
+func encryptPII(args []string) error {
+	fs := flag.NewFlagSet("encrypt-pii", flag.ContinueOnError)
+	batch := fs.Int("batch", 500, "rows to encrypt per transaction")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *batch < 1 {
+		return errors.New("-batch must be positive")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.PIIMasterKeyFile == "" {
+		return errors.New("PII_MASTER_KEY_FILE is not set")
+	}
+	if err := db.RunMigrations(cfg.DSN); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	pool, err := db.NewPool(cfg.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+	cipher, err := pii.Load(ctx, cfg.PIIMasterKeyFile, repositories.NewPIIKeyRepository(pool))
+	if err != nil {
+		return err
+	}
+	users, err := userRepository(cfg, pool, cipher)
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for {
+		n, err := users.EncryptPlaintextUsers(ctx, *batch)
+		if err != nil {
+			return fmt.Errorf("stopped after encrypting %d users: %w", total, err)
+		}
+		if n == 0 {
+			break
+		}
+		total += n
+		log.Printf("encrypted %d users", total)
+	}
+	log.Printf("done: encrypted %d users", total)
+
+	webhooks := repositories.NewWebhookRepository(pool, cipher)
+	secrets := 0
+	for {
+		n, err := webhooks.EncryptPlaintextSecrets(ctx, *batch)
+		if err != nil {
+			return fmt.Errorf("stopped after encrypting %d webhook secrets: %w", secrets, err)
+		}
+		if n == 0 {
+			break
+		}
+		secrets += n
+	}
+	log.Printf("done: encrypted %d webhook secrets", secrets)
+	return nil
+}
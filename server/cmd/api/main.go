@@ -1,14 +1,40 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 
 	"tyk-registration-server/internal/config"
 	"tyk-registration-server/internal/db"
 	"tyk-registration-server/internal/router"
 )
 
+// commands are maintenance subcommands run as `api <command> [flags]` instead of the server
+var commands = map[string]func(args []string) error{
+	"build-breach-filter": buildBreachFilter,
+	"canonicalize-users":  canonicalizeUsers,
+	"encrypt-pii":         encryptPII,
+	"normalize-phones":    normalizePhones,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		command, ok := commands[os.Args[1]]
+		if !ok {
+			log.Fatalf("unknown command %q", os.Args[1])
+		}
+		if err := command(os.Args[2:]); err != nil {
+			log.Fatalf("%s: %v", os.Args[1], err)
+		}
+		return
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
@@ -19,9 +45,69 @@ func main() {
 		log.Fatalf("failed to run migrations: %v", err)
 	}
 
-	app := router.New(cfg)
+	srv := router.NewServer(cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	if err := app.Listen(":" + cfg.Port); err != nil {
+	// Background workers stop when ctx is cancelled
+	go srv.Dispatcher.Run(ctx)
+	go purgeExpired(ctx, srv.Pool, "registration drafts", srv.Drafts.PurgeExpired, time.Hour)
+	go purgeExpired(ctx, srv.Pool, "idempotency keys", srv.IdempotencyKeys.DeleteExpired, time.Hour)
+	go purgeExpired(ctx, srv.Pool, "data exports", srv.Exports.PurgeExpired, time.Hour)
+	go purgeExpired(ctx, srv.Pool, "account deletions", srv.Accounts.PurgeDeleted, time.Hour)
+	go purgeExpired(ctx, srv.Pool, "delivered outbox events", srv.Dispatcher.PurgeDelivered, time.Hour)
+
+	// SIGHUP reloads the validation rules file without a restart
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := srv.Rules.Reload(); err != nil {
+				log.Printf("failed to reload validation rules, keeping current rules: %v", err)
+				continue
+			}
+			log.Printf("reloaded validation rules from %s", srv.Rules.Source())
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		log.Println("Shutting down...")
+		if err := srv.App.Shutdown(); err != nil {
+			log.Printf("failed to shut down server: %v", err)
+		}
+	}()
+
+	if err := srv.App.Listen(":" + cfg.Port); err != nil {
 		log.Fatalf("failed to start server: %v", err)
 	}
 }
+
+// purgeExpired runs purge every interval (hourly, as started above) on every instance. The
+// purges reclaim expired registration drafts, idempotency keys and data exports, accounts
+// past their deletion grace period, and outbox events already delivered; reads ignore all of
+// these already. Each purge holds an advisory lock named after it, so when several instances
+// tick together one runs the deletes and the others skip that round.
+func purgeExpired(ctx context.Context, pool *pgxpool.Pool, what string, purge func(context.Context) (int64, error), interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var n int64
+			_, err := db.WithAdvisoryLock(ctx, pool, "purge "+what, func(ctx context.Context) error {
+				var err error
+				n, err = purge(ctx)
+				return err
+			})
+			if err != nil {
+				log.Printf("failed to purge expired %s: %v", what, err)
+			} else if n > 0 {
+				log.Printf("purged %d expired %s", n, what)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+
+	"tyk-registration-server/internal/config"
+	"tyk-registration-server/internal/db"
+	"tyk-registration-server/internal/pii"
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/internal/services"
+)
+
+// normalizePhones rewrites the phones stored before registration normalized them (see
+// migration 000010) to E.164 with libphonenumber, and records their region and type. Run it
+// once after that migration; it can run while the server is up and be re-run, as rows
+// already done are skipped. Accounts whose normalized number another account already holds
+// are left unchanged and listed; the command then fails so they can be resolved by hand.
+func normalizePhones(args []string) error {
+	fs := flag.NewFlagSet("normalize-phones", flag.ContinueOnError)
+	batch := fs.Int("batch", 500, "accounts to read per query")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *batch < 1 {
+		return errors.New("-batch must be positive")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := db.RunMigrations(cfg.DSN); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	pool, err := db.NewPool(cfg.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+	cipher, err := pii.Load(ctx, cfg.PIIMasterKeyFile, repositories.NewPIIKeyRepository(pool))
+	if err != nil {
+		return err
+	}
+	users, err := userRepository(cfg, pool, cipher)
+	if err != nil {
+		return err
+	}
+
+	n, err := services.BackfillPhoneDetails(ctx, users, *batch)
+	log.Printf("done: updated %d phone numbers", n)
+	return err
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"os"
+)
+
+// NewSigner builds the signer selected by alg.
+// HS256 uses secret; when it's empty a random per-process secret is generated, which
+// invalidates all access tokens on restart, so config.Load only allows that with
+// JWT_EPHEMERAL_SECRET. EdDSA reads a PKCS#8 PEM private key from keyFile.
+func NewSigner(alg, secret, keyFile string) (Signer, error) {
+	switch alg {
+	case AlgHS256:
+		if secret == "" {
+			log.Println("JWT_EPHEMERAL_SECRET is set: signing with a per-process secret; tokens won't survive a restart")
+			buf := make([]byte, 32)
+			if _, err := rand.Read(buf); err != nil {
+				return nil, err
+			}
+			return NewHS256Signer(buf), nil
+		}
+		return NewHS256Signer([]byte(secret)), nil
+	case AlgEdDSA:
+		key, err := loadEd25519PrivateKey(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return NewEdDSASigner(key), nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", alg)
+	}
+}
+
+func loadEd25519PrivateKey(path string) (ed25519.PrivateKey, error) {
+	if path == "" {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE is required for %s", AlgEdDSA)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT private key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("JWT private key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT private key: %w", err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("JWT private key is not an Ed25519 key")
+	}
+	return key, nil
+}
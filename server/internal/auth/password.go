@@ -0,0 +1,288 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnknownHashFormat is returned by Verify for hashes no supported algorithm produced
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// PasswordHasher hashes passwords into self-describing strings and verifies them
+type PasswordHasher interface {
+	// Hash returns ctx's error if ctx ends while waiting for a hashing slot
+	Hash(ctx context.Context, password string) (string, error)
+	// Verify reports whether password matches hash and, if it does, whether hash uses an
+	// older algorithm or parameters and should be replaced by Hash(password). Like Hash, it
+	// gives up with ctx's error if ctx ends while waiting for a slot.
+	Verify(ctx context.Context, hash, password string) (ok, rehash bool, err error)
+	// KeyID is the pepper key new hashes use, or "" when peppering is off
+	KeyID() string
+}
+
+// Argon2idParams are the argon2id cost parameters
+type Argon2idParams struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follow the OWASP recommendations with room to spare
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Upper bounds on argon2id parameters, so a stored hash can't make Verify arbitrarily
+// expensive
+const (
+	maxArgon2idMemory     = 1 << 20 // KiB, 1 GiB
+	maxArgon2idIterations = 64
+	maxArgon2idSaltLength = 64
+	maxArgon2idKeyLength  = 128
+)
+
+func (p Argon2idParams) validate() error {
+	switch {
+	case p.Memory > maxArgon2idMemory:
+		return fmt.Errorf("argon2id memory must be at most %d KiB", maxArgon2idMemory)
+	case p.Iterations > maxArgon2idIterations:
+		return fmt.Errorf("argon2id iterations must be at most %d", maxArgon2idIterations)
+	case p.SaltLength > maxArgon2idSaltLength:
+		return fmt.Errorf("argon2id salt must be at most %d bytes", maxArgon2idSaltLength)
+	case p.KeyLength > maxArgon2idKeyLength:
+		return fmt.Errorf("argon2id key must be at most %d bytes", maxArgon2idKeyLength)
+	case p.Iterations < 1:
+		return errors.New("argon2id iterations must be at least 1")
+	case p.Parallelism < 1:
+		return errors.New("argon2id parallelism must be at least 1")
+	case p.Memory < 8*uint32(p.Parallelism):
+		return fmt.Errorf("argon2id memory must be at least %d KiB for parallelism %d", 8*uint32(p.Parallelism), p.Parallelism)
+	case p.SaltLength < 8:
+		return errors.New("argon2id salt must be at least 8 bytes")
+	case p.KeyLength < 16:
+		return errors.New("argon2id key must be at least 16 bytes")
+	}
+	return nil
+}
+
+// NewPasswordHasher hashes with argon2id using params, in the PHC string format
+// ($argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>), and verifies both those hashes and the
+// bcrypt hashes stored before argon2id was adopted. With a pepper, the password's HMAC
+// under the current key is hashed instead and the key ID is added to the parameters
+// (m=65536,t=3,p=2,kid=<id>). bcrypt hashes, and argon2id hashes with other parameters or
+// another pepper key, are reported for rehashing.
+//
+// Each argon2id computation holds params.Memory for its duration, so at most maxConcurrent
+// run at once (runtime.NumCPU() when 0) and the others wait their turn, or until their
+// context ends.
+func NewPasswordHasher(params Argon2idParams, pepper *Pepper, maxConcurrent int) (PasswordHasher, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	if maxConcurrent < 0 {
+		return nil, errors.New("argon2id concurrency must not be negative")
+	}
+	if maxConcurrent == 0 {
+		maxConcurrent = runtime.NumCPU()
+	}
+	return &passwordHasher{params: params, pepper: pepper, slots: make(chan struct{}, maxConcurrent)}, nil
+}
+
+type passwordHasher struct {
+	params Argon2idParams
+	pepper *Pepper
+	slots  chan struct{} // One per argon2id computation allowed to run
+}
+
+// idKey derives an argon2id key once a slot is free. A request whose context has already
+// ended, such as a client that hung up while queued, gives up instead of taking a slot.
+func (h *passwordHasher) idKey(ctx context.Context, input, salt []byte, p Argon2idParams) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-h.slots }()
+	return argon2.IDKey(input, salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength), nil
+}
+
+func (h *passwordHasher) KeyID() string {
+	if h.pepper == nil {
+		return ""
+	}
+	return h.pepper.CurrentID
+}
+
+// input is what gets hashed for password: its HMAC under pepper key keyID, or the
+// password itself for unpeppered hashes
+func (h *passwordHasher) input(keyID, password string) ([]byte, error) {
+	if keyID == "" {
+		return []byte(password), nil
+	}
+	if h.pepper == nil {
+		return nil, ErrUnknownPepperKey
+	}
+	return h.pepper.apply(keyID, password)
+}
+
+var phcEncoding = base64.RawStdEncoding
+
+func (h *passwordHasher) Hash(ctx context.Context, password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	keyID := h.KeyID()
+	input, err := h.input(keyID, password)
+	if err != nil {
+		return "", err
+	}
+	key, err := h.idKey(ctx, input, salt, h.params)
+	if err != nil {
+		return "", err
+	}
+
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", h.params.Memory, h.params.Iterations, h.params.Parallelism)
+	if keyID != "" {
+		params += ",kid=" + keyID
+	}
+	return fmt.Sprintf("$argon2id$v=%d$%s$%s$%s",
+		argon2.Version, params, phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+}
+
+func (h *passwordHasher) Verify(ctx context.Context, hash, password string) (bool, bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return h.verifyArgon2id(ctx, hash, password)
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		// bcrypt ignores everything past 72 bytes; rehashing lifts that limit too
+		return true, true, nil
+	default:
+		return false, false, ErrUnknownHashFormat
+	}
+}
+
+func isBcrypt(hash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *passwordHasher) verifyArgon2id(ctx context.Context, hash, password string) (bool, bool, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, false, ErrUnknownHashFormat
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return false, false, ErrUnknownHashFormat
+	}
+	if version != argon2.Version {
+		return false, false, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+	stored, keyID, err := parseArgon2idParams(parts[3])
+	if err != nil {
+		return false, false, err
+	}
+	salt, err := phcEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, ErrUnknownHashFormat
+	}
+	key, err := phcEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, false, ErrUnknownHashFormat
+	}
+	stored.SaltLength, stored.KeyLength = uint32(len(salt)), uint32(len(key))
+	if err := stored.validate(); err != nil {
+		return false, false, fmt.Errorf("%w: %v", ErrUnknownHashFormat, err)
+	}
+
+	input, err := h.input(keyID, password)
+	if err != nil {
+		return false, false, fmt.Errorf("%w %q", err, keyID)
+	}
+	derived, err := h.idKey(ctx, input, salt, stored)
+	if err != nil {
+		return false, false, err
+	}
+	if subtle.ConstantTimeCompare(derived, key) != 1 {
+		return false, false, nil
+	}
+	return true, stored != h.params || keyID != h.KeyID(), nil
+}
+
+// parseArgon2idParams reads "m=65536,t=3,p=2" with an optional ",kid=<id>". The key and
+// salt lengths are left for the caller to fill in.
+func parseArgon2idParams(s string) (Argon2idParams, string, error) {
+	var params Argon2idParams
+	var keyID string
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || seen[name] {
+			return params, "", ErrUnknownHashFormat
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "m":
+			params.Memory, err = parseUint32(value)
+		case "t":
+			params.Iterations, err = parseUint32(value)
+		case "p":
+			var p uint64
+			p, err = strconv.ParseUint(value, 10, 8)
+			params.Parallelism = uint8(p)
+		case "kid":
+			if !pepperKeyID.MatchString(value) {
+				err = ErrUnknownHashFormat
+			}
+			keyID = value
+		default:
+			err = ErrUnknownHashFormat
+		}
+		if err != nil {
+			return params, "", ErrUnknownHashFormat
+		}
+	}
+	if !seen["m"] || !seen["t"] || !seen["p"] {
+		return params, "", ErrUnknownHashFormat
+	}
+	return params, keyID, nil
+}
+
+func parseUint32(s string) (uint32, error) {
+	n, err := strconv.ParseUint(s, 10, 32)
+	return uint32(n), err
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrUnknownPepperKey is returned by Verify for a hash peppered with a key that is not configured
+var ErrUnknownPepperKey = errors.New("password hash uses an unknown pepper key")
+
+// minPepperKeyLength matches the HMAC-SHA256 output size
+const minPepperKeyLength = 32
+
+// pepperKeyID is limited to characters allowed in a PHC parameter value
+var pepperKeyID = regexp.MustCompile(`^[A-Za-z0-9.-]{1,32}$`)
+
+// Pepper holds the server-side keys mixed into passwords with HMAC-SHA256 before hashing,
+// so a leaked users table can't be attacked offline without them. Each hash records the ID
+// of its key. CurrentID peppers new hashes; the other keys only verify existing ones until
+// those are re-peppered on login.
+type Pepper struct {
+	CurrentID string
+	Keys      map[string][]byte
+}
+
+// LoadPepper combines the keys in spec ("id:base64key,id:base64key") and in the key file
+// at path (one "id:base64key" per line, # comments). current picks the key for new hashes
+// and may be omitted when there is only one. It returns nil, disabling the pepper, when no
+// keys are configured.
+func LoadPepper(spec, path, current string) (*Pepper, error) {
+	keys := make(map[string][]byte)
+	for _, entry := range strings.Split(spec, ",") {
+		if err := addPepperKey(keys, entry); err != nil {
+			return nil, err
+		}
+	}
+	if path != "" {
+		if err := loadPepperFile(keys, path); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(keys) == 0 {
+		if current != "" {
+			return nil, fmt.Errorf("pepper key %q is selected but no pepper keys are configured", current)
+		}
+		return nil, nil
+	}
+	if current == "" {
+		if len(keys) > 1 {
+			return nil, errors.New("several pepper keys are configured; select the one for new hashes")
+		}
+		for id := range keys {
+			current = id
+		}
+	}
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("selected pepper key %q is not configured", current)
+	}
+	return &Pepper{CurrentID: current, Keys: keys}, nil
+}
+
+func loadPepperFile(keys map[string][]byte, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open pepper key file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if err := addPepperKey(keys, scanner.Text()); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func addPepperKey(keys map[string][]byte, entry string) error {
+	entry = strings.TrimSpace(entry)
+	if entry == "" || strings.HasPrefix(entry, "#") {
+		return nil
+	}
+	id, encoded, ok := strings.Cut(entry, ":")
+	if !ok {
+		return errors.New(`pepper key must be "id:base64key"`)
+	}
+	id = strings.TrimSpace(id)
+	if !pepperKeyID.MatchString(id) {
+		return fmt.Errorf("invalid pepper key ID %q: use up to 32 letters, digits, '.' or '-'", id)
+	}
+	if _, dup := keys[id]; dup {
+		return fmt.Errorf("pepper key %q is configured twice", id)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return fmt.Errorf("pepper key %q is not valid base64: %w", id, err)
+	}
+	if len(key) < minPepperKeyLength {
+		return fmt.Errorf("pepper key %q must be at least %d bytes", id, minPepperKeyLength)
+	}
+	keys[id] = key
+	return nil
+}
+
+// apply returns the HMAC of password under key id, which is what gets hashed
+func (p *Pepper) apply(id, password string) ([]byte, error) {
+	key, ok := p.Keys[id]
+	if !ok {
+		return nil, ErrUnknownPepperKey
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+	return mac.Sum(nil), nil
+}
@@ -1,22 +1,378 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+
+	"tyk-registration-server/internal/middleware"
 )
 
 type Config struct {
-	Port string
-	DSN  string
+	Port    string
+	DSN     string
+	BaseURL string
+
+	SessionTTL          time.Duration
+	SessionCookieName   string
+	SessionCookieSecure bool
+
+	JWTAlgorithm string
+	JWTSecret    string
+	// JWTEphemeralSecret allows HS256 without JWT_SECRET, signing with a random per-process
+	// secret. For development only: tokens stop validating on restart and across instances.
+	JWTEphemeralSecret bool
+	JWTPrivateKeyFile  string
+	JWTIssuer          string
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
+
+	EmailVerificationTTL time.Duration
+	PasswordResetTTL     time.Duration
+
+	MailDriver   string
+	MailFrom     string
+	MailFileDir  string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPStartTLS bool
+
+	OutboxPollInterval time.Duration
+	OutboxBatchSize    int
+	OutboxLease        time.Duration
+	OutboxMaxAttempts  int
+	OutboxBaseBackoff  time.Duration
+	OutboxMaxBackoff   time.Duration
+	// OutboxRetention is how long delivered outbox events are kept
+	OutboxRetention time.Duration
+
+	AdminAPIKey    string
+	MetricsToken   string
+	WebhookTimeout time.Duration
+
+	ValidationRulesFile string
+	ValidationPipeline  []string
+	ValidationMode      string
+
+	UsernameProfanityFile string
+	BreachedPasswordsPath string
+	PasswordMinScore      int
+
+	// argon2id cost for new password hashes; memory is in KiB
+	PasswordHashMemory      uint32
+	PasswordHashIterations  uint32
+	PasswordHashParallelism uint8
+	// PasswordHashConcurrency caps argon2id computations running at once; 0 is one per CPU
+	PasswordHashConcurrency int
+
+	// Pepper keys as "id:base64key" pairs, inline or one per line in the file, and the ID
+	// used for new hashes (optional with a single key)
+	PasswordPepperKeys    string
+	PasswordPepperKeyFile string
+	PasswordPepperCurrent string
+
+	// PIIMasterKeyFile holds the key that wraps the PII encryption keys; unset stores PII
+	// in plaintext
+	PIIMasterKeyFile string
+
+	// EmailProvidersFile lists mail providers whose address variants reach one mailbox;
+	// unset uses the built-in list. EmailFoldLocalCase compares local parts case-insensitively.
+	EmailProvidersFile string
+	EmailFoldLocalCase bool
+
+	DraftTTL time.Duration
+
+	IdempotencyTTL time.Duration
+
+	// ExportTTL is how long a background data export stays downloadable.
+	// ExportInlineMaxRecords caps the sessions and audit events of an export built
+	// during the request; larger ones run in the background.
+	ExportTTL              time.Duration
+	ExportInlineMaxRecords int
+
+	// AccountDeletionGrace is how long a deleted account is kept before it is purged, by
+	// deleting or anonymizing it per AccountPurgeMode. DeletedIdentifiers says whether its
+	// email, username and phone are released at once or reserved until then.
+	AccountDeletionGrace time.Duration
+	AccountPurgeMode     string
+	DeletedIdentifiers   string
+
+	// RegisterRateLimit also covers finalizing drafts; StepsRateLimit covers step validation
+	// and the other draft routes
+	RegisterRateLimit       middleware.RateLimit
+	StepsRateLimit          middleware.RateLimit
+	UsernameRateLimit       middleware.RateLimit
+	LoginRateLimit          middleware.RateLimit
+	ForgotPasswordRateLimit middleware.RateLimit
+
+	// ProxyHeader carries the client IP when set and the request comes from a TrustedProxies address
+	ProxyHeader    string
+	TrustedProxies []string
 }
 
+// minJWTSecretBytes is the shortest HS256 secret accepted, the size of the SHA-256 output
+const minJWTSecretBytes = 32
+
 func Load() (*Config, error) {
 	_ = godotenv.Load()
 
+	sessionTTL, err := getDuration("SESSION_TTL", "24h")
+	if err != nil {
+		return nil, err
+	}
+	cookieSecure, err := getBool("SESSION_COOKIE_SECURE", "true")
+	if err != nil {
+		return nil, err
+	}
+
+	jwtAlgorithm := getEnv("JWT_ALGORITHM", "HS256")
+	jwtSecret := getEnv("JWT_SECRET", "")
+	jwtEphemeral, err := getBool("JWT_EPHEMERAL_SECRET", "false")
+	if err != nil {
+		return nil, err
+	}
+	if jwtAlgorithm == "HS256" && (jwtSecret != "" || !jwtEphemeral) && len(jwtSecret) < minJWTSecretBytes {
+		return nil, fmt.Errorf("JWT_SECRET must be at least %d bytes for HS256 (JWT_EPHEMERAL_SECRET=true allows none, for development)", minJWTSecretBytes)
+	}
+
+	accessTTL, err := getDuration("ACCESS_TOKEN_TTL", "15m")
+	if err != nil {
+		return nil, err
+	}
+	refreshTTL, err := getDuration("REFRESH_TOKEN_TTL", "720h")
+	if err != nil {
+		return nil, err
+	}
+
+	verificationTTL, err := getDuration("EMAIL_VERIFICATION_TTL", "48h")
+	if err != nil {
+		return nil, err
+	}
+
+	resetTTL, err := getDuration("PASSWORD_RESET_TTL", "1h")
+	if err != nil {
+		return nil, err
+	}
+
+	smtpPort, err := getInt("SMTP_PORT", "587")
+	if err != nil {
+		return nil, err
+	}
+	smtpStartTLS, err := getBool("SMTP_STARTTLS", "true")
+	if err != nil {
+		return nil, err
+	}
+
+	emailFoldLocalCase, err := getBool("EMAIL_FOLD_LOCAL_CASE", "true")
+	if err != nil {
+		return nil, err
+	}
+
+	outboxPoll, err := getDuration("OUTBOX_POLL_INTERVAL", "1s")
+	if err != nil {
+		return nil, err
+	}
+	outboxBatch, err := getInt("OUTBOX_BATCH_SIZE", "20")
+	if err != nil {
+		return nil, err
+	}
+	outboxLease, err := getDuration("OUTBOX_LEASE", "5m")
+	if err != nil {
+		return nil, err
+	}
+	outboxAttempts, err := getInt("OUTBOX_MAX_ATTEMPTS", "10")
+	if err != nil {
+		return nil, err
+	}
+	outboxBaseBackoff, err := getDuration("OUTBOX_BASE_BACKOFF", "5s")
+	if err != nil {
+		return nil, err
+	}
+	outboxMaxBackoff, err := getDuration("OUTBOX_MAX_BACKOFF", "1h")
+	if err != nil {
+		return nil, err
+	}
+	outboxRetention, err := getDuration("OUTBOX_RETENTION", "168h")
+	if err != nil {
+		return nil, err
+	}
+
+	webhookTimeout, err := getDuration("WEBHOOK_TIMEOUT", "10s")
+	if err != nil {
+		return nil, err
+	}
+
+	draftTTL, err := getDuration("REGISTRATION_DRAFT_TTL", "72h")
+	if err != nil {
+		return nil, err
+	}
+
+	idempotencyTTL, err := getDuration("IDEMPOTENCY_TTL", "24h")
+	if err != nil {
+		return nil, err
+	}
+
+	exportTTL, err := getDuration("EXPORT_TTL", "24h")
+	if err != nil {
+		return nil, err
+	}
+	exportInlineMax, err := getInt("EXPORT_INLINE_MAX_RECORDS", "500")
+	if err != nil {
+		return nil, err
+	}
+
+	deletionGrace, err := getDuration("ACCOUNT_DELETION_GRACE", "720h")
+	if err != nil {
+		return nil, err
+	}
+	purgeMode := getEnv("ACCOUNT_PURGE_MODE", "delete")
+	if purgeMode != "delete" && purgeMode != "anonymize" {
+		return nil, fmt.Errorf("invalid ACCOUNT_PURGE_MODE %q: must be delete or anonymize", purgeMode)
+	}
+	deletedIdentifiers := getEnv("ACCOUNT_DELETION_IDENTIFIERS", "reserve")
+	if deletedIdentifiers != "release" && deletedIdentifiers != "reserve" {
+		return nil, fmt.Errorf("invalid ACCOUNT_DELETION_IDENTIFIERS %q: must be release or reserve", deletedIdentifiers)
+	}
+
+	passwordMinScore, err := getInt("PASSWORD_MIN_SCORE", "3")
+	if err != nil {
+		return nil, err
+	}
+	if passwordMinScore < 0 || passwordMinScore > 4 {
+		return nil, fmt.Errorf("invalid PASSWORD_MIN_SCORE %d: must be between 0 and 4", passwordMinScore)
+	}
+
+	hashMemory, err := getInt("PASSWORD_HASH_MEMORY", "65536")
+	if err != nil {
+		return nil, err
+	}
+	hashIterations, err := getInt("PASSWORD_HASH_ITERATIONS", "3")
+	if err != nil {
+		return nil, err
+	}
+	hashParallelism, err := getInt("PASSWORD_HASH_PARALLELISM", "2")
+	if err != nil {
+		return nil, err
+	}
+	if hashMemory < 1 || hashIterations < 1 || hashParallelism < 1 || hashParallelism > 255 {
+		return nil, fmt.Errorf("invalid password hash parameters m=%d,t=%d,p=%d: all must be positive and parallelism at most 255", hashMemory, hashIterations, hashParallelism)
+	}
+
+	hashConcurrency, err := getInt("PASSWORD_HASH_CONCURRENCY", "0")
+	if err != nil {
+		return nil, err
+	}
+	if hashConcurrency < 0 {
+		return nil, fmt.Errorf("invalid PASSWORD_HASH_CONCURRENCY %d: must not be negative", hashConcurrency)
+	}
+
+	registerLimit, err := getRateLimit("RATE_LIMIT_REGISTER", "10/1m")
+	if err != nil {
+		return nil, err
+	}
+	stepsLimit, err := getRateLimit("RATE_LIMIT_REGISTRATION_STEPS", "60/1m")
+	if err != nil {
+		return nil, err
+	}
+	usernameLimit, err := getRateLimit("RATE_LIMIT_USERNAME_AVAILABILITY", "30/1m")
+	if err != nil {
+		return nil, err
+	}
+	loginLimit, err := getRateLimit("RATE_LIMIT_LOGIN", "10/1m")
+	if err != nil {
+		return nil, err
+	}
+	forgotLimit, err := getRateLimit("RATE_LIMIT_PASSWORD_FORGOT", "5/1m")
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
-		Port: getEnv("SERVER_PORT", "3001"),
-		DSN:  getEnv("DATABASE_URL"),
+		Port:                getEnv("SERVER_PORT", "3001"),
+		DSN:                 getEnv("DATABASE_URL"),
+		BaseURL:             getEnv("APP_BASE_URL", "http://localhost:3001"),
+		SessionTTL:          sessionTTL,
+		SessionCookieName:   getEnv("SESSION_COOKIE_NAME", "session_id"),
+		SessionCookieSecure: cookieSecure,
+		JWTAlgorithm:        jwtAlgorithm,
+		JWTSecret:           jwtSecret,
+		JWTEphemeralSecret:  jwtEphemeral,
+		JWTPrivateKeyFile:   getEnv("JWT_PRIVATE_KEY_FILE", ""),
+		JWTIssuer:           getEnv("JWT_ISSUER", "tyk-registration-server"),
+		AccessTokenTTL:      accessTTL,
+		RefreshTokenTTL:     refreshTTL,
+
+		EmailVerificationTTL: verificationTTL,
+		PasswordResetTTL:     resetTTL,
+
+		MailDriver:   getEnv("MAIL_DRIVER", "file"),
+		MailFrom:     getEnv("MAIL_FROM", "TyK Registration <no-reply@localhost>"),
+		MailFileDir:  getEnv("MAIL_FILE_DIR", "./tmp/mail"),
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     smtpPort,
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPStartTLS: smtpStartTLS,
+
+		OutboxPollInterval: outboxPoll,
+		OutboxBatchSize:    outboxBatch,
+		OutboxLease:        outboxLease,
+		OutboxMaxAttempts:  outboxAttempts,
+		OutboxBaseBackoff:  outboxBaseBackoff,
+		OutboxMaxBackoff:   outboxMaxBackoff,
+		OutboxRetention:    outboxRetention,
+
+		AdminAPIKey:    getEnv("ADMIN_API_KEY", ""),
+		MetricsToken:   getEnv("METRICS_TOKEN", ""),
+		WebhookTimeout: webhookTimeout,
+
+		ValidationRulesFile: getEnv("VALIDATION_RULES_FILE", ""),
+		ValidationPipeline:  getList("VALIDATION_PIPELINE", "field,cross,business"),
+		ValidationMode:      getEnv("VALIDATION_MODE", "short_circuit"),
+
+		UsernameProfanityFile: getEnv("USERNAME_PROFANITY_FILE", ""),
+		BreachedPasswordsPath: getEnv("BREACHED_PASSWORDS_PATH", ""),
+		PasswordMinScore:      passwordMinScore,
+
+		PasswordHashMemory:      uint32(hashMemory),
+		PasswordHashIterations:  uint32(hashIterations),
+		PasswordHashParallelism: uint8(hashParallelism),
+		PasswordHashConcurrency: hashConcurrency,
+
+		PasswordPepperKeys:    getEnv("PASSWORD_PEPPER_KEYS", ""),
+		PasswordPepperKeyFile: getEnv("PASSWORD_PEPPER_KEY_FILE", ""),
+		PasswordPepperCurrent: getEnv("PASSWORD_PEPPER_CURRENT", ""),
+
+		PIIMasterKeyFile: getEnv("PII_MASTER_KEY_FILE", ""),
+
+		EmailProvidersFile: getEnv("EMAIL_PROVIDERS_FILE", ""),
+		EmailFoldLocalCase: emailFoldLocalCase,
+
+		DraftTTL: draftTTL,
+
+		IdempotencyTTL: idempotencyTTL,
+
+		ExportTTL:              exportTTL,
+		ExportInlineMaxRecords: exportInlineMax,
+
+		AccountDeletionGrace: deletionGrace,
+		AccountPurgeMode:     purgeMode,
+		DeletedIdentifiers:   deletedIdentifiers,
+
+		RegisterRateLimit:       registerLimit,
+		StepsRateLimit:          stepsLimit,
+		UsernameRateLimit:       usernameLimit,
+		LoginRateLimit:          loginLimit,
+		ForgotPasswordRateLimit: forgotLimit,
+
+		ProxyHeader:    getEnv("PROXY_HEADER", ""),
+		TrustedProxies: getList("TRUSTED_PROXIES", ""),
 	}
 	return cfg, nil
 }
@@ -30,3 +386,56 @@ func getEnv(key string, fallback ...string) string {
 	}
 	panic("required environment variable " + key + " is not set")
 }
+
+// getList splits a comma-separated value, dropping blank entries
+func getList(key, fallback string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, fallback), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getDuration(key, fallback string) (time.Duration, error) {
+	d, err := time.ParseDuration(getEnv(key, fallback))
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration for %s: %w", key, err)
+	}
+	return d, nil
+}
+
+// getRateLimit parses "<requests>/<period>", e.g. "10/1m"; "off" disables the limit
+func getRateLimit(key, fallback string) (middleware.RateLimit, error) {
+	v := getEnv(key, fallback)
+	if strings.EqualFold(v, "off") {
+		return middleware.RateLimit{}, nil
+	}
+	n, period, ok := strings.Cut(v, "/")
+	burst, err := strconv.Atoi(n)
+	if !ok || err != nil || burst < 0 {
+		return middleware.RateLimit{}, fmt.Errorf("invalid rate limit for %s: want <requests>/<period>, got %q", key, v)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return middleware.RateLimit{}, fmt.Errorf("invalid rate limit period for %s: %q", key, period)
+	}
+	return middleware.RateLimit{Burst: burst, Period: d}, nil
+}
+
+func getBool(key, fallback string) (bool, error) {
+	b, err := strconv.ParseBool(getEnv(key, fallback))
+	if err != nil {
+		return false, fmt.Errorf("invalid boolean for %s: %w", key, err)
+	}
+	return b, nil
+}
+
+func getInt(key, fallback string) (int, error) {
+	n, err := strconv.Atoi(getEnv(key, fallback))
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer for %s: %w", key, err)
+	}
+	return n, nil
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"hash/fnv"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -24,3 +25,38 @@ func Ping(ctx context.Context, pool *pgxpool.Pool) error {
 	defer cancel()
 	return pool.Ping(ctx)
 }
+
+// WithAdvisoryLock runs fn while holding the Postgres advisory lock named name, so only one
+// instance runs it at a time. It reports false without running fn if another session holds
+// the lock. The lock belongs to a connection taken from the pool for the duration, and is
+// released with it if the unlock fails.
+func WithAdvisoryLock(ctx context.Context, pool *pgxpool.Pool, name string, fn func(context.Context) error) (bool, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	key := advisoryLockKey(name)
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		return false, err
+	}
+	if !locked {
+		return false, nil
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			// A session-level lock outlives the unlock failure, so drop the connection with it
+			_ = conn.Conn().Close(context.Background())
+		}
+	}()
+	return true, fn(ctx)
+}
+
+// advisoryLockKey maps a lock name to the bigint key Postgres advisory locks take
+func advisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,23 @@
+-- Side effects of a write (emails, webhooks, ...) are recorded here in the same transaction
+-- as the write itself and delivered later by the outbox dispatcher
+CREATE TABLE IF NOT EXISTS outbox (
+    id UUID PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    aggregate_id UUID,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    -- Claimed events are leased rather than row-locked, so handlers run outside any
+    -- transaction. An event whose lease runs out without an outcome is claimed again.
+    locked_until TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ
+);
+
+-- The dispatcher only ever scans pending rows that are due
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(next_attempt_at) WHERE status = 'pending';
+
+-- Delivered events are deleted after OUTBOX_RETENTION
+CREATE INDEX IF NOT EXISTS idx_outbox_delivered ON outbox(delivered_at) WHERE status = 'delivered';
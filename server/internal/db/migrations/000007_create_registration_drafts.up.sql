@@ -0,0 +1,15 @@
+-- Partially completed multi-step registrations. data holds the submitted step fields as
+-- JSON, keyed like the registration request; password fields are never stored.
+CREATE TABLE IF NOT EXISTS registration_drafts (
+    id UUID PRIMARY KEY,
+    -- A draft is reached with its id and a secret token returned once at creation; only the
+    -- token's hash is stored
+    token_hash TEXT NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    completed_steps TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_registration_drafts_expires_at ON registration_drafts(expires_at);
@@ -0,0 +1,17 @@
+-- Responses of requests sent with an Idempotency-Key header, replayed on retries
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    -- Caller (user or client IP), method and path
+    scope TEXT NOT NULL,
+    key TEXT NOT NULL,
+    -- SHA-256 of the method, path and canonical JSON body; a retry must match it
+    fingerprint TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'in_progress' CHECK (status IN ('in_progress', 'completed')),
+    response_status INTEGER,
+    response_body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- While in progress this is a short lock timeout, afterwards the replay window
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
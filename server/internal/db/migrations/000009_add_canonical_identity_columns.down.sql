@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_username_canonical_unique;
+DROP INDEX IF EXISTS idx_users_email_canonical_unique;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS username_canonical,
+    DROP COLUMN IF EXISTS email_canonical;
@@ -0,0 +1,54 @@
+-- Canonical forms of email and username, used for uniqueness checks and lookups so that
+-- "John@Example.com" and "john@example.com" are the same account
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email_canonical TEXT,
+    ADD COLUMN IF NOT EXISTS username_canonical TEXT;
+
+-- Provisional backfill with the default rules of utils.EmailRules and utils.CanonicalUsername.
+-- Postgres' lower() is simple case mapping where the application uses full case folding, so
+-- usernames with characters that fold to several (e.g. "ß" to "ss") get the wrong form, and
+-- configured email provider rules are not applied. Run `api canonicalize-users` after this
+-- migration to recompute every account in Go.
+UPDATE users u
+SET email_canonical = CASE
+        WHEN e.domain IN ('gmail.com', 'googlemail.com') THEN
+            replace(split_part(e.local, '+', 1), '.', '') || '@gmail.com'
+        ELSE e.address
+    END,
+    username_canonical = normalize(lower(normalize(btrim(u.username), NFKC)), NFKC)
+FROM (
+    SELECT id,
+           lower(btrim(email)) AS address,
+           substring(lower(btrim(email)) FROM '^(.*)@') AS local,
+           substring(lower(btrim(email)) FROM '@([^@]*)$') AS domain
+    FROM users
+) e
+WHERE e.id = u.id;
+
+-- Accounts that only differed by case or provider folding can't both keep their address.
+-- Stop here with the list so they can be resolved by hand before re-running.
+DO $$
+DECLARE
+    collisions TEXT;
+BEGIN
+    SELECT string_agg(format('%s: %s', canonical, accounts), '; ')
+    INTO collisions
+    FROM (
+        SELECT email_canonical AS canonical, string_agg(email, ', ' ORDER BY created_at) AS accounts
+        FROM users GROUP BY email_canonical HAVING count(*) > 1
+        UNION ALL
+        SELECT username_canonical, string_agg(username, ', ' ORDER BY created_at)
+        FROM users GROUP BY username_canonical HAVING count(*) > 1
+    ) c;
+
+    IF collisions IS NOT NULL THEN
+        RAISE EXCEPTION 'existing users collide after canonicalization: %', collisions;
+    END IF;
+END $$;
+
+ALTER TABLE users
+    ALTER COLUMN email_canonical SET NOT NULL,
+    ALTER COLUMN username_canonical SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_canonical_unique ON users(email_canonical);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_canonical_unique ON users(username_canonical);
@@ -0,0 +1,4 @@
+-- Numbers stay in E.164 form; the original formatting is not kept
+ALTER TABLE users
+    DROP COLUMN IF EXISTS phone_type,
+    DROP COLUMN IF EXISTS phone_region;
@@ -0,0 +1,12 @@
+-- Phones are stored in E.164 form with the region and number type detected when parsing
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS phone_region TEXT,
+    ADD COLUMN IF NOT EXISTS phone_type TEXT;
+
+-- A blank phone means no phone; as '' it would also collide under idx_users_phone_unique
+UPDATE users SET phone = NULL WHERE btrim(phone) = '';
+
+-- Normalizing a number to E.164, and finding its region and type, needs libphonenumber, so
+-- it is done by `api normalize-phones` after this migration: it rewrites the rows with
+-- phone_type IS NULL and fails listing the numbers that collide with another account once
+-- normalized.
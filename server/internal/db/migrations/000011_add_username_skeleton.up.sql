@@ -0,0 +1,17 @@
+-- Confusable skeleton (UTS #39) of username_canonical, so usernames that look like an existing
+-- one can be found with an index lookup. Not unique: existing lookalike pairs stay valid.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username_skeleton TEXT;
+
+-- Mirrors utils.UsernameSkeleton: NFD, map lookalikes to their Latin prototype, NFD again
+UPDATE users
+SET username_skeleton = normalize(
+    replace(
+        translate(normalize(username_canonical, NFD), 'аеѕіјорсухһԁԛԝӏαονριυγոսօıɡ01|', 'aesijopcyxhdqwlaovpiuynuoigoll'),
+        'm', 'rn'
+    ),
+    NFD
+);
+
+ALTER TABLE users ALTER COLUMN username_skeleton SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_username_skeleton ON users(username_skeleton);
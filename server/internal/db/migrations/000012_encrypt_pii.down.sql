@@ -0,0 +1,30 @@
+-- Decrypting needs the master key, which SQL doesn't have, and dropping the keys would lose
+-- the data. Only a database that was never encrypted can be rolled back.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE pii_key_id IS NOT NULL) THEN
+        RAISE EXCEPTION 'users hold encrypted PII, which can not be rolled back';
+    END IF;
+    IF EXISTS (SELECT 1 FROM webhook_endpoints WHERE secret_key_id IS NOT NULL) THEN
+        RAISE EXCEPTION 'webhook endpoints hold encrypted secrets, which can not be rolled back';
+    END IF;
+END $$;
+
+ALTER TABLE webhook_endpoints DROP COLUMN IF EXISTS secret_key_id;
+
+-- Drafts are short-lived; encrypted ones are discarded rather than decrypted
+DELETE FROM registration_drafts WHERE pii_key_id IS NOT NULL;
+ALTER TABLE registration_drafts
+    DROP COLUMN IF EXISTS pii_key_id,
+    DROP COLUMN IF EXISTS sealed_data;
+
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+
+DROP INDEX IF EXISTS idx_users_phone_index_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_phone_unique ON users(phone) WHERE phone IS NOT NULL;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS pii_key_id,
+    DROP COLUMN IF EXISTS phone_index;
+
+DROP TABLE IF EXISTS pii_keys;
@@ -0,0 +1,36 @@
+-- Envelope encryption of PII. Data keys are generated by the server and stored here wrapped
+-- by a master key that never reaches the database.
+CREATE TABLE IF NOT EXISTS pii_keys (
+    id UUID PRIMARY KEY,
+    purpose TEXT NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    master_key_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- There is only ever one blind index key: replacing it would orphan every stored index
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pii_keys_single_index_key ON pii_keys(purpose) WHERE purpose = 'index';
+
+-- pii_key_id is the data key a row's PII columns are encrypted with; NULL means plaintext.
+-- Once encrypted, email_canonical and phone_index hold blind indexes instead of the values.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS phone_index TEXT,
+    ADD COLUMN IF NOT EXISTS pii_key_id UUID REFERENCES pii_keys(id);
+
+UPDATE users SET phone_index = phone WHERE phone IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_users_phone_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_phone_index_unique ON users(phone_index) WHERE phone_index IS NOT NULL;
+
+-- Encrypted emails differ even when equal; idx_users_email_canonical_unique keeps them unique
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+
+-- Webhook signing secrets are encrypted under the same data keys; secret_key_id is the key a
+-- secret is encrypted with and NULL means plaintext
+ALTER TABLE webhook_endpoints ADD COLUMN IF NOT EXISTS secret_key_id UUID REFERENCES pii_keys(id);
+
+-- A draft holds the same PII; with encryption enabled its data is sealed into sealed_data
+-- under the data key pii_key_id and data is left empty
+ALTER TABLE registration_drafts
+    ADD COLUMN IF NOT EXISTS sealed_data BYTEA,
+    ADD COLUMN IF NOT EXISTS pii_key_id UUID REFERENCES pii_keys(id);
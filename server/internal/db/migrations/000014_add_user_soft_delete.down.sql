@@ -0,0 +1,32 @@
+-- Dropping deleted_at would bring accounts awaiting purge back to life
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE deleted_at IS NOT NULL AND purged_at IS NULL) THEN
+        RAISE EXCEPTION 'accounts are awaiting purge; purge them before rolling back';
+    END IF;
+END $$;
+
+DROP INDEX IF EXISTS idx_registration_drafts_email_canonical;
+ALTER TABLE registration_drafts DROP COLUMN IF EXISTS email_canonical;
+
+-- Purge tombstones have no user row left
+DELETE FROM audit_events WHERE user_id NOT IN (SELECT id FROM users);
+ALTER TABLE audit_events
+    ADD CONSTRAINT audit_events_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE users ADD CONSTRAINT users_username_key UNIQUE (username);
+
+DROP INDEX IF EXISTS idx_users_phone_index_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_phone_index_unique ON users(phone_index) WHERE phone_index IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_users_username_canonical_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_canonical_unique ON users(username_canonical);
+
+DROP INDEX IF EXISTS idx_users_email_canonical_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_canonical_unique ON users(email_canonical);
+
+DROP INDEX IF EXISTS idx_users_pending_purge;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS purged_at,
+    DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,33 @@
+-- Deleted accounts keep their row until the purger deletes or anonymizes it after the grace
+-- period; purged_at marks anonymized rows
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS purged_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_pending_purge ON users(deleted_at)
+    WHERE deleted_at IS NOT NULL AND purged_at IS NULL;
+
+-- Deleted accounts don't hold their email, username or phone. Whether they can be
+-- registered again before the purge is decided by the server's policy.
+DROP INDEX IF EXISTS idx_users_email_canonical_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_canonical_unique ON users(email_canonical)
+    WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_users_username_canonical_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_canonical_unique ON users(username_canonical)
+    WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_users_phone_index_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_phone_index_unique ON users(phone_index)
+    WHERE phone_index IS NOT NULL AND deleted_at IS NULL;
+
+-- Covered by username_canonical, and would keep deleted usernames taken
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_key;
+
+-- The purge tombstone outlives the user row it describes
+ALTER TABLE audit_events DROP CONSTRAINT IF EXISTS audit_events_user_id_fkey;
+
+-- Drafts record the canonical form of their email, a blind index once PII is encrypted, so
+-- purging an account also removes drafts started with its email
+ALTER TABLE registration_drafts ADD COLUMN IF NOT EXISTS email_canonical TEXT;
+CREATE INDEX IF NOT EXISTS idx_registration_drafts_email_canonical ON registration_drafts(email_canonical);
@@ -0,0 +1,19 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (
+    id,
+    user_id,
+    type,
+    ip_address,
+    user_agent,
+    details
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+);
+
+-- name: ListUserAuditEvents :many
+SELECT * FROM audit_events
+WHERE user_id = $1
+ORDER BY created_at, id;
+
+-- name: DeleteUserAuditEvents :exec
+DELETE FROM audit_events WHERE user_id = $1;
@@ -0,0 +1,48 @@
+-- name: CreateDataExport :exec
+INSERT INTO data_exports (
+    id,
+    user_id,
+    format,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+);
+
+-- name: GetDataExport :one
+SELECT * FROM data_exports
+WHERE id = $1 AND expires_at > NOW();
+
+-- name: GetLatestDataExport :one
+SELECT * FROM data_exports
+WHERE user_id = $1 AND format = $2 AND expires_at > NOW()
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: CompleteDataExport :execrows
+UPDATE data_exports
+SET status = 'ready', payload = $2, pii_key_id = $3, completed_at = NOW()
+WHERE id = $1 AND status = 'pending';
+
+-- name: CountUserExportRecords :one
+SELECT
+    (SELECT COUNT(*) FROM sessions WHERE sessions.user_id = $1)
+    + (SELECT COUNT(*) FROM audit_events WHERE audit_events.user_id = $1) AS records;
+
+-- name: GetUserDataChangedAt :one
+-- When the data an export of the user holds last changed. Export downloads are left out, or
+-- every download would make the export it served out of date.
+SELECT GREATEST(
+    (SELECT updated_at FROM users WHERE users.id = $1),
+    (SELECT MAX(created_at) FROM sessions WHERE sessions.user_id = $1),
+    (SELECT MAX(created_at) FROM audit_events
+     WHERE audit_events.user_id = $1 AND audit_events.type <> 'data_export.downloaded')
+)::timestamptz AS changed_at;
+
+-- name: DeleteDataExport :exec
+DELETE FROM data_exports WHERE id = $1;
+
+-- name: DeleteExpiredDataExports :execrows
+DELETE FROM data_exports WHERE expires_at <= NOW();
+
+-- name: DeleteUserDataExports :exec
+DELETE FROM data_exports WHERE user_id = $1;
@@ -0,0 +1,33 @@
+-- name: ReserveIdempotencyKey :execrows
+-- Claims the key unless a live (unexpired) record holds it
+INSERT INTO idempotency_keys (
+    scope,
+    key,
+    fingerprint,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (scope, key) DO UPDATE
+SET fingerprint = EXCLUDED.fingerprint,
+    status = 'in_progress',
+    response_status = NULL,
+    response_body = NULL,
+    created_at = NOW(),
+    expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= NOW();
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE scope = $1 AND key = $2 AND expires_at > NOW();
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET status = 'completed', response_status = $3, response_body = $4, expires_at = $5
+WHERE scope = $1 AND key = $2;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys WHERE expires_at <= NOW();
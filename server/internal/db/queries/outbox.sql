@@ -0,0 +1,51 @@
+-- name: InsertOutboxEvent :exec
+INSERT INTO outbox (
+    id,
+    event_type,
+    aggregate_id,
+    payload
+) VALUES (
+    $1, $2, $3, $4
+)
+-- Events with deterministic ids (webhook fan-out) can be enqueued again by a retried handler
+ON CONFLICT (id) DO NOTHING;
+
+-- name: ClaimOutboxEvents :many
+-- Leases due events until locked_until in one short statement. SKIP LOCKED lets several
+-- dispatchers poll concurrently without handing out the same event twice; the lease keeps
+-- it from being claimed again while its handlers run.
+UPDATE outbox
+SET locked_until = @locked_until
+WHERE id IN (
+    SELECT id FROM outbox
+    WHERE status = 'pending'
+      AND next_attempt_at <= NOW()
+      AND (locked_until IS NULL OR locked_until <= NOW())
+    ORDER BY next_attempt_at
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxEventDelivered :exec
+UPDATE outbox
+SET status = 'delivered', attempts = attempts + 1, delivered_at = NOW(), last_error = NULL, locked_until = NULL
+WHERE id = $1;
+
+-- name: MarkOutboxEventRetry :exec
+UPDATE outbox
+SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3, locked_until = NULL
+WHERE id = $1;
+
+-- name: MarkOutboxEventDead :exec
+UPDATE outbox
+SET status = 'dead', attempts = attempts + 1, last_error = $2, locked_until = NULL
+WHERE id = $1;
+
+-- name: DeleteDeliveredOutboxEvents :execrows
+DELETE FROM outbox WHERE status = 'delivered' AND delivered_at < $1;
+
+-- name: DeleteOutboxEventsByAggregate :exec
+-- Removes every event about an aggregate, including undelivered ones, e.g. when its data
+-- must be erased
+DELETE FROM outbox WHERE aggregate_id = $1;
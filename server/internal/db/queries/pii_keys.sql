@@ -0,0 +1,7 @@
+-- name: ListPIIKeys :many
+SELECT * FROM pii_keys ORDER BY created_at, id;
+
+-- name: CreatePIIKey :exec
+INSERT INTO pii_keys (id, purpose, wrapped_key, master_key_id)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,34 @@
+-- name: CreateRefreshToken :exec
+INSERT INTO refresh_tokens (
+    id,
+    user_id,
+    family_id,
+    token_hash,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5
+);
+
+-- name: GetRefreshTokenByHash :one
+-- Tokens of deleted accounts are not found, even before they are revoked
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1
+  AND EXISTS (SELECT 1 FROM users WHERE users.id = refresh_tokens.user_id AND users.deleted_at IS NULL);
+
+-- name: MarkRefreshTokenUsed :execrows
+UPDATE refresh_tokens
+SET used_at = NOW()
+WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: DeleteUserRefreshTokens :exec
+DELETE FROM refresh_tokens WHERE user_id = $1;
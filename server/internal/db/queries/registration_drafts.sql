@@ -0,0 +1,36 @@
+-- name: CreateRegistrationDraft :one
+INSERT INTO registration_drafts (
+    id,
+    token_hash,
+    expires_at
+) VALUES (
+    $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetRegistrationDraft :one
+SELECT * FROM registration_drafts
+WHERE id = $1 AND token_hash = $2 AND expires_at > NOW();
+
+-- name: UpdateRegistrationDraft :one
+UPDATE registration_drafts
+SET data = @data,
+    sealed_data = @sealed_data,
+    pii_key_id = @pii_key_id,
+    email_canonical = @email_canonical,
+    completed_steps = @completed_steps,
+    expires_at = @expires_at,
+    updated_at = NOW()
+WHERE id = @id AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteRegistrationDraft :execrows
+DELETE FROM registration_drafts WHERE id = $1 AND expires_at > NOW();
+
+-- name: DeleteUserRegistrationDrafts :exec
+-- Removes the drafts saved with the account's email, e.g. when its data must be erased
+DELETE FROM registration_drafts
+WHERE email_canonical = (SELECT email_canonical FROM users WHERE id = $1);
+
+-- name: DeleteExpiredRegistrationDrafts :execrows
+DELETE FROM registration_drafts WHERE expires_at <= NOW();
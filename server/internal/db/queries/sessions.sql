@@ -0,0 +1,29 @@
+-- name: CreateSession :exec
+INSERT INTO sessions (
+    id,
+    user_id,
+    token_hash,
+    user_agent,
+    ip_address,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+);
+
+
+-- name: DeleteSessionByTokenHash :exec
+DELETE FROM sessions WHERE token_hash = $1;
+
+-- name: DeleteUserSessions :exec
+DELETE FROM sessions WHERE user_id = $1;
+
+-- name: GetSessionByTokenHash :one
+-- Sessions of deleted accounts are revoked on deletion; the user check covers a failed revoke
+SELECT * FROM sessions
+WHERE token_hash = $1 AND expires_at > NOW()
+  AND EXISTS (SELECT 1 FROM users WHERE users.id = sessions.user_id AND users.deleted_at IS NULL);
+
+-- name: ListUserSessions :many
+SELECT * FROM sessions
+WHERE user_id = $1
+ORDER BY created_at, id;
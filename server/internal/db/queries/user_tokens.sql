@@ -0,0 +1,37 @@
+-- name: CreateUserToken :exec
+INSERT INTO user_tokens (
+    id,
+    user_id,
+    purpose,
+    token_hash,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5
+);
+
+-- name: ConsumeUserToken :one
+-- Marks the token used and returns its owner in one statement so a token can only be redeemed once
+UPDATE user_tokens
+SET used_at = NOW()
+WHERE token_hash = $1
+  AND purpose = $2
+  AND used_at IS NULL
+  AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: GetUserTokenOwner :one
+-- Returns the owner of a token that ConsumeUserToken would redeem, without redeeming it
+SELECT user_id
+FROM user_tokens
+WHERE token_hash = $1
+  AND purpose = $2
+  AND used_at IS NULL
+  AND expires_at > NOW();
+
+-- name: InvalidateUserTokens :exec
+UPDATE user_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
+
+-- name: DeleteUserTokens :exec
+DELETE FROM user_tokens WHERE user_id = $1;
@@ -12,28 +12,199 @@ INSERT INTO users (
     username,
     password_hash,
     terms_accepted,
-    newsletter
+    newsletter,
+    email_canonical,
+    username_canonical,
+    phone_region,
+    phone_type,
+    username_skeleton,
+    phone_index,
+    pii_key_id
 ) VALUES (
     $1, $2, $3, $4, $5,
     $6, $7, $8, $9, $10,
-    $11, $12, $13
+    $11, $12, $13, $14, $15,
+    $16, $17, $18, $19, $20
 );
 
+-- Email and phone are matched on any of several lookup keys: the blind index, and the
+-- plaintext value for rows not yet encrypted. The existence checks count deleted accounts
+-- awaiting purge when include_deleted is set, keeping their identifiers reserved; lookups
+-- never return them.
+
 -- name: CheckEmailExists :one
 SELECT EXISTS(
-    SELECT 1 FROM users WHERE email = $1
+    SELECT 1 FROM users
+    WHERE email_canonical = ANY(@keys::text[]) AND (deleted_at IS NULL OR @include_deleted::boolean)
 );
 
 -- name: CheckUsernameExists :one
 SELECT EXISTS(
-    SELECT 1 FROM users WHERE username = $1
+    SELECT 1 FROM users
+    WHERE username_canonical = @username_canonical AND (deleted_at IS NULL OR @include_deleted::boolean)
 );
 
 -- name: CheckPhoneExists :one
 SELECT EXISTS(
-    SELECT 1 FROM users WHERE phone = $1 AND phone IS NOT NULL
+    SELECT 1 FROM users
+    WHERE phone_index = ANY(@keys::text[]) AND (deleted_at IS NULL OR @include_deleted::boolean)
+);
+
+-- name: CheckUsernameLookalikeExists :one
+-- Another account whose username looks the same; an identical username is not a lookalike
+SELECT EXISTS(
+    SELECT 1 FROM users
+    WHERE username_skeleton = @username_skeleton AND username_canonical <> @username_canonical
+      AND (deleted_at IS NULL OR @include_deleted::boolean)
 );
 
+-- name: ListTakenUsernames :many
+-- Registered usernames equal to or looking like any of the candidates
+SELECT username_canonical, username_skeleton FROM users
+WHERE (username_canonical = ANY(@usernames::text[]) OR username_skeleton = ANY(@skeletons::text[]))
+  AND (deleted_at IS NULL OR @include_deleted::boolean);
+
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: CheckUserActive :one
+SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL);
+
+-- name: GetUserByEmail :one
+SELECT * FROM users WHERE email_canonical = ANY(@keys::text[]) AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetUserByUsername :one
+SELECT * FROM users WHERE username_canonical = $1 AND deleted_at IS NULL;
+
+-- name: MarkEmailVerified :exec
+UPDATE users
+SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email_verified_at IS NULL;
+
+-- name: UpdatePasswordHash :exec
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: ReplacePasswordHash :execrows
+-- Swaps in a rehashed password only if the hash is still the one that was verified, so a
+-- password reset that lands in between isn't undone
+UPDATE users
+SET password_hash = @new_hash, updated_at = NOW()
+WHERE id = @id AND password_hash = @old_hash;
+
+-- name: CountPasswordHashesByKey :many
+-- Groups users by hash algorithm and pepper key ID ('' for hashes without a pepper)
+SELECT
+    (CASE WHEN password_hash LIKE '$2_$%' THEN 'bcrypt'
+          ELSE split_part(password_hash, '$', 2) END)::text AS algorithm,
+    COALESCE(substring(password_hash FROM ',kid=([A-Za-z0-9.-]+)\$'), '')::text AS key_id,
+    COUNT(*) AS users
+FROM users
+WHERE deleted_at IS NULL
+GROUP BY 1, 2
+ORDER BY 1, 2;
+
+-- name: ListUsersMissingPhoneDetails :many
+SELECT id, phone, pii_key_id FROM users
+WHERE phone IS NOT NULL AND phone_type IS NULL
+ORDER BY id
+LIMIT $1;
+
+-- name: GetUserPhoneForUpdate :one
+SELECT phone, pii_key_id FROM users
+WHERE id = $1
+FOR UPDATE;
+
+-- name: SetPhoneDetails :exec
+UPDATE users
+SET phone = @phone,
+    phone_index = @phone_index,
+    phone_region = @phone_region,
+    phone_type = @phone_type
+WHERE id = @id;
+
+-- name: ListPlaintextUsers :many
+-- Rows whose PII is not encrypted yet, locked for the encryption pass
+SELECT * FROM users
+WHERE pii_key_id IS NULL AND purged_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: EncryptUserPII :execrows
+UPDATE users
+SET first_name = @first_name,
+    last_name = @last_name,
+    email = @email,
+    phone = @phone,
+    street = @street,
+    city = @city,
+    state = @state,
+    email_canonical = @email_canonical,
+    phone_index = @phone_index,
+    pii_key_id = @pii_key_id
+WHERE id = @id AND pii_key_id IS NULL;
+
+-- name: ListUserIdentities :many
+-- A page of accounts for recomputing canonical forms, in id order after @after_id
+SELECT id, email, username, email_canonical, username_canonical, username_skeleton, pii_key_id
+FROM users
+WHERE id > @after_id AND purged_at IS NULL
+ORDER BY id
+LIMIT @row_limit;
+
+-- name: SetUserCanonicalForms :execrows
+-- Skips the row if its email or username changed since it was read
+UPDATE users
+SET email_canonical = @email_canonical,
+    username_canonical = @username_canonical,
+    username_skeleton = @username_skeleton
+WHERE id = @id AND email = @email AND username = @username;
+
+-- name: SoftDeleteUser :execrows
+UPDATE users
+SET deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: ListPurgeableUsers :many
+-- Deleted accounts whose grace period is over, locked for the purge
+SELECT id, deleted_at FROM users
+WHERE deleted_at <= $1 AND purged_at IS NULL
+ORDER BY deleted_at
+LIMIT $2
+FOR UPDATE SKIP LOCKED;
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1;
+
+-- name: AnonymizeUser :exec
+-- Blanks a deleted account's personal data but keeps the row. The identifiers are replaced
+-- with values no registration can produce, keeping them unique.
+UPDATE users
+SET first_name = '',
+    last_name = '',
+    email = '',
+    phone = NULL,
+    street = '',
+    city = '',
+    state = '',
+    country = '',
+    username = 'deleted-' || id::text,
+    password_hash = '',
+    newsletter = FALSE,
+    email_verified_at = NULL,
+    email_canonical = 'deleted:' || id::text,
+    username_canonical = 'deleted:' || id::text,
+    username_skeleton = '',
+    phone_region = NULL,
+    phone_type = NULL,
+    phone_index = NULL,
+    pii_key_id = NULL,
+    purged_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
 -- name: HealthCheck :one
 SELECT 1;
 
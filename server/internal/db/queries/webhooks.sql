@@ -0,0 +1,58 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (
+    id,
+    url,
+    secret,
+    secret_key_id,
+    events
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: GetWebhookEndpoint :one
+SELECT * FROM webhook_endpoints WHERE id = $1;
+
+-- name: ListWebhookEndpoints :many
+SELECT * FROM webhook_endpoints ORDER BY created_at;
+
+-- name: ListActiveWebhookEndpointsForEvent :many
+SELECT * FROM webhook_endpoints
+WHERE active AND @event_type::text = ANY(events)
+ORDER BY created_at;
+
+-- name: ListPlaintextWebhookSecrets :many
+SELECT * FROM webhook_endpoints
+WHERE secret_key_id IS NULL
+ORDER BY created_at
+LIMIT $1
+FOR UPDATE;
+
+-- name: EncryptWebhookSecret :execrows
+UPDATE webhook_endpoints
+SET secret = $2, secret_key_id = $3
+WHERE id = $1 AND secret_key_id IS NULL;
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints WHERE id = $1;
+
+-- name: InsertWebhookDelivery :exec
+INSERT INTO webhook_deliveries (
+    id,
+    endpoint_id,
+    event_id,
+    event_type,
+    attempt,
+    status_code,
+    success,
+    error,
+    duration_ms
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+);
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
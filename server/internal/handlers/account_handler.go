@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"tyk-registration-server/internal/middleware"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/response"
+	"tyk-registration-server/internal/services"
+)
+
+type AccountHandler struct {
+	accounts services.AccountService
+	audit    services.AuditService
+	cookie   SessionCookieConfig
+}
+
+func NewAccountHandler(accounts services.AccountService, audit services.AuditService, cookie SessionCookieConfig) *AccountHandler {
+	return &AccountHandler{accounts: accounts, audit: audit, cookie: cookie}
+}
+
+// Delete schedules the signed-in user's account for deletion and signs them out
+func (h *AccountHandler) Delete(c *fiber.Ctx) error {
+	ctx := c.Context()
+	userID := middleware.GetUserIDFromCtx(c)
+
+	purgeAfter, err := h.accounts.Delete(ctx, userID)
+	if errors.Is(err, services.ErrAccountNotFound) {
+		return response.SendError(c, http.StatusNotFound, response.NewNotFoundError("Account not found"))
+	}
+	if err != nil {
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to delete account"))
+	}
+
+	h.audit.Record(ctx, models.AuditEvent{
+		UserID:    userID,
+		Type:      models.AuditAccountDeleted,
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		Details:   map[string]string{"purge_after": purgeAfter.UTC().Format(time.RFC3339)},
+	})
+	clearSessionCookie(c, h.cookie)
+
+	return response.SendSuccess(c, http.StatusAccepted, models.AccountDeletionResponse{
+		Message:    "Account scheduled for deletion",
+		PurgeAfter: purgeAfter,
+	})
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SessionCookieConfig controls how the session cookie is written
+type SessionCookieConfig struct {
+	Name   string
+	Secure bool
+}
+
+func setSessionCookie(c *fiber.Ctx, cfg SessionCookieConfig, token string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     cfg.Name,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		Secure:   cfg.Secure,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+func clearSessionCookie(c *fiber.Ctx, cfg SessionCookieConfig) {
+	c.Cookie(&fiber.Cookie{
+		Name:     cfg.Name,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		Secure:   cfg.Secure,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
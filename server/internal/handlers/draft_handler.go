@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"tyk-registration-server/internal/middleware"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/response"
+	"tyk-registration-server/internal/services"
+)
+
+// DraftTokenHeader carries the secret returned when a draft is created. It is a header
+// rather than part of the URL so it stays out of access logs and browser history.
+const DraftTokenHeader = "X-Draft-Token"
+
+// DraftHandler serves resumable multi-step registrations
+type DraftHandler struct {
+	drafts services.DraftService
+	users  services.UserService
+}
+
+func NewDraftHandler(drafts services.DraftService, users services.UserService) *DraftHandler {
+	return &DraftHandler{drafts: drafts, users: users}
+}
+
+func (h *DraftHandler) Create(c *fiber.Ctx) error {
+	draft, err := h.drafts.Create(c.Context())
+	if err != nil {
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to create draft"))
+	}
+	return response.SendSuccess(c, http.StatusCreated, draft)
+}
+
+func (h *DraftHandler) Get(c *fiber.Ctx) error {
+	id, ok := draftID(c)
+	if !ok {
+		return sendDraftNotFound(c)
+	}
+	draft, err := h.drafts.Get(c.Context(), id, c.Get(DraftTokenHeader))
+	if errors.Is(err, services.ErrDraftNotFound) {
+		return sendDraftNotFound(c)
+	}
+	if err != nil {
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to load draft"))
+	}
+	return response.SendSuccess(c, http.StatusOK, draft)
+}
+
+// SaveStep validates and stores one step of the form. Password fields are checked but dropped.
+func (h *DraftHandler) SaveStep(c *fiber.Ctx) error {
+	id, ok := draftID(c)
+	if !ok {
+		return sendDraftNotFound(c)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(c.Body(), &fields); err != nil {
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("Invalid JSON payload", nil))
+	}
+
+	draft, failure, err := h.drafts.SaveStep(c.Context(), id, c.Get(DraftTokenHeader), c.Params("step"), fields)
+	switch {
+	case errors.Is(err, services.ErrUnknownDraftStep):
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("There are validation errors", map[string]string{
+			"step": "Step must be one of: personal, address, account",
+		}))
+	case errors.Is(err, services.ErrDraftNotFound):
+		return sendDraftNotFound(c)
+	case errors.Is(err, services.ErrInvalidDraftData):
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("Invalid JSON payload", nil))
+	case err != nil:
+		log.Printf("failed to save draft step: %v", err)
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to save draft"))
+	case failure != nil:
+		return middleware.SendValidationFailure(c, failure)
+	}
+	return response.SendSuccess(c, http.StatusOK, draft)
+}
+
+// LoadRegistration assembles the registration request from the draft and the password in
+// the body, for the validation pipeline that runs before Finalize
+func (h *DraftHandler) LoadRegistration(c *fiber.Ctx) error {
+	id, ok := draftID(c)
+	if !ok {
+		return sendDraftNotFound(c)
+	}
+	var password models.FinalizeDraftRequest
+	if err := c.BodyParser(&password); err != nil {
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("Invalid JSON payload", nil))
+	}
+
+	req, err := h.drafts.Registration(c.Context(), id, c.Get(DraftTokenHeader), password)
+	switch {
+	case errors.Is(err, services.ErrDraftNotFound):
+		return sendDraftNotFound(c)
+	case errors.Is(err, services.ErrInvalidDraftData):
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("Invalid JSON payload", nil))
+	case err != nil:
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to load draft"))
+	}
+
+	middleware.SetRegistrationInCtx(c, req)
+	return c.Next()
+}
+
+// Finalize creates the user from a validated draft and discards the draft in the same
+// transaction, so a draft registers at most one user
+func (h *DraftHandler) Finalize(c *fiber.Ctx) error {
+	req := middleware.GetRegistrationFromCtx(c)
+	if req == nil {
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("Invalid request body", nil))
+	}
+	id, _ := draftID(c)
+
+	userID, err := h.users.RegisterDraft(c.Context(), id, req)
+	if errors.Is(err, services.ErrDraftNotFound) {
+		return sendDraftNotFound(c)
+	}
+	if err != nil {
+		return sendRegistrationError(c, err)
+	}
+
+	return response.SendSuccess(c, http.StatusCreated, models.RegistrationResponse{
+		UserID:  userID.String(),
+		Message: "Registration successful",
+	})
+}
+
+func draftID(c *fiber.Ctx) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Params("id"))
+	return id, err == nil
+}
+
+func sendDraftNotFound(c *fiber.Ctx) error {
+	return response.SendError(c, http.StatusNotFound, response.NewNotFoundError("Draft not found or expired"))
+}
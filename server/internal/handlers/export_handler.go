@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"tyk-registration-server/internal/middleware"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/response"
+	"tyk-registration-server/internal/services"
+)
+
+// exportRetryAfter is the polling interval suggested while an export is being built
+const exportRetryAfter = "30"
+
+var exportContentTypes = map[string]string{
+	models.ExportFormatJSON: fiber.MIMEApplicationJSON,
+	models.ExportFormatZIP:  "application/zip",
+}
+
+type ExportHandler struct {
+	exports services.ExportService
+	audit   services.AuditService
+}
+
+func NewExportHandler(exports services.ExportService, audit services.AuditService) *ExportHandler {
+	return &ExportHandler{exports: exports, audit: audit}
+}
+
+// Handle serves the signed-in user's data export, or 202 while it is being built
+func (h *ExportHandler) Handle(c *fiber.Ctx) error {
+	ctx := c.Context()
+	userID := middleware.GetUserIDFromCtx(c)
+	format := c.Query("format", models.ExportFormatJSON)
+
+	export, err := h.exports.Export(ctx, userID, format)
+	if errors.Is(err, services.ErrUnknownExportFormat) {
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("There are validation errors", map[string]string{
+			"format": "Format must be json or zip",
+		}))
+	}
+	if err != nil {
+		log.Printf("failed to export data for user %s: %v", userID, err)
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to export data"))
+	}
+
+	if export.Status == models.ExportStatusPending {
+		c.Set(fiber.HeaderRetryAfter, exportRetryAfter)
+		return response.SendSuccess(c, http.StatusAccepted, models.DataExportPendingResponse{
+			ExportID:  export.ID.String(),
+			Status:    export.Status,
+			CreatedAt: export.CreatedAt,
+			Message:   "Your export is being prepared; repeat this request to download it",
+		})
+	}
+
+	h.audit.Record(ctx, models.AuditEvent{
+		UserID:    userID,
+		Type:      models.AuditDataExportDownloaded,
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		Details:   map[string]string{"format": format},
+	})
+
+	c.Set(fiber.HeaderContentType, exportContentTypes[format])
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="data-export-%s.%s"`, export.CreatedAt.Format("2006-01-02"), format))
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(http.StatusOK).Send(export.Payload)
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/response"
+	"tyk-registration-server/internal/services"
+	"tyk-registration-server/internal/validator"
+)
+
+type ForgotPasswordHandler struct {
+	passwords services.PasswordService
+}
+
+func NewForgotPasswordHandler(passwords services.PasswordService) *ForgotPasswordHandler {
+	return &ForgotPasswordHandler{passwords: passwords}
+}
+
+// Handle always answers 202, whether or not an account exists and even for an address that
+// can't belong to one. The email itself is sent by the outbox.
+func (h *ForgotPasswordHandler) Handle(c *fiber.Ctx) error {
+	var req models.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err == nil && validator.ValidateEmail(req.Email) {
+		if err := h.passwords.RequestReset(c.Context(), req.Email); err != nil {
+			// Logged rather than returned: a 500 would tell the caller the address exists
+			log.Printf("failed to process password reset request: %v", err)
+		}
+	}
+
+	return response.SendSuccess(c, http.StatusAccepted, fiber.Map{
+		"message": "If an account exists for that email, a password reset link has been sent",
+	})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/response"
+	"tyk-registration-server/internal/services"
+)
+
+type LoginHandler struct {
+	users    services.UserService
+	sessions services.SessionService
+	tokens   services.TokenService
+	audit    services.AuditService
+	cookie   SessionCookieConfig
+}
+
+func NewLoginHandler(users services.UserService, sessions services.SessionService, tokens services.TokenService, audit services.AuditService, cookie SessionCookieConfig) *LoginHandler {
+	return &LoginHandler{users: users, sessions: sessions, tokens: tokens, audit: audit, cookie: cookie}
+}
+
+func (h *LoginHandler) Handle(c *fiber.Ctx) error {
+	var req models.LoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("Invalid JSON payload", nil))
+	}
+
+	fields := map[string]string{}
+	if strings.TrimSpace(req.Identifier) == "" {
+		fields["identifier"] = "Username or email is required"
+	}
+	if req.Password == "" {
+		fields["password"] = "Password is required"
+	}
+	if len(fields) > 0 {
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("There are validation errors", fields))
+	}
+
+	ctx := c.Context()
+	user, err := h.users.Authenticate(ctx, req.Identifier, req.Password)
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		return response.SendError(c, http.StatusUnauthorized, response.NewAuthenticationError("Invalid username/email or password"))
+	}
+	if err != nil {
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to authenticate"))
+	}
+
+	token, session, err := h.sessions.Create(ctx, user.ID, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to create session"))
+	}
+	setSessionCookie(c, h.cookie, token, session.ExpiresAt)
+
+	// Cookie-less clients (other services, mobile) use the bearer tokens instead
+	pair, err := h.tokens.Issue(ctx, user.ID)
+	if err != nil {
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to issue tokens"))
+	}
+
+	h.audit.Record(ctx, models.AuditEvent{
+		UserID:    user.ID,
+		Type:      models.AuditLogin,
+		IPAddress: session.IPAddress,
+		UserAgent: session.UserAgent,
+	})
+
+	resp := models.LoginResponse{
+		UserID:        user.ID.String(),
+		Username:      user.Username,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		ExpiresAt:     session.ExpiresAt,
+		Message:       "Login successful",
+		TokenPair:     *pair,
+	}
+	return response.SendSuccess(c, http.StatusOK, resp)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/response"
+	"tyk-registration-server/internal/services"
+)
+
+type LogoutHandler struct {
+	sessions services.SessionService
+	tokens   services.TokenService
+	cookie   SessionCookieConfig
+}
+
+func NewLogoutHandler(sessions services.SessionService, tokens services.TokenService, cookie SessionCookieConfig) *LogoutHandler {
+	return &LogoutHandler{sessions: sessions, tokens: tokens, cookie: cookie}
+}
+
+// Handle ends the cookie session (if any) and revokes the refresh token family (if one is sent).
+// Logging out is idempotent: unknown or missing credentials still succeed.
+func (h *LogoutHandler) Handle(c *fiber.Ctx) error {
+	var req models.LogoutRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.SendError(c, http.StatusBadRequest, response.NewValidationError("Invalid JSON payload", nil))
+		}
+	}
+
+	ctx := c.Context()
+	if err := h.sessions.Revoke(ctx, c.Cookies(h.cookie.Name)); err != nil {
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to end session"))
+	}
+	if req.RefreshToken != "" {
+		if err := h.tokens.Revoke(ctx, req.RefreshToken); err != nil {
+			return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to revoke refresh token"))
+		}
+	}
+	clearSessionCookie(c, h.cookie)
+
+	return response.SendSuccess(c, http.StatusOK, fiber.Map{"message": "Logged out"})
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"tyk-registration-server/internal/response"
+	"tyk-registration-server/internal/services"
+)
+
+type PasswordKeyHandler struct {
+	users services.UserService
+}
+
+func NewPasswordKeyHandler(users services.UserService) *PasswordKeyHandler {
+	return &PasswordKeyHandler{users: users}
+}
+
+// Report shows how many users are on each pepper key, so an old key can be retired once
+// nobody uses it
+func (h *PasswordKeyHandler) Report(c *fiber.Ctx) error {
+	report, err := h.users.PasswordKeyReport(c.Context())
+	if err != nil {
+		log.Printf("failed to build password key report: %v", err)
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to build password key report"))
+	}
+	return response.SendSuccess(c, http.StatusOK, report)
+}
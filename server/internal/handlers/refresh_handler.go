@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/response"
+	"tyk-registration-server/internal/services"
+)
+
+type RefreshHandler struct {
+	tokens services.TokenService
+}
+
+func NewRefreshHandler(tokens services.TokenService) *RefreshHandler {
+	return &RefreshHandler{tokens: tokens}
+}
+
+func (h *RefreshHandler) Handle(c *fiber.Ctx) error {
+	var req models.RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("Invalid JSON payload", nil))
+	}
+	if req.RefreshToken == "" {
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("There are validation errors", map[string]string{
+			"refresh_token": "Refresh token is required",
+		}))
+	}
+
+	pair, err := h.tokens.Refresh(c.Context(), req.RefreshToken)
+	if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+		return response.SendError(c, http.StatusUnauthorized, response.NewAuthenticationError("Invalid or expired refresh token"))
+	}
+	if err != nil {
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to refresh token"))
+	}
+
+	return response.SendSuccess(c, http.StatusOK, pair)
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
@@ -28,7 +30,7 @@ func (h *RegisterHandler) Handle(c *fiber.Ctx) error {
 	ctx := c.Context()
 	userID, svcErr := h.service.Register(ctx, req)
 	if svcErr != nil {
-		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to create user"))
+		return sendRegistrationError(c, svcErr)
 	}
 
 	resp := models.RegistrationResponse{
@@ -37,3 +39,14 @@ func (h *RegisterHandler) Handle(c *fiber.Ctx) error {
 	}
 	return response.SendSuccess(c, http.StatusCreated, resp)
 }
+
+// sendRegistrationError answers a failed UserService.Register. A conflict means a concurrent
+// registration took the same email, username or phone after validation passed.
+func sendRegistrationError(c *fiber.Ctx, err error) error {
+	var conflict *services.ConflictError
+	if errors.As(err, &conflict) {
+		return response.SendError(c, http.StatusConflict, response.NewBusinessError("Business validation failed", conflict.Fields))
+	}
+	log.Printf("failed to create user: %v", err)
+	return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to create user"))
+}
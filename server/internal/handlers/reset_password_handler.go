@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/response"
+	"tyk-registration-server/internal/services"
+)
+
+type ResetPasswordHandler struct {
+	passwords services.PasswordService
+}
+
+func NewResetPasswordHandler(passwords services.PasswordService) *ResetPasswordHandler {
+	return &ResetPasswordHandler{passwords: passwords}
+}
+
+func (h *ResetPasswordHandler) Handle(c *fiber.Ctx) error {
+	var req models.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("Invalid JSON payload", nil))
+	}
+
+	fields := map[string]string{}
+	if req.Token == "" {
+		fields["token"] = "Reset token is required"
+	}
+	if req.Password != req.ConfirmPassword {
+		fields["confirm_password"] = "Passwords must match"
+	}
+	if len(fields) > 0 {
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("There are validation errors", fields))
+	}
+
+	err := h.passwords.ResetPassword(c.Context(), req.Token, req.Password)
+	var rejected *services.PasswordRejectedError
+	switch {
+	case errors.As(err, &rejected):
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("There are validation errors", map[string]string{
+			"password": rejected.Message,
+		}))
+	case errors.Is(err, services.ErrInvalidResetToken):
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("There are validation errors", map[string]string{
+			"token": "Reset link is invalid or has expired",
+		}))
+	case err != nil:
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to reset password"))
+	}
+
+	return response.SendSuccess(c, http.StatusOK, fiber.Map{"message": "Password has been reset"})
+}
@@ -1,41 +1,31 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
-	"strings"
 
 	"github.com/gofiber/fiber/v2"
 
-	"tyk-registration-server/internal/models"
-	"tyk-registration-server/internal/repositories"
 	"tyk-registration-server/internal/response"
+	"tyk-registration-server/internal/services"
 )
 
 type UsernameHandler struct {
-	repo repositories.UserRepository
+	service services.UsernameService
 }
 
-func NewUsernameHandler(repo repositories.UserRepository) *UsernameHandler {
-	return &UsernameHandler{repo: repo}
+func NewUsernameHandler(service services.UsernameService) *UsernameHandler {
+	return &UsernameHandler{service: service}
 }
 
+// Handle reports whether a username is free. Taken names are matched by canonical form, so
+// "JohnDoe" is unavailable once "johndoe" is registered. An unavailable name comes with
+// suggestions, built from first_name and last_name too when the client sends them.
 func (h *UsernameHandler) Handle(c *fiber.Ctx) error {
-	username := strings.TrimSpace(c.Query("username"))
-	if len(username) < 6 {
-		return response.SendSuccess(c, http.StatusOK, models.UsernameAvailabilityResponse{
-			Username:  username,
-			Available: false,
-		})
-	}
-
-	ctx := c.Context()
-	exists, err := h.repo.UsernameExists(ctx, username)
+	resp, err := h.service.Availability(c.Context(), c.Query("username"), c.Query("first_name"), c.Query("last_name"))
 	if err != nil {
+		log.Printf("failed to check username availability: %v", err)
 		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to verify username"))
 	}
-
-	return response.SendSuccess(c, http.StatusOK, models.UsernameAvailabilityResponse{
-		Username:  username,
-		Available: !exists,
-	})
+	return response.SendSuccess(c, http.StatusOK, resp)
 }
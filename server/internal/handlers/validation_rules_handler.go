@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"tyk-registration-server/internal/response"
+	"tyk-registration-server/internal/validator"
+)
+
+type ValidationRulesHandler struct {
+	rules *validator.RuleEngine
+}
+
+func NewValidationRulesHandler(rules *validator.RuleEngine) *ValidationRulesHandler {
+	return &ValidationRulesHandler{rules: rules}
+}
+
+// Reload re-reads the rules file. An invalid file is reported and the current rules stay active.
+func (h *ValidationRulesHandler) Reload(c *fiber.Ctx) error {
+	if err := h.rules.Reload(); err != nil {
+		log.Printf("failed to reload validation rules: %v", err)
+		return response.SendError(c, http.StatusUnprocessableEntity, response.NewValidationError(
+			"Validation rules were not reloaded: "+err.Error(), nil))
+	}
+	return response.SendSuccess(c, http.StatusOK, fiber.Map{
+		"message": "Validation rules reloaded",
+		"source":  h.rules.Source(),
+	})
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"tyk-registration-server/internal/response"
+	"tyk-registration-server/internal/services"
+)
+
+type VerifyEmailHandler struct {
+	verification services.VerificationService
+}
+
+func NewVerifyEmailHandler(verification services.VerificationService) *VerifyEmailHandler {
+	return &VerifyEmailHandler{verification: verification}
+}
+
+func (h *VerifyEmailHandler) Handle(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("There are validation errors", map[string]string{
+			"token": "Verification token is required",
+		}))
+	}
+
+	err := h.verification.VerifyEmail(c.Context(), token)
+	if errors.Is(err, services.ErrInvalidVerificationToken) {
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("There are validation errors", map[string]string{
+			"token": "Verification link is invalid or has expired",
+		}))
+	}
+	if err != nil {
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to verify email"))
+	}
+
+	return response.SendSuccess(c, http.StatusOK, fiber.Map{"message": "Email verified"})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/response"
+	"tyk-registration-server/internal/services"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+// WebhookHandler serves the admin API for webhook endpoints and their delivery logs
+type WebhookHandler struct {
+	webhooks services.WebhookService
+}
+
+func NewWebhookHandler(webhooks services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhooks: webhooks}
+}
+
+// Create registers an endpoint and returns it with its signing secret. The secret is not
+// shown again.
+func (h *WebhookHandler) Create(c *fiber.Ctx) error {
+	var req models.CreateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("Invalid JSON payload", nil))
+	}
+	if fields := validateWebhookRequest(&req); len(fields) > 0 {
+		return response.SendError(c, http.StatusBadRequest, response.NewValidationError("There are validation errors", fields))
+	}
+
+	endpoint, err := h.webhooks.CreateEndpoint(c.Context(), &req)
+	if err != nil {
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to create webhook"))
+	}
+
+	return response.SendSuccess(c, http.StatusCreated, models.CreateWebhookResponse{
+		WebhookEndpoint: *endpoint,
+		Secret:          endpoint.Secret,
+	})
+}
+
+func (h *WebhookHandler) List(c *fiber.Ctx) error {
+	endpoints, err := h.webhooks.ListEndpoints(c.Context())
+	if err != nil {
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to list webhooks"))
+	}
+	return response.SendSuccess(c, http.StatusOK, fiber.Map{"webhooks": endpoints})
+}
+
+func (h *WebhookHandler) Delete(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.SendError(c, http.StatusNotFound, response.NewNotFoundError("Webhook not found"))
+	}
+
+	err = h.webhooks.DeleteEndpoint(c.Context(), id)
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		return response.SendError(c, http.StatusNotFound, response.NewNotFoundError("Webhook not found"))
+	}
+	if err != nil {
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to delete webhook"))
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// Deliveries lists the most recent delivery attempts for an endpoint, newest first
+func (h *WebhookHandler) Deliveries(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.SendError(c, http.StatusNotFound, response.NewNotFoundError("Webhook not found"))
+	}
+	limit := c.QueryInt("limit", defaultDeliveryLimit)
+	if limit <= 0 || limit > maxDeliveryLimit {
+		limit = defaultDeliveryLimit
+	}
+
+	deliveries, err := h.webhooks.ListDeliveries(c.Context(), id, limit)
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		return response.SendError(c, http.StatusNotFound, response.NewNotFoundError("Webhook not found"))
+	}
+	if err != nil {
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to list webhook deliveries"))
+	}
+	return response.SendSuccess(c, http.StatusOK, fiber.Map{"deliveries": deliveries})
+}
+
+func validateWebhookRequest(req *models.CreateWebhookRequest) map[string]string {
+	fields := map[string]string{}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fields["url"] = "Must be an absolute http or https URL"
+	}
+
+	if len(req.Events) == 0 {
+		fields["events"] = "At least one event is required"
+	}
+	for _, event := range req.Events {
+		if !slices.Contains(models.WebhookEvents, event) {
+			fields["events"] = "Unknown event: " + event
+			break
+		}
+	}
+	// Store each subscribed event once
+	slices.Sort(req.Events)
+	req.Events = slices.Compact(req.Events)
+
+	return fields
+}
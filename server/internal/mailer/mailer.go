@@ -0,0 +1,66 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+const (
+	DriverLog  = "log"
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+)
+
+// Message is a single outbound email with optional HTML alternative
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers outbound email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config selects and configures a driver
+type Config struct {
+	Driver  string
+	From    string
+	SMTP    SMTPConfig
+	FileDir string
+}
+
+// New returns the Mailer for cfg.Driver
+func New(cfg Config) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverLog:
+		return NewLogMailer(), nil
+	case DriverSMTP:
+		if cfg.SMTP.Host == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the smtp mail driver")
+		}
+		return NewSMTPMailer(cfg.From, cfg.SMTP), nil
+	case DriverFile:
+		return NewFileMailer(cfg.From, cfg.FileDir)
+	case "":
+		return nil, fmt.Errorf("no mail driver configured")
+	default:
+		return nil, fmt.Errorf("unsupported mail driver %q", cfg.Driver)
+	}
+}
+
+type logMailer struct{}
+
+// NewLogMailer returns a Mailer that only logs that a message was sent, for local
+// development. Bodies are never logged: they carry single-use verification and reset links.
+func NewLogMailer() Mailer {
+	return &logMailer{}
+}
+
+func (m *logMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("mail to=%s subject=%q (body not logged)", msg.To, msg.Subject)
+	return nil
+}
@@ -0,0 +1,83 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*.tmpl
+var templatesFS embed.FS
+
+// Template names
+const (
+	TemplateVerification  = "verification"
+	TemplateWelcome       = "welcome"
+	TemplatePasswordReset = "password_reset"
+)
+
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Each email is a pair of files: <name>.txt.tmpl, which must also define a "subject" block,
+// and an optional <name>.html.tmpl. Pairs are parsed separately so their blocks don't collide.
+var emailTemplates = mustParseTemplates()
+
+func mustParseTemplates() map[string]emailTemplate {
+	names, err := fs.Glob(templatesFS, "templates/*.txt.tmpl")
+	if err != nil {
+		panic(err)
+	}
+
+	parsed := make(map[string]emailTemplate, len(names))
+	for _, path := range names {
+		name := strings.TrimSuffix(strings.TrimPrefix(path, "templates/"), ".txt.tmpl")
+
+		tpl := emailTemplate{
+			text: texttemplate.Must(texttemplate.ParseFS(templatesFS, path)),
+		}
+		htmlPath := "templates/" + name + ".html.tmpl"
+		if _, err := fs.Stat(templatesFS, htmlPath); err == nil {
+			tpl.html = htmltemplate.Must(htmltemplate.ParseFS(templatesFS, htmlPath))
+		}
+		parsed[name] = tpl
+	}
+	return parsed
+}
+
+// Render builds a Message from the named embedded template.
+// HTML output is escaped by html/template; the text body is not.
+func Render(name, to string, data interface{}) (Message, error) {
+	tpl, ok := emailTemplates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, text bytes.Buffer
+	if err := tpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := tpl.text.Execute(&text, data); err != nil {
+		return Message{}, fmt.Errorf("render %s text: %w", name, err)
+	}
+
+	msg := Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimLeft(text.String(), "\n"),
+	}
+	if tpl.html != nil {
+		var html bytes.Buffer
+		if err := tpl.html.Execute(&html, data); err != nil {
+			return Message{}, fmt.Errorf("render %s html: %w", name, err)
+		}
+		msg.HTML = html.String()
+	}
+	return msg, nil
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"tyk-registration-server/internal/response"
+)
+
+const AdminKeyHeader = "X-Admin-Key"
+
+// RequireAdminKey guards admin routes with a static API key. An empty key disables them.
+func RequireAdminKey(key string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if key == "" {
+			return response.SendError(c, http.StatusForbidden, response.NewAuthenticationError("Admin API is disabled"))
+		}
+		got := c.Get(AdminKeyHeader)
+		if subtle.ConstantTimeCompare([]byte(got), []byte(key)) != 1 {
+			return response.SendError(c, http.StatusUnauthorized, response.NewAuthenticationError("Invalid admin key"))
+		}
+		return c.Next()
+	}
+}
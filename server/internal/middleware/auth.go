@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/internal/response"
+	"tyk-registration-server/internal/services"
+)
+
+const userIDKey = "user_id"
+
+// RequireUser admits signed-in users: those sending an access token in an
+// "Authorization: Bearer" header, or else the session cookie set by login.
+// GetUserIDFromCtx returns who they are. Deleted accounts are turned away even while
+// their access tokens are unexpired.
+func RequireUser(sessions services.SessionService, tokens services.TokenService, users repositories.UserRepository, cookieName string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := authenticate(c, sessions, tokens, cookieName)
+		if err == nil {
+			var active bool
+			if active, err = users.IsActive(c.Context(), userID); err == nil && !active {
+				err = services.ErrInvalidAccessToken
+			}
+		}
+		if errors.Is(err, services.ErrInvalidSession) || errors.Is(err, services.ErrInvalidAccessToken) {
+			return response.SendError(c, http.StatusUnauthorized, response.NewAuthenticationError("Authentication required"))
+		}
+		if err != nil {
+			log.Printf("failed to authenticate request: %v", err)
+			return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to authenticate"))
+		}
+		c.Locals(userIDKey, userID)
+		return c.Next()
+	}
+}
+
+func authenticate(c *fiber.Ctx, sessions services.SessionService, tokens services.TokenService, cookieName string) (uuid.UUID, error) {
+	if header := c.Get(fiber.HeaderAuthorization); header != "" {
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return uuid.Nil, services.ErrInvalidAccessToken
+		}
+		return tokens.VerifyAccessToken(strings.TrimSpace(token))
+	}
+	session, err := sessions.Authenticate(c.Context(), c.Cookies(cookieName))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return session.UserID, nil
+}
+
+// GetUserIDFromCtx returns the user admitted by RequireUser
+func GetUserIDFromCtx(c *fiber.Ctx) uuid.UUID {
+	userID, _ := c.Locals(userIDKey).(uuid.UUID)
+	return userID
+}
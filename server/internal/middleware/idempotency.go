@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/internal/response"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	defaultIdempotencyLockTTL = time.Minute
+)
+
+type IdempotencyConfig struct {
+	// TTL is how long a completed response is replayed
+	TTL time.Duration
+	// LockTimeout bounds how long an unfinished request holds its key, so a crash mid-request
+	// doesn't block retries for the whole TTL
+	LockTimeout time.Duration
+}
+
+// Idempotency makes a route safe to retry. A request carrying an Idempotency-Key header is
+// fingerprinted by method, path and parsed body; the first successful response is stored and
+// replayed to identical retries, while reusing the key with a different body, or while the
+// first request is still running, is a 409. Keys are scoped to the caller, the signed-in user
+// or else the client IP, so one client can't replay or block another's request by guessing
+// its key. Failed responses are not stored so the client can retry them. Requests without
+// the header pass through unchanged.
+func Idempotency(keys repositories.IdempotencyRepository, cfg IdempotencyConfig) fiber.Handler {
+	if cfg.LockTimeout <= 0 {
+		cfg.LockTimeout = defaultIdempotencyLockTTL
+	}
+
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return response.SendError(c, http.StatusBadRequest, response.NewValidationError("There are validation errors", map[string]string{
+				"idempotency_key": "Idempotency-Key must be at most 255 characters",
+			}))
+		}
+
+		scope := idempotencyPrincipal(c) + " " + c.Method() + " " + c.Path()
+		fingerprint := requestFingerprint(c)
+
+		reserved, err := keys.Reserve(c.Context(), scope, key, fingerprint, time.Now().Add(cfg.LockTimeout))
+		if err != nil {
+			log.Printf("failed to reserve idempotency key: %v", err)
+			return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to process request"))
+		}
+		if !reserved {
+			return replay(c, keys, scope, key, fingerprint)
+		}
+
+		if err := c.Next(); err != nil {
+			release(c, keys, scope, key)
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if status < 200 || status >= 300 {
+			release(c, keys, scope, key)
+			return nil
+		}
+		// fasthttp reuses the response buffer once the request is done
+		body := append([]byte(nil), c.Response().Body()...)
+		if err := keys.Complete(c.Context(), scope, key, status, body, time.Now().Add(cfg.TTL)); err != nil {
+			// The response is already built; a retry will re-run the request once the lock expires
+			log.Printf("failed to store idempotent response: %v", err)
+		}
+		return nil
+	}
+}
+
+func replay(c *fiber.Ctx, keys repositories.IdempotencyRepository, scope, key, fingerprint string) error {
+	record, err := keys.Get(c.Context(), scope, key)
+	if errors.Is(err, repositories.ErrNotFound) {
+		// The record expired between Reserve and Get; the next retry claims the key
+		return response.SendError(c, http.StatusConflict, response.NewBusinessError("A request with this Idempotency-Key is already in progress", nil))
+	}
+	if err != nil {
+		log.Printf("failed to load idempotency key: %v", err)
+		return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to process request"))
+	}
+
+	if record.Fingerprint != fingerprint {
+		return response.SendError(c, http.StatusConflict, response.NewBusinessError("Idempotency-Key was already used with a different request", map[string]string{
+			"idempotency_key": "Use a new key for a different request",
+		}))
+	}
+	if !record.Completed {
+		return response.SendError(c, http.StatusConflict, response.NewBusinessError("A request with this Idempotency-Key is already in progress", nil))
+	}
+
+	c.Set(IdempotentReplayedHeader, "true")
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Status(record.ResponseStatus).Send(record.ResponseBody)
+}
+
+func release(c *fiber.Ctx, keys repositories.IdempotencyRepository, scope, key string) {
+	if err := keys.Release(c.Context(), scope, key); err != nil {
+		log.Printf("failed to release idempotency key: %v", err)
+	}
+}
+
+// idempotencyPrincipal identifies who a key belongs to: the user admitted by RequireUser, or
+// the client IP on public routes
+func idempotencyPrincipal(c *fiber.Ctx) string {
+	if userID := GetUserIDFromCtx(c); userID != uuid.Nil {
+		return "user:" + userID.String()
+	}
+	return "ip:" + c.IP()
+}
+
+func requestFingerprint(c *fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method() + " " + c.Path() + "\n"))
+	h.Write(canonicalBody(c.Body()))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalBody re-encodes a JSON body with sorted keys and no insignificant whitespace, so a
+// retry that serializes the same request differently still matches. Numbers keep their
+// literal text. A body that isn't JSON is used as is.
+func canonicalBody(body []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return body
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return canonical
+}
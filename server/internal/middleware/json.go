@@ -20,6 +20,12 @@ func ParseRegistrationJSON() fiber.Handler {
 	}
 }
 
+// SetRegistrationInCtx hands a request assembled elsewhere (e.g. from a draft) to the
+// validators and handlers that follow
+func SetRegistrationInCtx(c *fiber.Ctx, req *models.RegistrationRequest) {
+	c.Locals(registrationReqKey, req)
+}
+
 func GetRegistrationFromCtx(c *fiber.Ctx) *models.RegistrationRequest {
 	val := c.Locals(registrationReqKey)
 	if val == nil {
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"tyk-registration-server/internal/response"
+)
+
+// RequireMetricsToken guards the metrics endpoint with a static bearer token, which is
+// what Prometheus sends with `authorization: {credentials: ...}`. An empty token disables it.
+func RequireMetricsToken(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token == "" {
+			return response.SendError(c, http.StatusForbidden, response.NewAuthenticationError("Metrics are disabled"))
+		}
+		got, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return response.SendError(c, http.StatusUnauthorized, response.NewAuthenticationError("Invalid metrics token"))
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"tyk-registration-server/internal/response"
+)
+
+// Rate limit response headers (IETF draft "RateLimit header fields for HTTP")
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RateLimitPolicyHeader    = "RateLimit-Policy"
+)
+
+// RateLimit is a token bucket budget: up to Burst requests at once, refilled evenly so that
+// Burst requests are allowed per Period
+type RateLimit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// Enabled reports whether the limit applies; a zero limit disables rate limiting
+func (l RateLimit) Enabled() bool {
+	return l.Burst > 0 && l.Period > 0
+}
+
+func (l RateLimit) rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next token, when the request was refused
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps token buckets. A store shared between server instances (e.g. Redis,
+// taking the token in a Lua script) makes them enforce one budget; MemoryRateLimitStore is
+// per process.
+type RateLimitStore interface {
+	// Take removes a token from the bucket for key if one is left at now
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+// RateLimitByIP limits requests to one route per client IP. route names the bucket, so
+// routes given different names have separate budgets. If the store fails the request is let
+// through: an outage of the limiter shouldn't take registration down with it.
+func RateLimitByIP(store RateLimitStore, route string, limit RateLimit) fiber.Handler {
+	if !limit.Enabled() {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	policy := strconv.Itoa(limit.Burst) + ";w=" + strconv.Itoa(int(limit.Period.Seconds()))
+
+	return func(c *fiber.Ctx) error {
+		result, err := store.Take(c.Context(), route+":"+c.IP(), limit, time.Now())
+		if err != nil {
+			log.Printf("rate limiter unavailable for %s: %v", route, err)
+			return c.Next()
+		}
+
+		c.Set(RateLimitLimitHeader, strconv.Itoa(limit.Burst))
+		c.Set(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+		c.Set(RateLimitResetHeader, strconv.Itoa(ceilSeconds(result.Reset)))
+		c.Set(RateLimitPolicyHeader, policy)
+
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			return response.SendError(c, http.StatusTooManyRequests, response.NewRateLimitError("Too many requests, please try again later"))
+		}
+		return c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// MemoryRateLimitStore keeps buckets in process memory. Buckets that have refilled completely
+// are indistinguishable from new ones and are swept periodically.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	limit   RateLimit
+}
+
+const rateLimitSweepInterval = time.Minute
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]*tokenBucket{}}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= rateLimitSweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	b.refill(now)
+
+	rate := limit.rate()
+	var result RateLimitResult
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((float64(limit.Burst) - b.tokens) / rate * float64(time.Second))
+	return result, nil
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed.Seconds()*b.limit.rate())
+		b.updated = now
+	}
+}
+
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// Len returns the number of buckets held
+func (s *MemoryRateLimitStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"tyk-registration-server/internal/response"
+	"tyk-registration-server/internal/validator"
+)
+
+// ValidateRegistration runs the configured validation pipeline over the request parsed by
+// ParseRegistrationJSON
+func ValidateRegistration(pipeline *validator.Pipeline) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := GetRegistrationFromCtx(c)
+		if req == nil {
+			return response.SendError(c, http.StatusBadRequest, response.NewValidationError("Invalid request body", nil))
+		}
+
+		failure, err := pipeline.Run(c.Context(), req)
+		if err != nil {
+			log.Printf("registration validation failed: %v", err)
+			return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to validate registration"))
+		}
+		if failure != nil {
+			return SendValidationFailure(c, failure)
+		}
+
+		return c.Next()
+	}
+}
+
+// ValidateRegistrationStep runs the pipeline over only the fields of the step named by the
+// step query parameter, for checking one page of the client's form before submission
+func ValidateRegistrationStep(pipeline *validator.Pipeline) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		scope, err := validator.StepScope(c.Query("step"))
+		if err != nil {
+			return response.SendError(c, http.StatusBadRequest, response.NewValidationError("There are validation errors", map[string]string{
+				"step": "Step must be one of: personal, address, account",
+			}))
+		}
+		req := GetRegistrationFromCtx(c)
+		if req == nil {
+			return response.SendError(c, http.StatusBadRequest, response.NewValidationError("Invalid request body", nil))
+		}
+
+		failure, err := pipeline.RunScoped(c.Context(), req, scope)
+		if err != nil {
+			log.Printf("registration step validation failed: %v", err)
+			return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Failed to validate registration"))
+		}
+		if failure != nil {
+			return SendValidationFailure(c, failure)
+		}
+
+		return c.Next()
+	}
+}
+
+// SendValidationFailure writes a pipeline failure: 422 business_error when only business
+// rules failed, 400 validation_error otherwise
+func SendValidationFailure(c *fiber.Ctx, failure *validator.Failure) error {
+	if failure.Business {
+		return response.SendError(c, http.StatusUnprocessableEntity, response.NewBusinessError(failure.Message, failure.Fields))
+	}
+	return response.SendError(c, http.StatusBadRequest, response.NewValidationError(failure.Message, failure.Fields))
+}
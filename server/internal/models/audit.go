@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit event types
+const (
+	AuditLogin                = "login"
+	AuditDataExportDownloaded = "data_export.downloaded"
+	AuditAccountDeleted       = "account.deleted"
+	// AuditAccountPurged is the tombstone left when a deleted account is purged
+	AuditAccountPurged = "account.purged"
+)
+
+// AuditEvent records a security-relevant action on an account
+type AuditEvent struct {
+	ID        uuid.UUID         `json:"id"`
+	UserID    uuid.UUID         `json:"-"`
+	Type      string            `json:"type"`
+	IPAddress string            `json:"ip_address,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
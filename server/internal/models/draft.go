@@ -0,0 +1,27 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RegistrationDraft is a multi-step registration saved server-side so it can be resumed.
+// Data holds the saved step fields keyed like RegistrationRequest, minus password fields.
+// Token is the secret needed to reach the draft again; it is only set on creation.
+type RegistrationDraft struct {
+	ID             uuid.UUID                  `json:"id"`
+	Token          string                     `json:"token,omitempty"`
+	Data           map[string]json.RawMessage `json:"data"`
+	CompletedSteps []string                   `json:"completed_steps"`
+	ExpiresAt      time.Time                  `json:"expires_at"`
+	CreatedAt      time.Time                  `json:"created_at"`
+	UpdatedAt      time.Time                  `json:"updated_at"`
+}
+
+// FinalizeDraftRequest supplies the password, which is never stored with the draft
+type FinalizeDraftRequest struct {
+	Password        string `json:"password"`
+	ConfirmPassword string `json:"confirm_password"`
+}
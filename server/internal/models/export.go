@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Data export formats
+const (
+	ExportFormatJSON = "json"
+	ExportFormatZIP  = "zip"
+)
+
+// Data export statuses
+const (
+	ExportStatusPending = "pending"
+	ExportStatusReady   = "ready"
+)
+
+// DataExport is a copy of everything stored about a user, handed to them on request.
+// Payload is the encoded ExportBundle once Status is ready.
+type DataExport struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Format      string
+	Status      string
+	Payload     []byte
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+	ExpiresAt   time.Time
+}
+
+// ExportBundle is the content of a data export
+type ExportBundle struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	User        ExportUser      `json:"user"`
+	Consents    []ExportConsent `json:"consents"`
+	Sessions    []ExportSession `json:"sessions"`
+	AuditEvents []AuditEvent    `json:"audit_events"`
+}
+
+// ExportUser is the user row minus credentials
+type ExportUser struct {
+	ID              string     `json:"id"`
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	Phone           *string    `json:"phone"`
+	PhoneRegion     *string    `json:"phone_region"`
+	PhoneType       *string    `json:"phone_type"`
+	Street          string     `json:"street"`
+	City            string     `json:"city"`
+	State           string     `json:"state"`
+	Country         string     `json:"country"`
+	Username        string     `json:"username"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// ExportConsent is a consent the user gave or declined
+type ExportConsent struct {
+	Type       string    `json:"type"`
+	Granted    bool      `json:"granted"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// ExportSession is a login session, without its token
+type ExportSession struct {
+	ID        string    `json:"id"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DataExportPendingResponse is returned while a background export is being built
+type DataExportPendingResponse struct {
+	ExportID  string    `json:"export_id"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	Message   string    `json:"message"`
+}
+
+// DataExportRequestedPayload is the payload of EventDataExportRequested
+type DataExportRequestedPayload struct {
+	ExportID string `json:"export_id"`
+	UserID   string `json:"user_id"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// IdempotencyRecord is the stored outcome of a request sent with an Idempotency-Key
+type IdempotencyRecord struct {
+	Scope       string
+	Key         string
+	Fingerprint string
+	// Completed is false while the original request is still being processed
+	Completed      bool
+	ResponseStatus int
+	ResponseBody   []byte
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// Outbox event types
+const (
+	EventUserRegistered           = "user.registered"
+	EventVerificationRequested    = "user.verification_requested"
+	EventUserEmailVerified        = "user.email_verified"
+	EventUserNewsletterSubscribed = "user.newsletter_subscribed"
+	EventWebhookDelivery          = "webhook.delivery"
+	EventDataExportRequested      = "user.data_export_requested"
+	EventPasswordResetRequested   = "user.password_reset_requested"
+	EventWelcomeRequested         = "user.welcome_requested"
+)
+
+// OutboxEvent is a side effect recorded alongside the write that caused it
+type OutboxEvent struct {
+	ID          uuid.UUID
+	Type        string
+	AggregateID uuid.UUID
+	Payload     json.RawMessage
+	Attempts    int
+}
+
+// NewOutboxEvent marshals payload into a new event for aggregateID
+func NewOutboxEvent(eventType string, aggregateID uuid.UUID, payload interface{}) (OutboxEvent, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return OutboxEvent{}, err
+	}
+	return OutboxEvent{
+		ID:          uuid.New(),
+		Type:        eventType,
+		AggregateID: aggregateID,
+		Payload:     raw,
+	}, nil
+}
+
+// Payloads of user events carry only the user's ID. Outbox rows are plaintext and kept for
+// a while after delivery, so handlers load the user's data when they run.
+
+// UserRegisteredPayload is the payload of EventUserRegistered
+type UserRegisteredPayload struct {
+	UserID string `json:"user_id"`
+}
+
+// VerificationRequestedPayload is the payload of EventVerificationRequested
+type VerificationRequestedPayload struct {
+	UserID string `json:"user_id"`
+}
+
+// UserEmailVerifiedPayload is the payload of EventUserEmailVerified
+type UserEmailVerifiedPayload struct {
+	UserID string `json:"user_id"`
+}
+
+// UserNewsletterSubscribedPayload is the payload of EventUserNewsletterSubscribed
+type UserNewsletterSubscribedPayload struct {
+	UserID string `json:"user_id"`
+}
+
+// PasswordResetRequestedPayload is the payload of EventPasswordResetRequested
+type PasswordResetRequestedPayload struct {
+	UserID string `json:"user_id"`
+}
+
+// WelcomeRequestedPayload is the payload of EventWelcomeRequested
+type WelcomeRequestedPayload struct {
+	UserID string `json:"user_id"`
+}
@@ -0,0 +1,25 @@
+package models
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token           string `json:"token"`
+	Password        string `json:"password"`
+	ConfirmPassword string `json:"confirm_password"`
+}
+
+// PasswordKeyUsage counts the users whose password hash uses one algorithm and pepper key.
+// Current is false for hashes that will be re-peppered on the owner's next login.
+type PasswordKeyUsage struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	Users     int64  `json:"users"`
+	Current   bool   `json:"current"`
+}
+
+type PasswordKeyReport struct {
+	CurrentKeyID string             `json:"current_key_id"`
+	Hashes       []PasswordKeyUsage `json:"hashes"`
+}
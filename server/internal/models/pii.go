@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PII key purposes
+const (
+	// PIIKeyPurposeData keys encrypt column values; the newest one is used for new rows
+	PIIKeyPurposeData = "data"
+	// PIIKeyPurposeIndex is the single key for blind indexes
+	PIIKeyPurposeIndex = "index"
+)
+
+// PIIKey is a key for PII encryption, stored wrapped by the master key
+type PIIKey struct {
+	ID          uuid.UUID
+	Purpose     string
+	WrappedKey  []byte
+	MasterKeyID string
+	CreatedAt   time.Time
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a server-side login session; the raw token only ever lives in the client's cookie
+type Session struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	UserAgent string
+	IPAddress string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+type LoginRequest struct {
+	Identifier string `json:"identifier"` // Username or email
+	Password   string `json:"password"`
+}
+
+type LoginResponse struct {
+	UserID        string    `json:"user_id"`
+	Username      string    `json:"username"`
+	EmailVerified bool      `json:"email_verified"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	Message       string    `json:"message"`
+	TokenPair
+}
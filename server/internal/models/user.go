@@ -1,5 +1,48 @@
 package models
 
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User is a stored account as read back from the database
+type User struct {
+	ID            uuid.UUID
+	FirstName     string
+	LastName      string
+	Email         string
+	Phone         *string // E.164
+	Street        string
+	City          string
+	State         string
+	Country       string
+	Username      string
+	PasswordHash  string
+	TermsAccepted bool
+	Newsletter    bool
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+
+	EmailVerifiedAt *time.Time
+
+	PhoneRegion *string // ISO 3166-1 alpha-2
+	PhoneType   *string // One of the utils.PhoneType constants
+}
+
+// UserPhone is a stored phone number awaiting region and type detection
+type UserPhone struct {
+	UserID uuid.UUID
+	Phone  string
+}
+
+// CanonicalCollision is an account whose recomputed canonical email or username (Field)
+// belongs to another account
+type CanonicalCollision struct {
+	UserID uuid.UUID
+	Field  string
+}
+
 type RegistrationRequest struct {
 	FirstName       string  `json:"first_name"`
 	LastName        string  `json:"last_name"`
@@ -25,4 +68,27 @@ type RegistrationResponse struct {
 type UsernameAvailabilityResponse struct {
 	Username  string `json:"username"`
 	Available bool   `json:"available"`
+	// Message says why the username can't be used when it breaks a username rule
+	Message string `json:"message,omitempty"`
+	// Suggestions are available alternatives, best first; only set when Available is false
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// Account purge modes: what happens to a deleted account once its grace period is over
+const (
+	PurgeModeDelete    = "delete"
+	PurgeModeAnonymize = "anonymize"
+)
+
+// Deleted identifier policies: whether a deleted account's email, username and phone can be
+// registered again before it is purged
+const (
+	DeletedIdentifiersRelease = "release"
+	DeletedIdentifiersReserve = "reserve"
+)
+
+// AccountDeletionResponse is returned by DELETE /api/me
+type AccountDeletionResponse struct {
+	Message    string    `json:"message"`
+	PurgeAfter time.Time `json:"purge_after"`
 }
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEvents lists the outbox event types that can be forwarded to webhook endpoints
+var WebhookEvents = []string{
+	EventUserRegistered,
+	EventUserEmailVerified,
+	EventUserNewsletterSubscribed,
+}
+
+type WebhookEndpoint struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID         uuid.UUID `json:"id"`
+	EndpointID uuid.UUID `json:"endpoint_id"`
+	EventID    uuid.UUID `json:"event_id"`
+	EventType  string    `json:"event_type"`
+	Attempt    int       `json:"attempt"`
+	StatusCode *int      `json:"status_code,omitempty"`
+	Success    bool      `json:"success"`
+	Error      *string   `json:"error,omitempty"`
+	DurationMs int       `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookEvent is the JSON body POSTed to webhook endpoints. ID is stable across retries
+// and endpoints so receivers can deduplicate.
+type WebhookEvent struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// UserWebhookData is the data of a user event sent to webhook endpoints. It is built from
+// the user's record when the webhook is sent, so it is never stored in the outbox.
+type UserWebhookData struct {
+	UserID     string `json:"user_id"`
+	Email      string `json:"email"`
+	FirstName  string `json:"first_name,omitempty"`
+	Username   string `json:"username,omitempty"`
+	Newsletter *bool  `json:"newsletter,omitempty"`
+}
+
+// WebhookDeliveryPayload is the payload of EventWebhookDelivery, one per endpoint and event
+type WebhookDeliveryPayload struct {
+	EndpointID string       `json:"endpoint_id"`
+	Event      WebhookEvent `json:"event"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret is optional; a random one is generated when empty
+	Secret string `json:"secret"`
+}
+
+// CreateWebhookResponse is the only response that includes the signing secret
+type CreateWebhookResponse struct {
+	WebhookEndpoint
+	Secret string `json:"secret"`
+}
@@ -0,0 +1,174 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/repositories"
+)
+
+// Handler delivers one event. Handlers may run more than once for the same event
+// (a retry after a partial failure, or a crash before the delivery was recorded),
+// so they must be idempotent or tolerate duplicates.
+type Handler func(ctx context.Context, event models.OutboxEvent) error
+
+// ErrPermanent marks a failure that retrying can't fix; the event goes straight to dead-letter
+var ErrPermanent = errors.New("permanent failure")
+
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// Lease is how long a claimed batch is reserved for this dispatcher. Handlers get
+	// until the lease runs out; events still unhandled then are left for the next claim.
+	Lease       time.Duration
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Retention is how long delivered events are kept before PurgeDelivered deletes them.
+	// Dead events are kept for inspection.
+	Retention time.Duration
+}
+
+type Dispatcher struct {
+	repo repositories.OutboxRepository
+	cfg  Config
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+func NewDispatcher(repo repositories.OutboxRepository, cfg Config) *Dispatcher {
+	return &Dispatcher{
+		repo:     repo,
+		cfg:      cfg,
+		handlers: map[string]Handler{},
+	}
+}
+
+// Register sets the handler for eventType. A type has exactly one handler, so a retry
+// only repeats the side effect that failed; registering a second one panics. Side effects
+// that must be retried independently get an event type of their own.
+func (d *Dispatcher) Register(eventType string, h Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.handlers[eventType]; ok {
+		panic(fmt.Sprintf("outbox: handler for %s registered twice", eventType))
+	}
+	d.handlers[eventType] = h
+}
+
+// Run polls for due events until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	log.Printf("outbox dispatcher started (poll every %s)", d.cfg.PollInterval)
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain full batches back to back, then wait for the next tick
+		for {
+			n, err := d.ProcessBatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("outbox: failed to process batch: %v", err)
+			}
+			if err != nil || n < d.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("outbox dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch claims and handles one batch of due events and returns how many it claimed.
+// The claim is a short statement that leases the events; handlers run outside any
+// transaction and each outcome is recorded on its own, so a failure to record one doesn't
+// undo the others.
+func (d *Dispatcher) ProcessBatch(ctx context.Context) (int, error) {
+	leaseEnd := time.Now().Add(d.cfg.Lease)
+	events, err := d.repo.ClaimPending(ctx, d.cfg.BatchSize, leaseEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	leaseCtx, cancel := context.WithDeadline(ctx, leaseEnd)
+	defer cancel()
+
+	var errs []error
+	for _, event := range events {
+		if leaseCtx.Err() != nil {
+			// The rest are claimed again once the lease runs out
+			break
+		}
+		if err := d.deliver(leaseCtx, event); err != nil {
+			errs = append(errs, fmt.Errorf("event %s: %w", event.ID, err))
+		}
+	}
+	return len(events), errors.Join(errs...)
+}
+
+// deliver runs the handlers and records the outcome; the returned error is only for
+// failures to record it, after which the event is retried when its lease runs out
+func (d *Dispatcher) deliver(ctx context.Context, event models.OutboxEvent) error {
+	handleErr := d.handle(ctx, event)
+	if handleErr == nil {
+		return d.repo.MarkDelivered(ctx, event.ID)
+	}
+
+	attempt := event.Attempts + 1
+	if errors.Is(handleErr, ErrPermanent) || attempt >= d.cfg.MaxAttempts {
+		log.Printf("outbox: event %s (%s) dead-lettered after %d attempts: %v", event.ID, event.Type, attempt, handleErr)
+		return d.repo.MarkDead(ctx, event.ID, handleErr.Error())
+	}
+
+	next := time.Now().Add(Backoff(attempt, d.cfg.BaseBackoff, d.cfg.MaxBackoff))
+	log.Printf("outbox: event %s (%s) attempt %d failed, retrying at %s: %v", event.ID, event.Type, attempt, next.Format(time.RFC3339), handleErr)
+	return d.repo.MarkRetry(ctx, event.ID, next, handleErr.Error())
+}
+
+func (d *Dispatcher) handle(ctx context.Context, event models.OutboxEvent) (err error) {
+	d.mu.RLock()
+	h := d.handlers[event.Type]
+	d.mu.RUnlock()
+	if h == nil {
+		return nil
+	}
+
+	// A panicking handler must not take the dispatcher down; the event is retried like any failure
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+
+	return h(ctx, event)
+}
+
+// PurgeDelivered deletes events delivered more than Retention ago and returns how many
+func (d *Dispatcher) PurgeDelivered(ctx context.Context) (int64, error) {
+	return d.repo.DeleteDelivered(ctx, time.Now().Add(-d.cfg.Retention))
+}
+
+// Backoff returns the delay before retry number attempt (1-based): base doubled per
+// attempt, capped at max, with up to 20% random jitter so failed events don't retry in lockstep
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return delay - jitter
+}
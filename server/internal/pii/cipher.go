@@ -0,0 +1,185 @@
+package pii
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"tyk-registration-server/internal/models"
+)
+
+// KeyStore persists the wrapped keys
+type KeyStore interface {
+	// ListKeys returns all keys, oldest first
+	ListKeys(ctx context.Context) ([]models.PIIKey, error)
+	// CreateKey stores key. Storing an index key when there already is one does nothing.
+	CreateKey(ctx context.Context, key models.PIIKey) error
+}
+
+// Cipher encrypts PII column values with AES-256-GCM under a data key and computes
+// HMAC-SHA256 blind indexes of the values that are looked up. Ciphertexts are bound to
+// their row and column through the associated data, so they can't be moved around.
+type Cipher struct {
+	dataKeyID uuid.UUID
+	dataKeys  map[uuid.UUID]cipher.AEAD
+	indexKey  []byte
+}
+
+// Load returns the Cipher for the master key in keyFile, or nil when keyFile is empty and
+// PII is stored in plaintext
+func Load(ctx context.Context, keyFile string, store KeyStore) (*Cipher, error) {
+	if keyFile == "" {
+		return nil, nil
+	}
+	wrapper, err := LoadKeyFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return NewCipher(ctx, wrapper, store)
+}
+
+// NewCipher unwraps the stored keys with wrapper, generating the data and index keys on
+// first use
+func NewCipher(ctx context.Context, wrapper KeyWrapper, store KeyStore) (*Cipher, error) {
+	keys, err := store.ListKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PII keys: %w", err)
+	}
+	created := false
+	for _, purpose := range []string{models.PIIKeyPurposeIndex, models.PIIKeyPurposeData} {
+		if hasPurpose(keys, purpose) {
+			continue
+		}
+		if err := createKey(ctx, wrapper, store, purpose); err != nil {
+			return nil, err
+		}
+		created = true
+	}
+	if created {
+		// Another instance may have created the index key first; theirs is the one kept
+		if keys, err = store.ListKeys(ctx); err != nil {
+			return nil, fmt.Errorf("failed to list PII keys: %w", err)
+		}
+	}
+
+	c := &Cipher{dataKeys: make(map[uuid.UUID]cipher.AEAD)}
+	for _, key := range keys {
+		if key.MasterKeyID != wrapper.ID() {
+			return nil, fmt.Errorf("PII key %s is wrapped by master key %s, not the configured %s", key.ID, key.MasterKeyID, wrapper.ID())
+		}
+		raw, err := wrapper.Unwrap(ctx, key.WrappedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap PII key %s: %w", key.ID, err)
+		}
+		switch key.Purpose {
+		case models.PIIKeyPurposeIndex:
+			c.indexKey = raw
+		case models.PIIKeyPurposeData:
+			aead, err := newAEAD(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid PII key %s: %w", key.ID, err)
+			}
+			c.dataKeys[key.ID] = aead
+			c.dataKeyID = key.ID
+		default:
+			return nil, fmt.Errorf("PII key %s has unknown purpose %q", key.ID, key.Purpose)
+		}
+	}
+	return c, nil
+}
+
+func hasPurpose(keys []models.PIIKey, purpose string) bool {
+	for _, key := range keys {
+		if key.Purpose == purpose {
+			return true
+		}
+	}
+	return false
+}
+
+func createKey(ctx context.Context, wrapper KeyWrapper, store KeyStore, purpose string) error {
+	raw := make([]byte, keySize)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	wrapped, err := wrapper.Wrap(ctx, raw)
+	if err != nil {
+		return fmt.Errorf("failed to wrap new PII %s key: %w", purpose, err)
+	}
+	err = store.CreateKey(ctx, models.PIIKey{
+		ID:          uuid.New(),
+		Purpose:     purpose,
+		WrappedKey:  wrapped,
+		MasterKeyID: wrapper.ID(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store new PII %s key: %w", purpose, err)
+	}
+	return nil
+}
+
+// DataKeyID is the key Encrypt uses, to be stored with the row
+func (c *Cipher) DataKeyID() uuid.UUID {
+	return c.dataKeyID
+}
+
+// Encrypt seals value for the given column of row id
+func (c *Cipher) Encrypt(id uuid.UUID, column, value string) (string, error) {
+	sealed, err := c.Seal(id, column, []byte(value))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value Encrypt produced under data key keyID for the same row and column
+func (c *Cipher) Decrypt(keyID, id uuid.UUID, column, value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("%s of %s: %w", column, id, errCiphertext)
+	}
+	plain, err := c.Open(keyID, id, column, sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// Seal is Encrypt for binary columns: the result is not base64-encoded
+func (c *Cipher) Seal(id uuid.UUID, column string, value []byte) ([]byte, error) {
+	return seal(c.dataKeys[c.dataKeyID], value, associatedData(id, column))
+}
+
+// Open decrypts a value Seal produced under data key keyID for the same row and column
+func (c *Cipher) Open(keyID, id uuid.UUID, column string, sealed []byte) ([]byte, error) {
+	aead, ok := c.dataKeys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown PII key %s", keyID)
+	}
+	plain, err := open(aead, sealed, associatedData(id, column))
+	if err != nil {
+		return nil, fmt.Errorf("%s of %s: %w", column, id, err)
+	}
+	return plain, nil
+}
+
+// BlindIndex is a deterministic keyed hash of value for equality lookups and unique
+// indexes. column keeps equal values in different columns from sharing an index.
+func (c *Cipher) BlindIndex(column, value string) string {
+	mac := hmac.New(sha256.New, c.indexKey)
+	mac.Write([]byte(column))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func associatedData(id uuid.UUID, column string) []byte {
+	return append(id[:], column...)
+}
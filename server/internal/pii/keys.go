@@ -0,0 +1,102 @@
+package pii
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyWrapper protects the data keys stored in the database with a master key held
+// elsewhere: a local key file, or a KMS that wraps and unwraps without releasing its key
+type KeyWrapper interface {
+	// ID names the master key; it is stored with each wrapped key so a wrong master key is
+	// reported as such rather than as corrupt data
+	ID() string
+	Wrap(ctx context.Context, key []byte) ([]byte, error)
+	Unwrap(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+const keySize = 32 // AES-256, HMAC-SHA256
+
+// LoadKeyFile reads a master key file holding 32 random bytes in base64, as written by
+// `openssl rand -base64 32`
+func LoadKeyFile(path string) (KeyWrapper, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PII master key file: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return nil, fmt.Errorf("PII master key file is not valid base64: %w", err)
+	}
+	return NewLocalKeyWrapper(key)
+}
+
+// NewLocalKeyWrapper wraps data keys with AES-256-GCM under key
+func NewLocalKeyWrapper(key []byte) (KeyWrapper, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("PII master key must be %d bytes, got %d", keySize, len(key))
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := sha256.Sum256(key)
+	return &localKeyWrapper{aead: aead, id: "local:" + hex.EncodeToString(fingerprint[:8])}, nil
+}
+
+type localKeyWrapper struct {
+	aead cipher.AEAD
+	id   string
+}
+
+func (w *localKeyWrapper) ID() string {
+	return w.id
+}
+
+func (w *localKeyWrapper) Wrap(_ context.Context, key []byte) ([]byte, error) {
+	return seal(w.aead, key, nil)
+}
+
+func (w *localKeyWrapper) Unwrap(_ context.Context, wrapped []byte) ([]byte, error) {
+	return open(w.aead, wrapped, nil)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext under a random nonce, which is prepended to the result
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+var errCiphertext = errors.New("ciphertext is corrupt or was encrypted under another key")
+
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, errCiphertext
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, errCiphertext
+	}
+	return plaintext, nil
+}
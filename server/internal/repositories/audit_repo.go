@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"tyk-registration-server/internal/db/sqlc"
+	"tyk-registration-server/internal/models"
+)
+
+type AuditRepository interface {
+	CreateAuditEvent(ctx context.Context, event *models.AuditEvent) error
+	// ListUserAuditEvents returns the user's events, oldest first
+	ListUserAuditEvents(ctx context.Context, userID uuid.UUID) ([]models.AuditEvent, error)
+}
+
+type auditRepository struct {
+	q *sqlc.Queries
+}
+
+func NewAuditRepository(pool sqlc.DBTX) AuditRepository {
+	return &auditRepository{
+		q: sqlc.New(pool),
+	}
+}
+
+func (r *auditRepository) CreateAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	details := []byte("{}")
+	if len(event.Details) > 0 {
+		var err error
+		if details, err = json.Marshal(event.Details); err != nil {
+			return err
+		}
+	}
+	return r.q.CreateAuditEvent(ctx, sqlc.CreateAuditEventParams{
+		ID:        pgtype.UUID{Bytes: event.ID, Valid: true},
+		UserID:    pgtype.UUID{Bytes: event.UserID, Valid: true},
+		Type:      event.Type,
+		IpAddress: pgtype.Text{String: event.IPAddress, Valid: event.IPAddress != ""},
+		UserAgent: pgtype.Text{String: event.UserAgent, Valid: event.UserAgent != ""},
+		Details:   details,
+	})
+}
+
+func (r *auditRepository) ListUserAuditEvents(ctx context.Context, userID uuid.UUID) ([]models.AuditEvent, error) {
+	rows, err := r.q.ListUserAuditEvents(ctx, pgtype.UUID{Bytes: userID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	events := make([]models.AuditEvent, len(rows))
+	for i, row := range rows {
+		events[i] = models.AuditEvent{
+			ID:        uuid.UUID(row.ID.Bytes),
+			UserID:    uuid.UUID(row.UserID.Bytes),
+			Type:      row.Type,
+			IPAddress: row.IpAddress.String,
+			UserAgent: row.UserAgent.String,
+			CreatedAt: row.CreatedAt.Time,
+		}
+		if len(row.Details) > 0 {
+			if err := json.Unmarshal(row.Details, &events[i].Details); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"tyk-registration-server/internal/db/sqlc"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/pii"
+)
+
+type DataExportRepository interface {
+	CreateDataExport(ctx context.Context, export *models.DataExport) error
+	// GetDataExport returns ErrNotFound for unknown and expired exports
+	GetDataExport(ctx context.Context, id uuid.UUID) (*models.DataExport, error)
+	// GetLatestDataExport returns the user's newest unexpired export in format, or ErrNotFound
+	GetLatestDataExport(ctx context.Context, userID uuid.UUID, format string) (*models.DataExport, error)
+	// CompleteDataExport stores the payload of a pending export and marks it ready. It
+	// returns false if the export was already completed.
+	CompleteDataExport(ctx context.Context, id uuid.UUID, payload []byte) (bool, error)
+	// CountUserExportRecords counts the sessions and audit events an export of the user
+	// would contain, to decide whether it can be built inline
+	CountUserExportRecords(ctx context.Context, userID uuid.UUID) (int64, error)
+	// DataChangedAt is when the user's exported data last changed; zero if never
+	DataChangedAt(ctx context.Context, userID uuid.UUID) (time.Time, error)
+	DeleteDataExport(ctx context.Context, id uuid.UUID) error
+	DeleteExpiredDataExports(ctx context.Context) (int64, error)
+	// WithTx runs fn in one transaction, so an export and the outbox event that builds it
+	// commit or roll back together
+	WithTx(ctx context.Context, fn func(exports DataExportRepository, outbox OutboxRepository) error) error
+}
+
+type dataExportRepository struct {
+	db  TxBeginner
+	q   *sqlc.Queries
+	pii *pii.Cipher
+}
+
+// NewDataExportRepository stores payloads in plaintext when cipher is nil. Exports hold the
+// same PII as the users table, so they are encrypted under the same keys.
+func NewDataExportRepository(db TxBeginner, cipher *pii.Cipher) DataExportRepository {
+	return &dataExportRepository{
+		db:  db,
+		q:   sqlc.New(db),
+		pii: cipher,
+	}
+}
+
+func (r *dataExportRepository) WithTx(ctx context.Context, fn func(exports DataExportRepository, outbox OutboxRepository) error) error {
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		return fn(NewDataExportRepository(tx, r.pii), NewOutboxRepository(tx))
+	})
+}
+
+func (r *dataExportRepository) CreateDataExport(ctx context.Context, export *models.DataExport) error {
+	return r.q.CreateDataExport(ctx, sqlc.CreateDataExportParams{
+		ID:        pgtype.UUID{Bytes: export.ID, Valid: true},
+		UserID:    pgtype.UUID{Bytes: export.UserID, Valid: true},
+		Format:    export.Format,
+		ExpiresAt: pgtype.Timestamptz{Time: export.ExpiresAt, Valid: true},
+	})
+}
+
+func (r *dataExportRepository) GetDataExport(ctx context.Context, id uuid.UUID) (*models.DataExport, error) {
+	row, err := r.q.GetDataExport(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return r.toDataExport(row)
+}
+
+func (r *dataExportRepository) GetLatestDataExport(ctx context.Context, userID uuid.UUID, format string) (*models.DataExport, error) {
+	row, err := r.q.GetLatestDataExport(ctx, sqlc.GetLatestDataExportParams{
+		UserID: pgtype.UUID{Bytes: userID, Valid: true},
+		Format: format,
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return r.toDataExport(row)
+}
+
+func (r *dataExportRepository) CompleteDataExport(ctx context.Context, id uuid.UUID, payload []byte) (bool, error) {
+	var keyID pgtype.UUID
+	if r.pii != nil {
+		sealed, err := r.pii.Seal(id, "payload", payload)
+		if err != nil {
+			return false, err
+		}
+		payload = sealed
+		keyID = pgtype.UUID{Bytes: r.pii.DataKeyID(), Valid: true}
+	}
+	n, err := r.q.CompleteDataExport(ctx, sqlc.CompleteDataExportParams{
+		ID:       pgtype.UUID{Bytes: id, Valid: true},
+		Payload:  payload,
+		PiiKeyID: keyID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *dataExportRepository) CountUserExportRecords(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return r.q.CountUserExportRecords(ctx, pgtype.UUID{Bytes: userID, Valid: true})
+}
+
+func (r *dataExportRepository) DataChangedAt(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	changedAt, err := r.q.GetUserDataChangedAt(ctx, pgtype.UUID{Bytes: userID, Valid: true})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return changedAt.Time, nil
+}
+
+func (r *dataExportRepository) DeleteDataExport(ctx context.Context, id uuid.UUID) error {
+	return r.q.DeleteDataExport(ctx, pgtype.UUID{Bytes: id, Valid: true})
+}
+
+func (r *dataExportRepository) DeleteExpiredDataExports(ctx context.Context) (int64, error) {
+	return r.q.DeleteExpiredDataExports(ctx)
+}
+
+func (r *dataExportRepository) toDataExport(row sqlc.DataExport) (*models.DataExport, error) {
+	export := &models.DataExport{
+		ID:        uuid.UUID(row.ID.Bytes),
+		UserID:    uuid.UUID(row.UserID.Bytes),
+		Format:    row.Format,
+		Status:    row.Status,
+		Payload:   row.Payload,
+		CreatedAt: row.CreatedAt.Time,
+		ExpiresAt: row.ExpiresAt.Time,
+	}
+	if row.CompletedAt.Valid {
+		export.CompletedAt = &row.CompletedAt.Time
+	}
+	if row.PiiKeyID.Valid {
+		if r.pii == nil {
+			return nil, fmt.Errorf("data export %s is encrypted: %w", export.ID, ErrPIIEncryptionDisabled)
+		}
+		payload, err := r.pii.Open(uuid.UUID(row.PiiKeyID.Bytes), export.ID, "payload", row.Payload)
+		if err != nil {
+			return nil, err
+		}
+		export.Payload = payload
+	}
+	return export, nil
+}
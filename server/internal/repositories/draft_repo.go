@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"tyk-registration-server/internal/db/sqlc"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/pii"
+	"tyk-registration-server/internal/utils"
+)
+
+type DraftRepository interface {
+	// CreateDraft stores a draft reached with tokenHash, the hash of its secret token
+	CreateDraft(ctx context.Context, tokenHash string, expiresAt time.Time) (*models.RegistrationDraft, error)
+	// GetDraft returns ErrNotFound for unknown and expired drafts, and for a wrong token
+	GetDraft(ctx context.Context, id uuid.UUID, tokenHash string) (*models.RegistrationDraft, error)
+	// UpdateDraft replaces the draft's data and steps. It returns ErrNotFound if the draft
+	// is gone or expired.
+	UpdateDraft(ctx context.Context, draft *models.RegistrationDraft) (*models.RegistrationDraft, error)
+	DeleteExpiredDrafts(ctx context.Context) (int64, error)
+}
+
+type draftRepository struct {
+	q          *sqlc.Queries
+	pii        *pii.Cipher
+	emailRules *utils.EmailRules
+}
+
+// NewDraftRepository stores draft data in plaintext when cipher is nil. Drafts hold the
+// same PII as the users table, so they are encrypted under the same keys. Each draft also
+// records its email's canonical form under rules (nil uses utils.DefaultEmailRules), as the
+// users table does, so an account's drafts can be purged with it.
+func NewDraftRepository(pool sqlc.DBTX, cipher *pii.Cipher, rules *utils.EmailRules) DraftRepository {
+	if rules == nil {
+		rules = utils.DefaultEmailRules()
+	}
+	return &draftRepository{
+		q:          sqlc.New(pool),
+		pii:        cipher,
+		emailRules: rules,
+	}
+}
+
+func (r *draftRepository) CreateDraft(ctx context.Context, tokenHash string, expiresAt time.Time) (*models.RegistrationDraft, error) {
+	row, err := r.q.CreateRegistrationDraft(ctx, sqlc.CreateRegistrationDraftParams{
+		ID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		TokenHash: tokenHash,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.toDraft(row)
+}
+
+func (r *draftRepository) GetDraft(ctx context.Context, id uuid.UUID, tokenHash string) (*models.RegistrationDraft, error) {
+	row, err := r.q.GetRegistrationDraft(ctx, sqlc.GetRegistrationDraftParams{
+		ID:        pgtype.UUID{Bytes: id, Valid: true},
+		TokenHash: tokenHash,
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return r.toDraft(row)
+}
+
+func (r *draftRepository) UpdateDraft(ctx context.Context, draft *models.RegistrationDraft) (*models.RegistrationDraft, error) {
+	data, err := json.Marshal(draft.Data)
+	if err != nil {
+		return nil, err
+	}
+	params := sqlc.UpdateRegistrationDraftParams{
+		Data:           data,
+		CompletedSteps: draft.CompletedSteps,
+		ExpiresAt:      pgtype.Timestamptz{Time: draft.ExpiresAt, Valid: true},
+		ID:             pgtype.UUID{Bytes: draft.ID, Valid: true},
+		EmailCanonical: r.emailKey(draft.Data["email"]),
+	}
+	if r.pii != nil {
+		if params.SealedData, err = r.pii.Seal(draft.ID, "data", data); err != nil {
+			return nil, err
+		}
+		params.Data = []byte("{}")
+		params.PiiKeyID = pgtype.UUID{Bytes: r.pii.DataKeyID(), Valid: true}
+	}
+	row, err := r.q.UpdateRegistrationDraft(ctx, params)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return r.toDraft(row)
+}
+
+// emailKey is the draft's email as the users table stores it: canonical, and a blind index
+// once PII is encrypted. It is NULL until an email is saved.
+func (r *draftRepository) emailKey(raw json.RawMessage) pgtype.Text {
+	var email string
+	if err := json.Unmarshal(raw, &email); err != nil || strings.TrimSpace(email) == "" {
+		return pgtype.Text{}
+	}
+	key := r.emailRules.Canonical(email)
+	if r.pii != nil {
+		key = r.pii.BlindIndex("email", key)
+	}
+	return pgtype.Text{String: key, Valid: true}
+}
+
+func (r *draftRepository) DeleteExpiredDrafts(ctx context.Context) (int64, error) {
+	return r.q.DeleteExpiredRegistrationDrafts(ctx)
+}
+
+func (r *draftRepository) toDraft(row sqlc.RegistrationDraft) (*models.RegistrationDraft, error) {
+	id := uuid.UUID(row.ID.Bytes)
+	raw := row.Data
+	if row.PiiKeyID.Valid {
+		if r.pii == nil {
+			return nil, fmt.Errorf("draft %s is encrypted: %w", id, ErrPIIEncryptionDisabled)
+		}
+		var err error
+		if raw, err = r.pii.Open(uuid.UUID(row.PiiKeyID.Bytes), id, "data", row.SealedData); err != nil {
+			return nil, err
+		}
+	}
+	data := map[string]json.RawMessage{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return nil, err
+		}
+	}
+	steps := row.CompletedSteps
+	if steps == nil {
+		steps = []string{}
+	}
+	return &models.RegistrationDraft{
+		ID:             id,
+		Data:           data,
+		CompletedSteps: steps,
+		ExpiresAt:      row.ExpiresAt.Time,
+		CreatedAt:      row.CreatedAt.Time,
+		UpdatedAt:      row.UpdatedAt.Time,
+	}, nil
+}
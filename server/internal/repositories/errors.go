@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrNotFound is returned when a lookup matches no row
+var ErrNotFound = errors.New("record not found")
+
+// UniqueViolationError is returned when a write collides with an existing row on a unique
+// constraint. Field is the request field the constraint covers, or "" if it isn't known.
+type UniqueViolationError struct {
+	Field      string
+	Constraint string
+}
+
+func (e *UniqueViolationError) Error() string {
+	return fmt.Sprintf("unique violation on %s (%s)", e.Field, e.Constraint)
+}
+
+// uniqueViolationSQLState is Postgres' unique_violation error code
+const uniqueViolationSQLState = "23505"
+
+// userUniqueConstraints maps the unique constraints and indexes on users to request fields
+var userUniqueConstraints = map[string]string{
+	"idx_users_email_canonical_unique":    "email",
+	"idx_users_username_canonical_unique": "username",
+	"idx_users_phone_index_unique":        "phone",
+}
+
+// uniqueViolation translates a unique-violation error into UniqueViolationError, using
+// fields to name the column; other errors are returned unchanged
+func uniqueViolation(err error, fields map[string]string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationSQLState {
+		return &UniqueViolationError{Field: fields[pgErr.ConstraintName], Constraint: pgErr.ConstraintName}
+	}
+	return err
+}
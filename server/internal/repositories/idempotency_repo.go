@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"tyk-registration-server/internal/db/sqlc"
+	"tyk-registration-server/internal/models"
+)
+
+type IdempotencyRepository interface {
+	// Reserve claims scope/key for a new request until lockUntil. It returns false if a live
+	// record already holds the key.
+	Reserve(ctx context.Context, scope, key, fingerprint string, lockUntil time.Time) (bool, error)
+	// Get returns ErrNotFound if there is no live record for the key
+	Get(ctx context.Context, scope, key string) (*models.IdempotencyRecord, error)
+	// Complete stores the response to replay until expiresAt
+	Complete(ctx context.Context, scope, key string, status int, body []byte, expiresAt time.Time) error
+	// Release drops a reservation so the key can be retried
+	Release(ctx context.Context, scope, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type idempotencyRepository struct {
+	q *sqlc.Queries
+}
+
+func NewIdempotencyRepository(pool sqlc.DBTX) IdempotencyRepository {
+	return &idempotencyRepository{
+		q: sqlc.New(pool),
+	}
+}
+
+func (r *idempotencyRepository) Reserve(ctx context.Context, scope, key, fingerprint string, lockUntil time.Time) (bool, error) {
+	n, err := r.q.ReserveIdempotencyKey(ctx, sqlc.ReserveIdempotencyKeyParams{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   pgtype.Timestamptz{Time: lockUntil, Valid: true},
+	})
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (r *idempotencyRepository) Get(ctx context.Context, scope, key string) (*models.IdempotencyRecord, error) {
+	row, err := r.q.GetIdempotencyKey(ctx, sqlc.GetIdempotencyKeyParams{Scope: scope, Key: key})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &models.IdempotencyRecord{
+		Scope:          row.Scope,
+		Key:            row.Key,
+		Fingerprint:    row.Fingerprint,
+		Completed:      row.Status == "completed",
+		ResponseStatus: int(row.ResponseStatus.Int32),
+		ResponseBody:   row.ResponseBody,
+	}, nil
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, scope, key string, status int, body []byte, expiresAt time.Time) error {
+	return r.q.CompleteIdempotencyKey(ctx, sqlc.CompleteIdempotencyKeyParams{
+		Scope:          scope,
+		Key:            key,
+		ResponseStatus: pgtype.Int4{Int32: int32(status), Valid: true},
+		ResponseBody:   body,
+		ExpiresAt:      pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	return r.q.DeleteIdempotencyKey(ctx, sqlc.DeleteIdempotencyKeyParams{Scope: scope, Key: key})
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	return r.q.DeleteExpiredIdempotencyKeys(ctx)
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"tyk-registration-server/internal/db/sqlc"
+	"tyk-registration-server/internal/models"
+)
+
+type OutboxRepository interface {
+	// Enqueue is a no-op if an event with the same id is already queued
+	Enqueue(ctx context.Context, event models.OutboxEvent) error
+	// ClaimPending leases up to limit due events until lockedUntil. Nothing else claims
+	// them until the lease runs out or one of the Mark methods records their outcome.
+	ClaimPending(ctx context.Context, limit int, lockedUntil time.Time) ([]models.OutboxEvent, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID) error
+	MarkRetry(ctx context.Context, id uuid.UUID, nextAttemptAt time.Time, lastErr string) error
+	MarkDead(ctx context.Context, id uuid.UUID, lastErr string) error
+	// DeleteDelivered deletes events delivered before deliveredBefore and returns how many
+	DeleteDelivered(ctx context.Context, deliveredBefore time.Time) (int64, error)
+	WithTx(ctx context.Context, fn func(outbox OutboxRepository) error) error
+}
+
+type outboxRepository struct {
+	db TxBeginner
+	q  *sqlc.Queries
+}
+
+func NewOutboxRepository(db TxBeginner) OutboxRepository {
+	return &outboxRepository{
+		db: db,
+		q:  sqlc.New(db),
+	}
+}
+
+func (r *outboxRepository) Enqueue(ctx context.Context, event models.OutboxEvent) error {
+	return r.q.InsertOutboxEvent(ctx, sqlc.InsertOutboxEventParams{
+		ID:          pgtype.UUID{Bytes: event.ID, Valid: true},
+		EventType:   event.Type,
+		AggregateID: pgtype.UUID{Bytes: event.AggregateID, Valid: event.AggregateID != uuid.Nil},
+		Payload:     event.Payload,
+	})
+}
+
+func (r *outboxRepository) ClaimPending(ctx context.Context, limit int, lockedUntil time.Time) ([]models.OutboxEvent, error) {
+	rows, err := r.q.ClaimOutboxEvents(ctx, sqlc.ClaimOutboxEventsParams{
+		LockedUntil: pgtype.Timestamptz{Time: lockedUntil, Valid: true},
+		BatchSize:   int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]models.OutboxEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, models.OutboxEvent{
+			ID:          uuid.UUID(row.ID.Bytes),
+			Type:        row.EventType,
+			AggregateID: uuid.UUID(row.AggregateID.Bytes),
+			Payload:     row.Payload,
+			Attempts:    int(row.Attempts),
+		})
+	}
+	return events, nil
+}
+
+func (r *outboxRepository) MarkDelivered(ctx context.Context, id uuid.UUID) error {
+	return r.q.MarkOutboxEventDelivered(ctx, pgtype.UUID{Bytes: id, Valid: true})
+}
+
+func (r *outboxRepository) MarkRetry(ctx context.Context, id uuid.UUID, nextAttemptAt time.Time, lastErr string) error {
+	return r.q.MarkOutboxEventRetry(ctx, sqlc.MarkOutboxEventRetryParams{
+		ID:            pgtype.UUID{Bytes: id, Valid: true},
+		NextAttemptAt: pgtype.Timestamptz{Time: nextAttemptAt, Valid: true},
+		LastError:     pgtype.Text{String: lastErr, Valid: true},
+	})
+}
+
+func (r *outboxRepository) MarkDead(ctx context.Context, id uuid.UUID, lastErr string) error {
+	return r.q.MarkOutboxEventDead(ctx, sqlc.MarkOutboxEventDeadParams{
+		ID:        pgtype.UUID{Bytes: id, Valid: true},
+		LastError: pgtype.Text{String: lastErr, Valid: true},
+	})
+}
+
+func (r *outboxRepository) DeleteDelivered(ctx context.Context, deliveredBefore time.Time) (int64, error) {
+	return r.q.DeleteDeliveredOutboxEvents(ctx, pgtype.Timestamptz{Time: deliveredBefore, Valid: true})
+}
+
+func (r *outboxRepository) WithTx(ctx context.Context, fn func(outbox OutboxRepository) error) error {
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		return fn(NewOutboxRepository(tx))
+	})
+}
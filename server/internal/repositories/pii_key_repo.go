@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"tyk-registration-server/internal/db/sqlc"
+	"tyk-registration-server/internal/models"
+)
+
+// PIIKeyRepository stores the wrapped PII encryption keys; it satisfies pii.KeyStore
+type PIIKeyRepository interface {
+	ListKeys(ctx context.Context) ([]models.PIIKey, error)
+	CreateKey(ctx context.Context, key models.PIIKey) error
+}
+
+type piiKeyRepository struct {
+	q *sqlc.Queries
+}
+
+func NewPIIKeyRepository(pool sqlc.DBTX) PIIKeyRepository {
+	return &piiKeyRepository{
+		q: sqlc.New(pool),
+	}
+}
+
+func (r *piiKeyRepository) ListKeys(ctx context.Context) ([]models.PIIKey, error) {
+	rows, err := r.q.ListPIIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]models.PIIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, models.PIIKey{
+			ID:          uuid.UUID(row.ID.Bytes),
+			Purpose:     row.Purpose,
+			WrappedKey:  row.WrappedKey,
+			MasterKeyID: row.MasterKeyID,
+			CreatedAt:   row.CreatedAt.Time,
+		})
+	}
+	return keys, nil
+}
+
+func (r *piiKeyRepository) CreateKey(ctx context.Context, key models.PIIKey) error {
+	return r.q.CreatePIIKey(ctx, sqlc.CreatePIIKeyParams{
+		ID:          pgtype.UUID{Bytes: key.ID, Valid: true},
+		Purpose:     key.Purpose,
+		WrappedKey:  key.WrappedKey,
+		MasterKeyID: key.MasterKeyID,
+	})
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"tyk-registration-server/internal/db/sqlc"
+	"tyk-registration-server/internal/models"
+)
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken, tokenHash string) error
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	// MarkUsed flags the token as rotated; it returns false if it was already used or revoked
+	MarkUsed(ctx context.Context, id uuid.UUID) (bool, error)
+	RevokeFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeUserTokens(ctx context.Context, userID uuid.UUID) error
+}
+
+type refreshTokenRepository struct {
+	q *sqlc.Queries
+}
+
+func NewRefreshTokenRepository(pool sqlc.DBTX) RefreshTokenRepository {
+	return &refreshTokenRepository{
+		q: sqlc.New(pool),
+	}
+}
+
+func (r *refreshTokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken, tokenHash string) error {
+	params := sqlc.CreateRefreshTokenParams{
+		ID:        pgtype.UUID{Bytes: token.ID, Valid: true},
+		UserID:    pgtype.UUID{Bytes: token.UserID, Valid: true},
+		FamilyID:  pgtype.UUID{Bytes: token.FamilyID, Valid: true},
+		TokenHash: tokenHash,
+		ExpiresAt: pgtype.Timestamptz{Time: token.ExpiresAt, Valid: true},
+	}
+	return r.q.CreateRefreshToken(ctx, params)
+}
+
+func (r *refreshTokenRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	row, err := r.q.GetRefreshTokenByHash(ctx, tokenHash)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	token := &models.RefreshToken{
+		ID:        uuid.UUID(row.ID.Bytes),
+		UserID:    uuid.UUID(row.UserID.Bytes),
+		FamilyID:  uuid.UUID(row.FamilyID.Bytes),
+		ExpiresAt: row.ExpiresAt.Time,
+	}
+	if row.UsedAt.Valid {
+		token.UsedAt = &row.UsedAt.Time
+	}
+	if row.RevokedAt.Valid {
+		token.RevokedAt = &row.RevokedAt.Time
+	}
+	return token, nil
+}
+
+func (r *refreshTokenRepository) MarkUsed(ctx context.Context, id uuid.UUID) (bool, error) {
+	rows, err := r.q.MarkRefreshTokenUsed(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	return r.q.RevokeRefreshTokenFamily(ctx, pgtype.UUID{Bytes: familyID, Valid: true})
+}
+
+func (r *refreshTokenRepository) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	return r.q.RevokeUserRefreshTokens(ctx, pgtype.UUID{Bytes: userID, Valid: true})
+}
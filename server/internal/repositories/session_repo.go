@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"tyk-registration-server/internal/db/sqlc"
+	"tyk-registration-server/internal/models"
+)
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *models.Session, tokenHash string) error
+	// GetSessionByTokenHash returns ErrNotFound for unknown and expired sessions
+	GetSessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error)
+	ListUserSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+	DeleteSession(ctx context.Context, tokenHash string) error
+	DeleteUserSessions(ctx context.Context, userID uuid.UUID) error
+}
+
+type sessionRepository struct {
+	q *sqlc.Queries
+}
+
+func NewSessionRepository(pool sqlc.DBTX) SessionRepository {
+	return &sessionRepository{
+		q: sqlc.New(pool),
+	}
+}
+
+func (r *sessionRepository) CreateSession(ctx context.Context, session *models.Session, tokenHash string) error {
+	params := sqlc.CreateSessionParams{
+		ID:        pgtype.UUID{Bytes: session.ID, Valid: true},
+		UserID:    pgtype.UUID{Bytes: session.UserID, Valid: true},
+		TokenHash: tokenHash,
+		UserAgent: pgtype.Text{String: session.UserAgent, Valid: session.UserAgent != ""},
+		IpAddress: pgtype.Text{String: session.IPAddress, Valid: session.IPAddress != ""},
+		ExpiresAt: pgtype.Timestamptz{Time: session.ExpiresAt, Valid: true},
+	}
+	return r.q.CreateSession(ctx, params)
+}
+
+func (r *sessionRepository) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error) {
+	row, err := r.q.GetSessionByTokenHash(ctx, tokenHash)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	session := toSession(row)
+	return &session, nil
+}
+
+func (r *sessionRepository) ListUserSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	rows, err := r.q.ListUserSessions(ctx, pgtype.UUID{Bytes: userID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]models.Session, len(rows))
+	for i, row := range rows {
+		sessions[i] = toSession(row)
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) DeleteSession(ctx context.Context, tokenHash string) error {
+	return r.q.DeleteSessionByTokenHash(ctx, tokenHash)
+}
+
+func (r *sessionRepository) DeleteUserSessions(ctx context.Context, userID uuid.UUID) error {
+	return r.q.DeleteUserSessions(ctx, pgtype.UUID{Bytes: userID, Valid: true})
+}
+
+func toSession(row sqlc.Session) models.Session {
+	return models.Session{
+		ID:        uuid.UUID(row.ID.Bytes),
+		UserID:    uuid.UUID(row.UserID.Bytes),
+		UserAgent: row.UserAgent.String,
+		IPAddress: row.IpAddress.String,
+		ExpiresAt: row.ExpiresAt.Time,
+		CreatedAt: row.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"tyk-registration-server/internal/db/sqlc"
+)
+
+// TxBeginner is a connection that can open transactions.
+// Both *pgxpool.Pool and pgx.Tx satisfy it (the latter via savepoints), so
+// repositories bound to a transaction can nest WithTx calls.
+type TxBeginner interface {
+	sqlc.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// withTx runs fn in a transaction, committing if it returns nil and rolling back otherwise
+func withTx(ctx context.Context, db TxBeginner, fn func(tx pgx.Tx) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// No-op once committed
+		_ = tx.Rollback(ctx)
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
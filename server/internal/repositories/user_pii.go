@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"tyk-registration-server/internal/db/sqlc"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/utils"
+)
+
+// ErrPIIEncryptionDisabled is returned by EncryptPlaintextUsers without a cipher
+var ErrPIIEncryptionDisabled = errors.New("PII encryption is not configured")
+
+// piiFields are the users columns encrypted when the repository has a cipher. Each is
+// sealed with its column name as associated data.
+type piiFields struct {
+	FirstName, LastName, Email, Street, City, State string
+	Phone                                           pgtype.Text
+}
+
+func (f *piiFields) columns() map[string]*string {
+	cols := map[string]*string{
+		"first_name": &f.FirstName,
+		"last_name":  &f.LastName,
+		"email":      &f.Email,
+		"street":     &f.Street,
+		"city":       &f.City,
+		"state":      &f.State,
+	}
+	if f.Phone.Valid {
+		cols["phone"] = &f.Phone.String
+	}
+	return cols
+}
+
+func rowPIIFields(row *sqlc.User) *piiFields {
+	return &piiFields{
+		FirstName: row.FirstName, LastName: row.LastName, Email: row.Email,
+		Street: row.Street, City: row.City, State: row.State, Phone: row.Phone,
+	}
+}
+
+// seal encrypts f in place for row id and returns the data key to store with it. Without
+// a cipher the fields stay plaintext and the key is NULL.
+func (r *userRepository) seal(id uuid.UUID, f *piiFields) (pgtype.UUID, error) {
+	if r.pii == nil {
+		return pgtype.UUID{}, nil
+	}
+	for column, value := range f.columns() {
+		sealed, err := r.pii.Encrypt(id, column, *value)
+		if err != nil {
+			return pgtype.UUID{}, err
+		}
+		*value = sealed
+	}
+	return pgtype.UUID{Bytes: r.pii.DataKeyID(), Valid: true}, nil
+}
+
+// open decrypts row's PII columns in place if they are encrypted
+func (r *userRepository) open(row *sqlc.User) error {
+	if !row.PiiKeyID.Valid {
+		return nil
+	}
+	f := rowPIIFields(row)
+	if err := r.openFields(uuid.UUID(row.ID.Bytes), row.PiiKeyID, f); err != nil {
+		return err
+	}
+	row.FirstName, row.LastName, row.Email = f.FirstName, f.LastName, f.Email
+	row.Street, row.City, row.State, row.Phone = f.Street, f.City, f.State, f.Phone
+	return nil
+}
+
+func (r *userRepository) openFields(id uuid.UUID, keyID pgtype.UUID, f *piiFields) error {
+	if r.pii == nil {
+		return fmt.Errorf("user %s has encrypted PII: %w", id, ErrPIIEncryptionDisabled)
+	}
+	for column, value := range f.columns() {
+		plain, err := r.pii.Decrypt(uuid.UUID(keyID.Bytes), id, column, *value)
+		if err != nil {
+			return err
+		}
+		*value = plain
+	}
+	return nil
+}
+
+// lookupKey is what gets stored for an email or phone that is looked up: its blind index,
+// or the value itself without a cipher
+func (r *userRepository) lookupKey(column, value string) string {
+	if r.pii == nil {
+		return value
+	}
+	return r.pii.BlindIndex(column, value)
+}
+
+// lookupKeys are the stored forms to match a value against. Rows not yet encrypted still
+// hold the value itself.
+func (r *userRepository) lookupKeys(column, value string) []string {
+	if r.pii == nil {
+		return []string{value}
+	}
+	return []string{r.pii.BlindIndex(column, value), value}
+}
+
+func (r *userRepository) EncryptPlaintextUsers(ctx context.Context, limit int) (int, error) {
+	if r.pii == nil {
+		return 0, ErrPIIEncryptionDisabled
+	}
+	encrypted := 0
+	err := withTx(ctx, r.db, func(tx pgx.Tx) error {
+		q := r.q.WithTx(tx)
+		rows, err := q.ListPlaintextUsers(ctx, int32(limit))
+		if err != nil {
+			return err
+		}
+		for i := range rows {
+			row := &rows[i]
+			id := uuid.UUID(row.ID.Bytes)
+			f := rowPIIFields(row)
+			keyID, err := r.seal(id, f)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt user %s: %w", id, err)
+			}
+			params := sqlc.EncryptUserPIIParams{
+				FirstName:      f.FirstName,
+				LastName:       f.LastName,
+				Email:          f.Email,
+				Phone:          f.Phone,
+				Street:         f.Street,
+				City:           f.City,
+				State:          f.State,
+				EmailCanonical: r.lookupKey("email", r.emailRules.Canonical(row.Email)),
+				PiiKeyID:       keyID,
+				ID:             row.ID,
+			}
+			if row.Phone.Valid {
+				params.PhoneIndex = pgtype.Text{String: r.lookupKey("phone", row.Phone.String), Valid: true}
+			}
+			n, err := q.EncryptUserPII(ctx, params)
+			if err != nil {
+				return fmt.Errorf("failed to store encrypted user %s: %w", id, err)
+			}
+			encrypted += int(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return encrypted, nil
+}
+
+func (r *userRepository) CanonicalizeUsers(ctx context.Context, afterID uuid.UUID, limit int) (uuid.UUID, int, []models.CanonicalCollision, error) {
+	rows, err := r.q.ListUserIdentities(ctx, sqlc.ListUserIdentitiesParams{
+		AfterID:  pgtype.UUID{Bytes: afterID, Valid: true},
+		RowLimit: int32(limit),
+	})
+	if err != nil || len(rows) == 0 {
+		return uuid.Nil, 0, nil, err
+	}
+
+	updated := 0
+	var collisions []models.CanonicalCollision
+	for _, row := range rows {
+		id := uuid.UUID(row.ID.Bytes)
+		email := row.Email
+		if row.PiiKeyID.Valid {
+			f := &piiFields{Email: row.Email}
+			if err := r.openFields(id, row.PiiKeyID, f); err != nil {
+				return uuid.Nil, updated, collisions, err
+			}
+			email = f.Email
+		}
+		params := sqlc.SetUserCanonicalFormsParams{
+			EmailCanonical:    r.lookupKey("email", r.emailRules.Canonical(email)),
+			UsernameCanonical: utils.CanonicalUsername(row.Username),
+			UsernameSkeleton:  utils.UsernameSkeleton(row.Username),
+			ID:                row.ID,
+			Email:             row.Email,
+			Username:          row.Username,
+		}
+		if params.EmailCanonical == row.EmailCanonical && params.UsernameCanonical == row.UsernameCanonical &&
+			params.UsernameSkeleton == row.UsernameSkeleton {
+			continue
+		}
+
+		n, err := r.q.SetUserCanonicalForms(ctx, params)
+		var conflict *UniqueViolationError
+		if errors.As(uniqueViolation(err, userUniqueConstraints), &conflict) {
+			collisions = append(collisions, models.CanonicalCollision{UserID: id, Field: conflict.Field})
+			continue
+		}
+		if err != nil {
+			return uuid.Nil, updated, collisions, fmt.Errorf("failed to update user %s: %w", id, err)
+		}
+		updated += int(n)
+	}
+	return uuid.UUID(rows[len(rows)-1].ID.Bytes), updated, collisions, nil
+}
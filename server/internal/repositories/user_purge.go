@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"tyk-registration-server/internal/db/sqlc"
+	"tyk-registration-server/internal/models"
+)
+
+func (r *userRepository) SoftDeleteUser(ctx context.Context, id uuid.UUID) error {
+	userID := pgtype.UUID{Bytes: id, Valid: true}
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		q := r.q.WithTx(tx)
+		n, err := q.SoftDeleteUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return signOut(ctx, q, userID)
+	})
+}
+
+func (r *userRepository) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, mode string, limit int) (int, error) {
+	purged := 0
+	err := withTx(ctx, r.db, func(tx pgx.Tx) error {
+		q := r.q.WithTx(tx)
+		rows, err := q.ListPurgeableUsers(ctx, sqlc.ListPurgeableUsersParams{
+			DeletedAt: pgtype.Timestamptz{Time: deletedBefore, Valid: true},
+			Limit:     int32(limit),
+		})
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := purgeUser(ctx, q, row, mode); err != nil {
+				return err
+			}
+		}
+		purged = len(rows)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// purgeUser erases a deleted account and everything recorded about it, then leaves a
+// tombstone audit event naming only the account ID
+func purgeUser(ctx context.Context, q *sqlc.Queries, row sqlc.ListPurgeableUsersRow, mode string) error {
+	if err := q.DeleteUserAuditEvents(ctx, row.ID); err != nil {
+		return err
+	}
+	if err := q.DeleteOutboxEventsByAggregate(ctx, row.ID); err != nil {
+		return err
+	}
+	// Before the email is anonymized, as drafts are matched by it
+	if err := q.DeleteUserRegistrationDrafts(ctx, row.ID); err != nil {
+		return err
+	}
+
+	switch mode {
+	case models.PurgeModeAnonymize:
+		// The row stays; what hangs off it goes
+		for _, deleteRows := range []func(context.Context, pgtype.UUID) error{
+			q.DeleteUserSessions,
+			q.DeleteUserRefreshTokens,
+			q.DeleteUserTokens,
+			q.DeleteUserDataExports,
+		} {
+			if err := deleteRows(ctx, row.ID); err != nil {
+				return err
+			}
+		}
+		if err := q.AnonymizeUser(ctx, row.ID); err != nil {
+			return err
+		}
+	default:
+		// Sessions, tokens and data exports cascade
+		if err := q.DeleteUser(ctx, row.ID); err != nil {
+			return err
+		}
+	}
+
+	details, err := json.Marshal(map[string]string{
+		"mode":       mode,
+		"deleted_at": row.DeletedAt.Time.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	return q.CreateAuditEvent(ctx, sqlc.CreateAuditEventParams{
+		ID:      pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:  row.ID,
+		Type:    models.AuditAccountPurged,
+		Details: details,
+	})
+}
@@ -2,33 +2,135 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"tyk-registration-server/internal/db/sqlc"
 	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/pii"
+	"tyk-registration-server/internal/utils"
 )
 
+// UserRepository matches emails and usernames by their canonical form (see
+// utils.EmailRules and utils.CanonicalUsername); they are stored as entered. With a PII
+// cipher, names, email, phone and address are encrypted on write and decrypted on read, and
+// emails and phones are matched by blind index. Deleted accounts are never returned by
+// lookups.
 type UserRepository interface {
+	// The Exists checks and TakenUsernames count deleted accounts awaiting purge only if
+	// the repository reserves their identifiers
 	EmailExists(ctx context.Context, email string) (bool, error)
 	UsernameExists(ctx context.Context, username string) (bool, error)
+	// UsernameLookalikeExists reports whether another account's username has the same
+	// confusable skeleton (see utils.UsernameSkeleton)
+	UsernameLookalikeExists(ctx context.Context, username string) (bool, error)
+	// TakenUsernames checks a batch of usernames in one query and returns the canonical
+	// forms of those that are registered or look like a registered username
+	TakenUsernames(ctx context.Context, usernames []string) (map[string]bool, error)
 	PhoneExists(ctx context.Context, phone string) (bool, error)
+	// CreateUser returns a *UniqueViolationError if the email, username or phone is taken
 	CreateUser(ctx context.Context, req *models.RegistrationRequest, passwordHash string) (uuid.UUID, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	// IsActive reports whether the account exists and has not been deleted
+	IsActive(ctx context.Context, id uuid.UUID) (bool, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	MarkEmailVerified(ctx context.Context, id uuid.UUID) error
+	// ConsumeToken is UserTokenRepository.ConsumeToken, here so a token can be redeemed in
+	// the same WithTx transaction as the change it authorises
+	ConsumeToken(ctx context.Context, purpose, tokenHash string) (uuid.UUID, error)
+	// DeleteDraft deletes an unexpired registration draft, here so a draft can be consumed
+	// in the transaction that registers its user. It returns ErrNotFound if there is none.
+	DeleteDraft(ctx context.Context, id uuid.UUID) error
+	UpdatePasswordHash(ctx context.Context, id uuid.UUID, passwordHash string) error
+	// SignOutEverywhere deletes the user's sessions and revokes their refresh tokens, here
+	// so it commits with the change that calls for it
+	SignOutEverywhere(ctx context.Context, id uuid.UUID) error
+	// ReplacePasswordHash stores newHash if the user's hash is still oldHash, reporting
+	// whether it did
+	ReplacePasswordHash(ctx context.Context, id uuid.UUID, oldHash, newHash string) (bool, error)
+	// CountPasswordHashesByKey counts users per hash algorithm and pepper key
+	CountPasswordHashesByKey(ctx context.Context) ([]models.PasswordKeyUsage, error)
+	// ListPhonesMissingDetails returns up to limit numbers stored before region and type
+	// were recorded
+	ListPhonesMissingDetails(ctx context.Context, limit int) ([]models.UserPhone, error)
+	// SetPhoneDetails replaces the user's number, if it is still phone, with p: its E.164
+	// form, region and type. It returns a *UniqueViolationError if another account has the
+	// number.
+	SetPhoneDetails(ctx context.Context, id uuid.UUID, phone string, p utils.Phone) error
+	// EncryptPlaintextUsers encrypts the PII of up to limit users stored before encryption
+	// was enabled and returns how many it did
+	EncryptPlaintextUsers(ctx context.Context, limit int) (int, error)
+	// CanonicalizeUsers recomputes the canonical email, username and skeleton of up to
+	// limit accounts with ids after afterID, for when the canonicalization rules change.
+	// It returns the last id it read (uuid.Nil when there are no more), how many rows it
+	// changed, and the accounts it could not change because another already holds the
+	// new form.
+	CanonicalizeUsers(ctx context.Context, afterID uuid.UUID, limit int) (last uuid.UUID, updated int, collisions []models.CanonicalCollision, err error)
+	// SoftDeleteUser marks the account deleted and, in the same transaction, deletes its
+	// sessions and revokes its refresh tokens. It returns ErrNotFound if it already is.
+	SoftDeleteUser(ctx context.Context, id uuid.UUID) error
+	// PurgeDeletedUsers purges up to limit accounts deleted before deletedBefore, in the
+	// given models.PurgeMode*, and returns how many it did
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, mode string, limit int) (int, error)
+	// WithTx runs fn in one transaction. The repositories passed to fn are bound to it,
+	// so a user write and the outbox events it causes commit or roll back together.
+	WithTx(ctx context.Context, fn func(users UserRepository, outbox OutboxRepository) error) error
 }
 
 type userRepository struct {
-	q *sqlc.Queries
+	db             TxBeginner
+	q              *sqlc.Queries
+	pii            *pii.Cipher
+	reserveDeleted bool
+	emailRules     *utils.EmailRules
 }
 
-func NewUserRepository(pool sqlc.DBTX) UserRepository {
+// UserRepositoryOptions configures NewUserRepository
+type UserRepositoryOptions struct {
+	// ReserveDeleted keeps the email, username and phone of deleted accounts taken until
+	// they are purged
+	ReserveDeleted bool
+	// EmailRules decides which addresses reach the same mailbox; nil uses
+	// utils.DefaultEmailRules
+	EmailRules *utils.EmailRules
+}
+
+// NewUserRepository stores PII in plaintext when cipher is nil
+func NewUserRepository(db TxBeginner, cipher *pii.Cipher, opts UserRepositoryOptions) UserRepository {
+	if opts.EmailRules == nil {
+		opts.EmailRules = utils.DefaultEmailRules()
+	}
 	return &userRepository{
-		q: sqlc.New(pool),
+		db:             db,
+		q:              sqlc.New(db),
+		pii:            cipher,
+		reserveDeleted: opts.ReserveDeleted,
+		emailRules:     opts.EmailRules,
 	}
 }
 
+func (r *userRepository) options() UserRepositoryOptions {
+	return UserRepositoryOptions{ReserveDeleted: r.reserveDeleted, EmailRules: r.emailRules}
+}
+
+func (r *userRepository) WithTx(ctx context.Context, fn func(users UserRepository, outbox OutboxRepository) error) error {
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		return fn(NewUserRepository(tx, r.pii, r.options()), NewOutboxRepository(tx))
+	})
+}
+
 func (r *userRepository) EmailExists(ctx context.Context, email string) (bool, error) {
-	exists, err := r.q.CheckEmailExists(ctx, email)
+	exists, err := r.q.CheckEmailExists(ctx, sqlc.CheckEmailExistsParams{
+		Keys:           r.lookupKeys("email", r.emailRules.Canonical(email)),
+		IncludeDeleted: r.reserveDeleted,
+	})
 	if err != nil {
 		return false, err
 	}
@@ -36,19 +138,63 @@ func (r *userRepository) EmailExists(ctx context.Context, email string) (bool, e
 }
 
 func (r *userRepository) UsernameExists(ctx context.Context, username string) (bool, error) {
-	exists, err := r.q.CheckUsernameExists(ctx, username)
+	exists, err := r.q.CheckUsernameExists(ctx, sqlc.CheckUsernameExistsParams{
+		UsernameCanonical: utils.CanonicalUsername(username),
+		IncludeDeleted:    r.reserveDeleted,
+	})
 	if err != nil {
 		return false, err
 	}
 	return exists, nil
 }
 
+func (r *userRepository) UsernameLookalikeExists(ctx context.Context, username string) (bool, error) {
+	return r.q.CheckUsernameLookalikeExists(ctx, sqlc.CheckUsernameLookalikeExistsParams{
+		UsernameSkeleton:  utils.UsernameSkeleton(username),
+		UsernameCanonical: utils.CanonicalUsername(username),
+		IncludeDeleted:    r.reserveDeleted,
+	})
+}
+
+func (r *userRepository) TakenUsernames(ctx context.Context, usernames []string) (map[string]bool, error) {
+	params := sqlc.ListTakenUsernamesParams{
+		Usernames:      make([]string, len(usernames)),
+		Skeletons:      make([]string, len(usernames)),
+		IncludeDeleted: r.reserveDeleted,
+	}
+	for i, username := range usernames {
+		params.Usernames[i] = utils.CanonicalUsername(username)
+		params.Skeletons[i] = utils.UsernameSkeleton(username)
+	}
+	rows, err := r.q.ListTakenUsernames(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	registered := make(map[string]bool, len(rows))
+	lookalikes := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		registered[row.UsernameCanonical] = true
+		lookalikes[row.UsernameSkeleton] = true
+	}
+	taken := map[string]bool{}
+	for i, canonical := range params.Usernames {
+		if registered[canonical] || lookalikes[params.Skeletons[i]] {
+			taken[canonical] = true
+		}
+	}
+	return taken, nil
+}
+
 func (r *userRepository) PhoneExists(ctx context.Context, phone string) (bool, error) {
-	phoneText := pgtype.Text{
-		String: phone,
-		Valid:  true,
+	number := strings.TrimSpace(phone)
+	if p, err := utils.ParsePhone(phone); err == nil {
+		number = p.E164
 	}
-	exists, err := r.q.CheckPhoneExists(ctx, phoneText)
+	exists, err := r.q.CheckPhoneExists(ctx, sqlc.CheckPhoneExistsParams{
+		Keys:           r.lookupKeys("phone", number),
+		IncludeDeleted: r.reserveDeleted,
+	})
 	if err != nil {
 		return false, err
 	}
@@ -58,31 +204,263 @@ func (r *userRepository) PhoneExists(ctx context.Context, phone string) (bool, e
 func (r *userRepository) CreateUser(ctx context.Context, req *models.RegistrationRequest, passwordHash string) (uuid.UUID, error) {
 	id := uuid.New()
 
-	var phone pgtype.Text
-	if req.Phone != nil {
-		phone.String = *req.Phone
-		phone.Valid = true
+	phone, phoneRegion, phoneType := phoneColumns(req.Phone)
+	var phoneIndex pgtype.Text
+	if phone.Valid {
+		phoneIndex = pgtype.Text{String: r.lookupKey("phone", phone.String), Valid: true}
+	}
+
+	f := &piiFields{
+		FirstName: req.FirstName, LastName: req.LastName, Email: req.Email,
+		Street: req.Street, City: req.City, State: req.State, Phone: phone,
+	}
+	keyID, err := r.seal(id, f)
+	if err != nil {
+		return uuid.Nil, err
 	}
 
 	params := sqlc.CreateUserParams{
 		ID:            pgtype.UUID{Bytes: id, Valid: true},
-		FirstName:     req.FirstName,
-		LastName:      req.LastName,
-		Email:         req.Email,
-		Phone:         phone,
-		Street:        req.Street,
-		City:          req.City,
-		State:         req.State,
+		FirstName:     f.FirstName,
+		LastName:      f.LastName,
+		Email:         f.Email,
+		Phone:         f.Phone,
+		Street:        f.Street,
+		City:          f.City,
+		State:         f.State,
 		Country:       req.Country,
 		Username:      req.Username,
 		PasswordHash:  passwordHash,
 		TermsAccepted: req.TermsAccepted,
 		Newsletter:    req.Newsletter,
+
+		EmailCanonical:    r.lookupKey("email", r.emailRules.Canonical(req.Email)),
+		UsernameCanonical: utils.CanonicalUsername(req.Username),
+		PhoneRegion:       phoneRegion,
+		PhoneType:         phoneType,
+		UsernameSkeleton:  utils.UsernameSkeleton(req.Username),
+		PhoneIndex:        phoneIndex,
+		PiiKeyID:          keyID,
 	}
 
-	err := r.q.CreateUser(ctx, params)
-	if err != nil {
-		return uuid.Nil, err
+	if err := r.q.CreateUser(ctx, params); err != nil {
+		return uuid.Nil, uniqueViolation(err, userUniqueConstraints)
 	}
 	return id, nil
 }
+
+func (r *userRepository) IsActive(ctx context.Context, id uuid.UUID) (bool, error) {
+	return r.q.CheckUserActive(ctx, pgtype.UUID{Bytes: id, Valid: true})
+}
+
+func (r *userRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	row, err := r.q.GetUserByID(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return r.toUser(row)
+}
+
+func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	row, err := r.q.GetUserByEmail(ctx, r.lookupKeys("email", r.emailRules.Canonical(email)))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return r.toUser(row)
+}
+
+func (r *userRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	row, err := r.q.GetUserByUsername(ctx, utils.CanonicalUsername(username))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return r.toUser(row)
+}
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	return r.q.MarkEmailVerified(ctx, pgtype.UUID{Bytes: id, Valid: true})
+}
+
+func (r *userRepository) ConsumeToken(ctx context.Context, purpose, tokenHash string) (uuid.UUID, error) {
+	userID, err := r.q.ConsumeUserToken(ctx, sqlc.ConsumeUserTokenParams{
+		TokenHash: tokenHash,
+		Purpose:   purpose,
+	})
+	if err != nil {
+		return uuid.Nil, notFound(err)
+	}
+	return uuid.UUID(userID.Bytes), nil
+}
+
+func (r *userRepository) DeleteDraft(ctx context.Context, id uuid.UUID) error {
+	n, err := r.q.DeleteRegistrationDraft(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) UpdatePasswordHash(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	return r.q.UpdatePasswordHash(ctx, sqlc.UpdatePasswordHashParams{
+		ID:           pgtype.UUID{Bytes: id, Valid: true},
+		PasswordHash: passwordHash,
+	})
+}
+
+func (r *userRepository) SignOutEverywhere(ctx context.Context, id uuid.UUID) error {
+	return signOut(ctx, r.q, pgtype.UUID{Bytes: id, Valid: true})
+}
+
+func signOut(ctx context.Context, q *sqlc.Queries, userID pgtype.UUID) error {
+	if err := q.DeleteUserSessions(ctx, userID); err != nil {
+		return err
+	}
+	return q.RevokeUserRefreshTokens(ctx, userID)
+}
+
+func (r *userRepository) ReplacePasswordHash(ctx context.Context, id uuid.UUID, oldHash, newHash string) (bool, error) {
+	n, err := r.q.ReplacePasswordHash(ctx, sqlc.ReplacePasswordHashParams{
+		NewHash: newHash,
+		ID:      pgtype.UUID{Bytes: id, Valid: true},
+		OldHash: oldHash,
+	})
+	return n > 0, err
+}
+
+func (r *userRepository) CountPasswordHashesByKey(ctx context.Context) ([]models.PasswordKeyUsage, error) {
+	rows, err := r.q.CountPasswordHashesByKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usage := make([]models.PasswordKeyUsage, 0, len(rows))
+	for _, row := range rows {
+		usage = append(usage, models.PasswordKeyUsage{Algorithm: row.Algorithm, KeyID: row.KeyID, Users: row.Users})
+	}
+	return usage, nil
+}
+
+func (r *userRepository) ListPhonesMissingDetails(ctx context.Context, limit int) ([]models.UserPhone, error) {
+	rows, err := r.q.ListUsersMissingPhoneDetails(ctx, int32(limit))
+	if err != nil {
+		return nil, err
+	}
+	phones := make([]models.UserPhone, 0, len(rows))
+	for _, row := range rows {
+		id := uuid.UUID(row.ID.Bytes)
+		phone := row.Phone.String
+		if row.PiiKeyID.Valid {
+			f := &piiFields{Phone: row.Phone}
+			if err := r.openFields(id, row.PiiKeyID, f); err != nil {
+				return nil, err
+			}
+			phone = f.Phone.String
+		}
+		phones = append(phones, models.UserPhone{UserID: id, Phone: phone})
+	}
+	return phones, nil
+}
+
+func (r *userRepository) SetPhoneDetails(ctx context.Context, id uuid.UUID, phone string, p utils.Phone) error {
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		q := r.q.WithTx(tx)
+		row, err := q.GetUserPhoneForUpdate(ctx, pgtype.UUID{Bytes: id, Valid: true})
+		if err != nil {
+			return notFound(err)
+		}
+		f := &piiFields{Phone: row.Phone}
+		if row.PiiKeyID.Valid {
+			if err := r.openFields(id, row.PiiKeyID, f); err != nil {
+				return err
+			}
+		}
+		if !f.Phone.Valid || f.Phone.String != phone {
+			return nil
+		}
+
+		// The number is stored the way the rest of the row is: encrypted with a blind
+		// index, or plaintext and indexed by itself
+		number, index := p.E164, p.E164
+		if row.PiiKeyID.Valid {
+			if uuid.UUID(row.PiiKeyID.Bytes) != r.pii.DataKeyID() {
+				return fmt.Errorf("user %s is encrypted under data key %s, not the current one", id, uuid.UUID(row.PiiKeyID.Bytes))
+			}
+			if number, err = r.pii.Encrypt(id, "phone", p.E164); err != nil {
+				return err
+			}
+			index = r.pii.BlindIndex("phone", p.E164)
+		}
+		err = q.SetPhoneDetails(ctx, sqlc.SetPhoneDetailsParams{
+			Phone:       pgtype.Text{String: number, Valid: true},
+			PhoneIndex:  pgtype.Text{String: index, Valid: true},
+			PhoneRegion: pgtype.Text{String: p.Region, Valid: p.Region != ""},
+			PhoneType:   pgtype.Text{String: p.Type, Valid: true},
+			ID:          pgtype.UUID{Bytes: id, Valid: true},
+		})
+		return uniqueViolation(err, userUniqueConstraints)
+	})
+}
+
+// phoneColumns stores phone in E.164 form with its region and type. A blank phone is NULL. A
+// number that doesn't parse can only arrive with field validation disabled; it is kept as
+// sent with type unknown.
+func phoneColumns(phone *string) (number, region, phoneType pgtype.Text) {
+	if phone == nil || strings.TrimSpace(*phone) == "" {
+		return
+	}
+	p, err := utils.ParsePhone(*phone)
+	if err != nil {
+		return pgtype.Text{String: strings.TrimSpace(*phone), Valid: true},
+			pgtype.Text{},
+			pgtype.Text{String: utils.PhoneTypeUnknown, Valid: true}
+	}
+	return pgtype.Text{String: p.E164, Valid: true},
+		pgtype.Text{String: p.Region, Valid: p.Region != ""},
+		pgtype.Text{String: p.Type, Valid: true}
+}
+
+func (r *userRepository) toUser(row sqlc.User) (*models.User, error) {
+	if err := r.open(&row); err != nil {
+		return nil, err
+	}
+	user := &models.User{
+		ID:            uuid.UUID(row.ID.Bytes),
+		FirstName:     row.FirstName,
+		LastName:      row.LastName,
+		Email:         row.Email,
+		Street:        row.Street,
+		City:          row.City,
+		State:         row.State,
+		Country:       row.Country,
+		Username:      row.Username,
+		PasswordHash:  row.PasswordHash,
+		TermsAccepted: row.TermsAccepted,
+		Newsletter:    row.Newsletter,
+		CreatedAt:     row.CreatedAt.Time,
+		UpdatedAt:     row.UpdatedAt.Time,
+	}
+	if row.Phone.Valid {
+		phone := row.Phone.String
+		user.Phone = &phone
+	}
+	if row.EmailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &row.EmailVerifiedAt.Time
+	}
+	if row.PhoneRegion.Valid {
+		user.PhoneRegion = &row.PhoneRegion.String
+	}
+	if row.PhoneType.Valid {
+		user.PhoneType = &row.PhoneType.String
+	}
+	return user, nil
+}
+
+// notFound translates pgx's no-rows error into ErrNotFound so callers don't depend on pgx
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"tyk-registration-server/internal/db/sqlc"
+)
+
+type UserTokenRepository interface {
+	CreateToken(ctx context.Context, userID uuid.UUID, purpose, tokenHash string, expiresAt time.Time) error
+	// ConsumeToken redeems an unused, unexpired token and returns its owner.
+	// It returns ErrNotFound if the token is unknown, expired or already used.
+	ConsumeToken(ctx context.Context, purpose, tokenHash string) (uuid.UUID, error)
+	// TokenOwner returns the owner of a token ConsumeToken would redeem, leaving it unused
+	TokenOwner(ctx context.Context, purpose, tokenHash string) (uuid.UUID, error)
+	// InvalidateTokens burns every outstanding token of the given purpose for a user
+	InvalidateTokens(ctx context.Context, userID uuid.UUID, purpose string) error
+}
+
+type userTokenRepository struct {
+	q *sqlc.Queries
+}
+
+func NewUserTokenRepository(pool sqlc.DBTX) UserTokenRepository {
+	return &userTokenRepository{
+		q: sqlc.New(pool),
+	}
+}
+
+func (r *userTokenRepository) CreateToken(ctx context.Context, userID uuid.UUID, purpose, tokenHash string, expiresAt time.Time) error {
+	params := sqlc.CreateUserTokenParams{
+		ID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:    pgtype.UUID{Bytes: userID, Valid: true},
+		Purpose:   purpose,
+		TokenHash: tokenHash,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	}
+	return r.q.CreateUserToken(ctx, params)
+}
+
+func (r *userTokenRepository) ConsumeToken(ctx context.Context, purpose, tokenHash string) (uuid.UUID, error) {
+	userID, err := r.q.ConsumeUserToken(ctx, sqlc.ConsumeUserTokenParams{
+		TokenHash: tokenHash,
+		Purpose:   purpose,
+	})
+	if err != nil {
+		return uuid.Nil, notFound(err)
+	}
+	return uuid.UUID(userID.Bytes), nil
+}
+
+func (r *userTokenRepository) TokenOwner(ctx context.Context, purpose, tokenHash string) (uuid.UUID, error) {
+	userID, err := r.q.GetUserTokenOwner(ctx, sqlc.GetUserTokenOwnerParams{
+		TokenHash: tokenHash,
+		Purpose:   purpose,
+	})
+	if err != nil {
+		return uuid.Nil, notFound(err)
+	}
+	return uuid.UUID(userID.Bytes), nil
+}
+
+func (r *userTokenRepository) InvalidateTokens(ctx context.Context, userID uuid.UUID, purpose string) error {
+	return r.q.InvalidateUserTokens(ctx, sqlc.InvalidateUserTokensParams{
+		UserID:  pgtype.UUID{Bytes: userID, Valid: true},
+		Purpose: purpose,
+	})
+}
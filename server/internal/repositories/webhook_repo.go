@@ -0,0 +1,240 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"tyk-registration-server/internal/db/sqlc"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/pii"
+)
+
+type WebhookRepository interface {
+	CreateEndpoint(ctx context.Context, url, secret string, events []string) (*models.WebhookEndpoint, error)
+	GetEndpoint(ctx context.Context, id uuid.UUID) (*models.WebhookEndpoint, error)
+	ListEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error)
+	// ListSubscribedEndpoints returns the active endpoints subscribed to eventType
+	ListSubscribedEndpoints(ctx context.Context, eventType string) ([]models.WebhookEndpoint, error)
+	// DeleteEndpoint removes the endpoint and its delivery log. It returns ErrNotFound
+	// if no endpoint has that id.
+	DeleteEndpoint(ctx context.Context, id uuid.UUID) error
+	RecordDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+	// ListDeliveries returns the most recent delivery attempts for an endpoint, newest first
+	ListDeliveries(ctx context.Context, endpointID uuid.UUID, limit int) ([]models.WebhookDelivery, error)
+	// EncryptPlaintextSecrets encrypts up to limit signing secrets stored before encryption
+	// was enabled and returns how many it did
+	EncryptPlaintextSecrets(ctx context.Context, limit int) (int, error)
+}
+
+type webhookRepository struct {
+	db  TxBeginner
+	q   *sqlc.Queries
+	pii *pii.Cipher
+}
+
+// NewWebhookRepository stores signing secrets in plaintext when cipher is nil. They can't
+// be hashed because every delivery is signed with them, so they are encrypted under the
+// PII keys instead.
+func NewWebhookRepository(db TxBeginner, cipher *pii.Cipher) WebhookRepository {
+	return &webhookRepository{
+		db:  db,
+		q:   sqlc.New(db),
+		pii: cipher,
+	}
+}
+
+func (r *webhookRepository) CreateEndpoint(ctx context.Context, url, secret string, events []string) (*models.WebhookEndpoint, error) {
+	id := uuid.New()
+	stored, keyID, err := r.sealSecret(id, secret)
+	if err != nil {
+		return nil, err
+	}
+	row, err := r.q.CreateWebhookEndpoint(ctx, sqlc.CreateWebhookEndpointParams{
+		ID:          pgtype.UUID{Bytes: id, Valid: true},
+		Url:         url,
+		Secret:      stored,
+		SecretKeyID: keyID,
+		Events:      events,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.toWebhookEndpoint(row)
+}
+
+func (r *webhookRepository) GetEndpoint(ctx context.Context, id uuid.UUID) (*models.WebhookEndpoint, error) {
+	row, err := r.q.GetWebhookEndpoint(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return r.toWebhookEndpoint(row)
+}
+
+func (r *webhookRepository) ListEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	rows, err := r.q.ListWebhookEndpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.toWebhookEndpoints(rows)
+}
+
+func (r *webhookRepository) ListSubscribedEndpoints(ctx context.Context, eventType string) ([]models.WebhookEndpoint, error) {
+	rows, err := r.q.ListActiveWebhookEndpointsForEvent(ctx, eventType)
+	if err != nil {
+		return nil, err
+	}
+	return r.toWebhookEndpoints(rows)
+}
+
+func (r *webhookRepository) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	n, err := r.q.DeleteWebhookEndpoint(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *webhookRepository) RecordDelivery(ctx context.Context, d models.WebhookDelivery) error {
+	var status pgtype.Int4
+	if d.StatusCode != nil {
+		status = pgtype.Int4{Int32: int32(*d.StatusCode), Valid: true}
+	}
+	var errText pgtype.Text
+	if d.Error != nil {
+		errText = pgtype.Text{String: *d.Error, Valid: true}
+	}
+
+	return r.q.InsertWebhookDelivery(ctx, sqlc.InsertWebhookDeliveryParams{
+		ID:         pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		EndpointID: pgtype.UUID{Bytes: d.EndpointID, Valid: true},
+		EventID:    pgtype.UUID{Bytes: d.EventID, Valid: true},
+		EventType:  d.EventType,
+		Attempt:    int32(d.Attempt),
+		StatusCode: status,
+		Success:    d.Success,
+		Error:      errText,
+		DurationMs: int32(d.DurationMs),
+	})
+}
+
+func (r *webhookRepository) ListDeliveries(ctx context.Context, endpointID uuid.UUID, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := r.q.ListWebhookDeliveries(ctx, sqlc.ListWebhookDeliveriesParams{
+		EndpointID: pgtype.UUID{Bytes: endpointID, Valid: true},
+		Limit:      int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0, len(rows))
+	for _, row := range rows {
+		d := models.WebhookDelivery{
+			ID:         uuid.UUID(row.ID.Bytes),
+			EndpointID: uuid.UUID(row.EndpointID.Bytes),
+			EventID:    uuid.UUID(row.EventID.Bytes),
+			EventType:  row.EventType,
+			Attempt:    int(row.Attempt),
+			Success:    row.Success,
+			DurationMs: int(row.DurationMs),
+			CreatedAt:  row.CreatedAt.Time,
+		}
+		if row.StatusCode.Valid {
+			status := int(row.StatusCode.Int32)
+			d.StatusCode = &status
+		}
+		if row.Error.Valid {
+			d.Error = &row.Error.String
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepository) EncryptPlaintextSecrets(ctx context.Context, limit int) (int, error) {
+	if r.pii == nil {
+		return 0, ErrPIIEncryptionDisabled
+	}
+	encrypted := 0
+	err := withTx(ctx, r.db, func(tx pgx.Tx) error {
+		q := r.q.WithTx(tx)
+		rows, err := q.ListPlaintextWebhookSecrets(ctx, int32(limit))
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			id := uuid.UUID(row.ID.Bytes)
+			secret, keyID, err := r.sealSecret(id, row.Secret)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt secret of webhook endpoint %s: %w", id, err)
+			}
+			n, err := q.EncryptWebhookSecret(ctx, sqlc.EncryptWebhookSecretParams{
+				ID:          row.ID,
+				Secret:      secret,
+				SecretKeyID: keyID,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to store encrypted secret of webhook endpoint %s: %w", id, err)
+			}
+			encrypted += int(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return encrypted, nil
+}
+
+// sealSecret returns the secret as stored for endpoint id and the key it is encrypted with,
+// which is unset when encryption is disabled
+func (r *webhookRepository) sealSecret(id uuid.UUID, secret string) (string, pgtype.UUID, error) {
+	if r.pii == nil {
+		return secret, pgtype.UUID{}, nil
+	}
+	sealed, err := r.pii.Encrypt(id, "secret", secret)
+	if err != nil {
+		return "", pgtype.UUID{}, err
+	}
+	return sealed, pgtype.UUID{Bytes: r.pii.DataKeyID(), Valid: true}, nil
+}
+
+func (r *webhookRepository) toWebhookEndpoints(rows []sqlc.WebhookEndpoint) ([]models.WebhookEndpoint, error) {
+	endpoints := make([]models.WebhookEndpoint, 0, len(rows))
+	for _, row := range rows {
+		endpoint, err := r.toWebhookEndpoint(row)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, *endpoint)
+	}
+	return endpoints, nil
+}
+
+func (r *webhookRepository) toWebhookEndpoint(row sqlc.WebhookEndpoint) (*models.WebhookEndpoint, error) {
+	endpoint := &models.WebhookEndpoint{
+		ID:        uuid.UUID(row.ID.Bytes),
+		URL:       row.Url,
+		Secret:    row.Secret,
+		Events:    row.Events,
+		Active:    row.Active,
+		CreatedAt: row.CreatedAt.Time,
+	}
+	if row.SecretKeyID.Valid {
+		if r.pii == nil {
+			return nil, fmt.Errorf("webhook endpoint %s secret is encrypted: %w", endpoint.ID, ErrPIIEncryptionDisabled)
+		}
+		secret, err := r.pii.Decrypt(uuid.UUID(row.SecretKeyID.Bytes), endpoint.ID, "secret", row.Secret)
+		if err != nil {
+			return nil, err
+		}
+		endpoint.Secret = secret
+	}
+	return endpoint, nil
+}
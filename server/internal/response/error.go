@@ -24,6 +24,27 @@ func NewBusinessError(message string, fields map[string]string) *Error {
 	}
 }
 
+func NewAuthenticationError(message string) *Error {
+	return &Error{
+		Code:    "authentication_error",
+		Message: message,
+	}
+}
+
+func NewNotFoundError(message string) *Error {
+	return &Error{
+		Code:    "not_found",
+		Message: message,
+	}
+}
+
+func NewRateLimitError(message string) *Error {
+	return &Error{
+		Code:    "rate_limited",
+		Message: message,
+	}
+}
+
 func NewInternalError(message string) *Error {
 	return &Error{
 		Code:    "internal_error",
@@ -1,24 +1,58 @@
 package router
 
 import (
+	"context"
 	"log"
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/jackc/pgx/v5/pgxpool"
 
+	"tyk-registration-server/internal/auth"
 	"tyk-registration-server/internal/config"
 	"tyk-registration-server/internal/db"
 	"tyk-registration-server/internal/handlers"
+	"tyk-registration-server/internal/mailer"
+	"tyk-registration-server/internal/metrics"
 	"tyk-registration-server/internal/middleware"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/outbox"
+	"tyk-registration-server/internal/pii"
 	"tyk-registration-server/internal/repositories"
 	"tyk-registration-server/internal/response"
 	"tyk-registration-server/internal/services"
+	"tyk-registration-server/internal/utils"
+	"tyk-registration-server/internal/validator"
+	"tyk-registration-server/internal/webhooks"
 )
 
+// Server bundles the HTTP app with the background workers started alongside it
+type Server struct {
+	App        *fiber.App
+	Dispatcher *outbox.Dispatcher
+	Rules      *validator.RuleEngine
+	Drafts     services.DraftService
+	// IdempotencyKeys is exposed so main can purge expired keys
+	IdempotencyKeys repositories.IdempotencyRepository
+	Exports         services.ExportService
+	Accounts        services.AccountService
+	// Pool is exposed so main can coordinate background jobs across instances
+	Pool *pgxpool.Pool
+}
+
+// New returns just the HTTP app; background workers are not started
 func New(cfg *config.Config) *fiber.App {
+	return NewServer(cfg).App
+}
+
+func NewServer(cfg *config.Config) *Server {
 	app := fiber.New(fiber.Config{
+		// Rate limits key on c.IP(), which must be the client's address rather than the proxy's
+		ProxyHeader:             cfg.ProxyHeader,
+		EnableTrustedProxyCheck: cfg.ProxyHeader != "",
+		TrustedProxies:          cfg.TrustedProxies,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			log.Printf("unhandled error: %v", err)
 			return response.SendError(c, http.StatusInternalServerError, response.NewInternalError("Internal server error ❌"))
@@ -34,11 +68,174 @@ func New(cfg *config.Config) *fiber.App {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
 
-	repo := repositories.NewUserRepository(pool)
-	userService := services.NewUserService(repo)
+	signer, err := auth.NewSigner(cfg.JWTAlgorithm, cfg.JWTSecret, cfg.JWTPrivateKeyFile)
+	if err != nil {
+		log.Fatalf("failed to create JWT signer: %v", err)
+	}
+
+	rules, err := validator.NewRuleEngine(cfg.ValidationRulesFile, models.RegistrationRequest{})
+	if err != nil {
+		log.Fatalf("failed to load validation rules: %v", err)
+	}
+
+	piiCipher, err := pii.Load(context.Background(), cfg.PIIMasterKeyFile, repositories.NewPIIKeyRepository(pool))
+	if err != nil {
+		log.Fatalf("failed to load PII encryption keys: %v", err)
+	}
+	if piiCipher == nil {
+		log.Println("PII_MASTER_KEY_FILE is not set; personal data is stored unencrypted")
+	}
+
+	emailRules, err := utils.LoadEmailRules(cfg.EmailProvidersFile, cfg.EmailFoldLocalCase)
+	if err != nil {
+		log.Fatalf("failed to load email provider rules: %v", err)
+	}
+
+	repo := repositories.NewUserRepository(pool, piiCipher, repositories.UserRepositoryOptions{
+		ReserveDeleted: cfg.DeletedIdentifiers == models.DeletedIdentifiersReserve,
+		EmailRules:     emailRules,
+	})
+	sessionRepo := repositories.NewSessionRepository(pool)
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(pool)
+	userTokenRepo := repositories.NewUserTokenRepository(pool)
+	outboxRepo := repositories.NewOutboxRepository(pool)
+	webhookRepo := repositories.NewWebhookRepository(pool, piiCipher)
+	draftRepo := repositories.NewDraftRepository(pool, piiCipher, emailRules)
+	idempotencyRepo := repositories.NewIdempotencyRepository(pool)
+	auditRepo := repositories.NewAuditRepository(pool)
+	exportRepo := repositories.NewDataExportRepository(pool, piiCipher)
+	rateLimits := middleware.NewMemoryRateLimitStore()
+
+	mail, err := mailer.New(mailer.Config{
+		Driver:  cfg.MailDriver,
+		From:    cfg.MailFrom,
+		FileDir: cfg.MailFileDir,
+		SMTP: mailer.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			StartTLS: cfg.SMTPStartTLS,
+		},
+	})
+	if err != nil {
+		log.Fatalf("failed to create mailer: %v", err)
+	}
+
+	breaches, err := validator.LoadBreachChecker(cfg.BreachedPasswordsPath)
+	if err != nil {
+		log.Fatalf("invalid breached password config: %v", err)
+	}
+	passwordChecker := &validator.PasswordChecker{MinScore: cfg.PasswordMinScore, Breaches: breaches}
+
+	hashParams := auth.DefaultArgon2idParams
+	hashParams.Memory = cfg.PasswordHashMemory
+	hashParams.Iterations = cfg.PasswordHashIterations
+	hashParams.Parallelism = cfg.PasswordHashParallelism
+	pepper, err := auth.LoadPepper(cfg.PasswordPepperKeys, cfg.PasswordPepperKeyFile, cfg.PasswordPepperCurrent)
+	if err != nil {
+		log.Fatalf("invalid password pepper config: %v", err)
+	}
+	hasher, err := auth.NewPasswordHasher(hashParams, pepper, cfg.PasswordHashConcurrency)
+	if err != nil {
+		log.Fatalf("invalid password hash config: %v", err)
+	}
+
+	verificationService := services.NewVerificationService(repo, userTokenRepo, mail, services.VerificationConfig{
+		BaseURL:  cfg.BaseURL,
+		TokenTTL: cfg.EmailVerificationTTL,
+	})
+	userService := services.NewUserService(repo, hasher)
+	sessionService := services.NewSessionService(sessionRepo, cfg.SessionTTL)
+	tokenService := services.NewTokenService(refreshTokenRepo, signer, services.TokenConfig{
+		Issuer:     cfg.JWTIssuer,
+		AccessTTL:  cfg.AccessTokenTTL,
+		RefreshTTL: cfg.RefreshTokenTTL,
+	})
+	passwordService := services.NewPasswordService(repo, userTokenRepo, mail, hasher, services.PasswordConfig{
+		BaseURL:   cfg.BaseURL,
+		TokenTTL:  cfg.PasswordResetTTL,
+		Rules:     rules,
+		Passwords: passwordChecker,
+	})
+	auditService := services.NewAuditService(auditRepo)
+	exportService := services.NewExportService(repo, sessionRepo, auditRepo, exportRepo, services.ExportConfig{
+		TTL:              cfg.ExportTTL,
+		InlineMaxRecords: int64(cfg.ExportInlineMaxRecords),
+	})
+	accountService := services.NewAccountService(repo, services.AccountConfig{
+		Grace:     cfg.AccountDeletionGrace,
+		PurgeMode: cfg.AccountPurgeMode,
+	})
+	webhookService := services.NewWebhookService(webhookRepo, outboxRepo, repo, webhooks.NewSender(cfg.WebhookTimeout))
+
+	dispatcher := outbox.NewDispatcher(outboxRepo, outbox.Config{
+		PollInterval: cfg.OutboxPollInterval,
+		BatchSize:    cfg.OutboxBatchSize,
+		Lease:        cfg.OutboxLease,
+		MaxAttempts:  cfg.OutboxMaxAttempts,
+		BaseBackoff:  cfg.OutboxBaseBackoff,
+		MaxBackoff:   cfg.OutboxMaxBackoff,
+		Retention:    cfg.OutboxRetention,
+	})
+	dispatcher.Register(models.EventVerificationRequested, services.SendVerification(verificationService))
+	dispatcher.Register(models.EventWelcomeRequested, services.SendWelcome(verificationService))
+	for _, eventType := range models.WebhookEvents {
+		dispatcher.Register(eventType, services.FanOutWebhooks(webhookService))
+	}
+	dispatcher.Register(models.EventWebhookDelivery, services.DeliverWebhook(webhookService))
+	dispatcher.Register(models.EventDataExportRequested, services.BuildDataExport(exportService))
+	dispatcher.Register(models.EventPasswordResetRequested, services.SendPasswordReset(passwordService))
+
+	metricsRegistry := metrics.NewRegistry()
+
+	validationMode, err := validator.ParseMode(cfg.ValidationMode)
+	if err != nil {
+		log.Fatalf("invalid validation config: %v", err)
+	}
+	profanity, err := validator.LoadProfanityList(cfg.UsernameProfanityFile)
+	if err != nil {
+		log.Fatalf("invalid username policy config: %v", err)
+	}
+	usernamePolicy := validator.NewUsernamePolicy(profanity, repo)
+
+	validators := validator.NewRegistry(
+		validator.NewFieldValidator(rules, usernamePolicy, passwordChecker),
+		validator.NewCrossFieldValidator(),
+		validator.NewBusinessValidator(repo),
+	)
+	pipeline, err := validators.Pipeline(validator.PipelineConfig{
+		Stages:  cfg.ValidationPipeline,
+		Mode:    validationMode,
+		Metrics: metricsRegistry,
+	})
+	if err != nil {
+		log.Fatalf("invalid validation config: %v", err)
+	}
+	log.Printf("registration validation pipeline: %v (%s)", pipeline.Stages(), validationMode)
+
+	draftService := services.NewDraftService(draftRepo, pipeline, cfg.DraftTTL)
+	usernameService := services.NewUsernameService(repo, rules, usernamePolicy)
+
+	sessionCookie := handlers.SessionCookieConfig{
+		Name:   cfg.SessionCookieName,
+		Secure: cfg.SessionCookieSecure,
+	}
 
 	registerHandler := handlers.NewRegisterHandler(userService)
-	usernameHandler := handlers.NewUsernameHandler(repo)
+	usernameHandler := handlers.NewUsernameHandler(usernameService)
+	loginHandler := handlers.NewLoginHandler(userService, sessionService, tokenService, auditService, sessionCookie)
+	refreshHandler := handlers.NewRefreshHandler(tokenService)
+	logoutHandler := handlers.NewLogoutHandler(sessionService, tokenService, sessionCookie)
+	verifyEmailHandler := handlers.NewVerifyEmailHandler(verificationService)
+	forgotPasswordHandler := handlers.NewForgotPasswordHandler(passwordService)
+	resetPasswordHandler := handlers.NewResetPasswordHandler(passwordService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	rulesHandler := handlers.NewValidationRulesHandler(rules)
+	draftHandler := handlers.NewDraftHandler(draftService, userService)
+	passwordKeyHandler := handlers.NewPasswordKeyHandler(userService)
+	exportHandler := handlers.NewExportHandler(exportService, auditService)
+	accountHandler := handlers.NewAccountHandler(accountService, auditService, sessionCookie)
 
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return response.SendSuccess(c, http.StatusOK, fiber.Map{"status": "ok"})
@@ -51,17 +248,104 @@ func New(cfg *config.Config) *fiber.App {
 		return response.SendSuccess(c, http.StatusOK, fiber.Map{"status": "ready"})
 	})
 
+	app.Get("/metrics", middleware.RequireMetricsToken(cfg.MetricsToken), func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, metrics.ContentType)
+		return metricsRegistry.Write(c)
+	})
+
+	// Finalizing a draft registers an account, so it shares the register budget
+	registerLimit := middleware.RateLimitByIP(rateLimits, "register", cfg.RegisterRateLimit)
+	stepsLimit := middleware.RateLimitByIP(rateLimits, "registration-steps", cfg.StepsRateLimit)
+
 	api := app.Group("/api")
 	api.Post("/register",
+		registerLimit,
+		middleware.Idempotency(idempotencyRepo, middleware.IdempotencyConfig{TTL: cfg.IdempotencyTTL}),
 		middleware.ParseRegistrationJSON(),
-		middleware.FieldValidator(),
-		middleware.CrossFieldValidator(),
-		middleware.BusinessValidator(repo),
+		middleware.ValidateRegistration(pipeline),
 		func(c *fiber.Ctx) error {
 			return registerHandler.Handle(c)
 		})
-	api.Get("/username-availability", func(c *fiber.Ctx) error {
-		return usernameHandler.Handle(c)
+	api.Post("/register/validate",
+		stepsLimit,
+		middleware.ParseRegistrationJSON(),
+		middleware.ValidateRegistrationStep(pipeline),
+		func(c *fiber.Ctx) error {
+			return response.SendSuccess(c, http.StatusOK, fiber.Map{"valid": true, "step": c.Query("step")})
+		})
+	api.Post("/register/drafts", stepsLimit, func(c *fiber.Ctx) error {
+		return draftHandler.Create(c)
+	})
+	api.Get("/register/drafts/:id", stepsLimit, func(c *fiber.Ctx) error {
+		return draftHandler.Get(c)
+	})
+	api.Put("/register/drafts/:id/steps/:step", stepsLimit, func(c *fiber.Ctx) error {
+		return draftHandler.SaveStep(c)
+	})
+	api.Post("/register/drafts/:id/finalize",
+		registerLimit,
+		middleware.Idempotency(idempotencyRepo, middleware.IdempotencyConfig{TTL: cfg.IdempotencyTTL}),
+		func(c *fiber.Ctx) error {
+			return draftHandler.LoadRegistration(c)
+		},
+		middleware.ValidateRegistration(pipeline),
+		func(c *fiber.Ctx) error {
+			return draftHandler.Finalize(c)
+		})
+	api.Get("/username-availability",
+		middleware.RateLimitByIP(rateLimits, "username-availability", cfg.UsernameRateLimit),
+		func(c *fiber.Ctx) error {
+			return usernameHandler.Handle(c)
+		})
+	api.Get("/verify-email", func(c *fiber.Ctx) error {
+		return verifyEmailHandler.Handle(c)
+	})
+	api.Post("/login",
+		middleware.RateLimitByIP(rateLimits, "login", cfg.LoginRateLimit),
+		func(c *fiber.Ctx) error {
+			return loginHandler.Handle(c)
+		})
+	api.Post("/password/forgot",
+		middleware.RateLimitByIP(rateLimits, "password-forgot", cfg.ForgotPasswordRateLimit),
+		func(c *fiber.Ctx) error {
+			return forgotPasswordHandler.Handle(c)
+		})
+	api.Post("/password/reset", func(c *fiber.Ctx) error {
+		return resetPasswordHandler.Handle(c)
+	})
+	api.Post("/token/refresh", func(c *fiber.Ctx) error {
+		return refreshHandler.Handle(c)
+	})
+	api.Post("/logout", func(c *fiber.Ctx) error {
+		return logoutHandler.Handle(c)
+	})
+
+	me := api.Group("/me", middleware.RequireUser(sessionService, tokenService, repo, cfg.SessionCookieName))
+	me.Delete("/", func(c *fiber.Ctx) error {
+		return accountHandler.Delete(c)
+	})
+	me.Get("/export", func(c *fiber.Ctx) error {
+		return exportHandler.Handle(c)
+	})
+
+	admin := api.Group("/admin", middleware.RequireAdminKey(cfg.AdminAPIKey))
+	admin.Post("/webhooks", func(c *fiber.Ctx) error {
+		return webhookHandler.Create(c)
+	})
+	admin.Get("/webhooks", func(c *fiber.Ctx) error {
+		return webhookHandler.List(c)
+	})
+	admin.Delete("/webhooks/:id", func(c *fiber.Ctx) error {
+		return webhookHandler.Delete(c)
+	})
+	admin.Get("/webhooks/:id/deliveries", func(c *fiber.Ctx) error {
+		return webhookHandler.Deliveries(c)
+	})
+	admin.Post("/validation-rules/reload", func(c *fiber.Ctx) error {
+		return rulesHandler.Reload(c)
+	})
+	admin.Get("/password-keys", func(c *fiber.Ctx) error {
+		return passwordKeyHandler.Report(c)
 	})
 
 	// Static file serving for built frontend
@@ -70,5 +354,14 @@ func New(cfg *config.Config) *fiber.App {
 		return c.SendFile("../client/dist/index.html")
 	})
 
-	return app
+	return &Server{
+		App:             app,
+		Dispatcher:      dispatcher,
+		Rules:           rules,
+		Drafts:          draftService,
+		IdempotencyKeys: idempotencyRepo,
+		Exports:         exportService,
+		Accounts:        accountService,
+		Pool:            pool,
+	}
 }
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tyk-registration-server/internal/repositories"
+)
+
+// ErrAccountNotFound is returned for accounts that are unknown or already deleted
+var ErrAccountNotFound = errors.New("account not found")
+
+// purgeBatchSize is how many accounts are purged per transaction
+const purgeBatchSize = 100
+
+type AccountService interface {
+	// Delete marks the account deleted and signs it out everywhere. It returns when the
+	// account will be purged.
+	Delete(ctx context.Context, userID uuid.UUID) (time.Time, error)
+	// PurgeDeleted purges the accounts whose grace period is over
+	PurgeDeleted(ctx context.Context) (int64, error)
+}
+
+type AccountConfig struct {
+	// Grace is how long a deleted account is kept before it is purged
+	Grace time.Duration
+	// PurgeMode is models.PurgeModeDelete or models.PurgeModeAnonymize
+	PurgeMode string
+}
+
+type accountService struct {
+	users repositories.UserRepository
+	cfg   AccountConfig
+}
+
+func NewAccountService(users repositories.UserRepository, cfg AccountConfig) AccountService {
+	return &accountService{users: users, cfg: cfg}
+}
+
+func (s *accountService) Delete(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	err := s.users.SoftDeleteUser(ctx, userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return time.Time{}, ErrAccountNotFound
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(s.cfg.Grace), nil
+}
+
+func (s *accountService) PurgeDeleted(ctx context.Context) (int64, error) {
+	deletedBefore := time.Now().Add(-s.cfg.Grace)
+	var total int64
+	for {
+		n, err := s.users.PurgeDeletedUsers(ctx, deletedBefore, s.cfg.PurgeMode, purgeBatchSize)
+		total += int64(n)
+		if err != nil || n < purgeBatchSize {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/repositories"
+)
+
+type AuditService interface {
+	// Record stores event. Failures are logged rather than returned: the action being
+	// audited has already happened and shouldn't fail because of its audit trail.
+	Record(ctx context.Context, event models.AuditEvent)
+}
+
+type auditService struct {
+	repo repositories.AuditRepository
+}
+
+func NewAuditService(repo repositories.AuditRepository) AuditService {
+	return &auditService{repo: repo}
+}
+
+func (s *auditService) Record(ctx context.Context, event models.AuditEvent) {
+	event.ID = uuid.New()
+	if err := s.repo.CreateAuditEvent(ctx, &event); err != nil {
+		log.Printf("failed to record %s audit event for user %s: %v", event.Type, event.UserID, err)
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/internal/utils"
+	"tyk-registration-server/internal/validator"
+)
+
+var (
+	ErrDraftNotFound    = errors.New("registration draft not found")
+	ErrUnknownDraftStep = errors.New("unknown registration step")
+	// ErrInvalidDraftData means a submitted field has the wrong JSON type
+	ErrInvalidDraftData = errors.New("invalid draft data")
+)
+
+// passwordFields are validated with the account step but never written to a draft
+var passwordFields = []string{"password", "confirm_password"}
+
+const draftTokenBytes = 32
+
+// DraftService methods that take a token return ErrDraftNotFound when it doesn't match, so
+// a draft id on its own reveals nothing
+type DraftService interface {
+	// Create returns the new draft with its token, which is not shown again
+	Create(ctx context.Context) (*models.RegistrationDraft, error)
+	Get(ctx context.Context, id uuid.UUID, token string) (*models.RegistrationDraft, error)
+	// SaveStep validates a step's fields together with what the draft already holds and
+	// stores them if they pass. Fields that don't belong to the step are ignored. Saving
+	// pushes the draft's expiry back by the TTL.
+	SaveStep(ctx context.Context, id uuid.UUID, token, step string, fields map[string]json.RawMessage) (*models.RegistrationDraft, *validator.Failure, error)
+	// Registration assembles the full registration request from a draft and the password
+	Registration(ctx context.Context, id uuid.UUID, token string, password models.FinalizeDraftRequest) (*models.RegistrationRequest, error)
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+type draftService struct {
+	drafts   repositories.DraftRepository
+	pipeline *validator.Pipeline
+	ttl      time.Duration
+}
+
+func NewDraftService(drafts repositories.DraftRepository, pipeline *validator.Pipeline, ttl time.Duration) DraftService {
+	return &draftService{drafts: drafts, pipeline: pipeline, ttl: ttl}
+}
+
+func (s *draftService) Create(ctx context.Context) (*models.RegistrationDraft, error) {
+	token, err := utils.GenerateToken(draftTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	draft, err := s.drafts.CreateDraft(ctx, utils.HashToken(token), time.Now().Add(s.ttl))
+	if err != nil {
+		return nil, err
+	}
+	draft.Token = token
+	return draft, nil
+}
+
+func (s *draftService) Get(ctx context.Context, id uuid.UUID, token string) (*models.RegistrationDraft, error) {
+	if token == "" {
+		return nil, ErrDraftNotFound
+	}
+	draft, err := s.drafts.GetDraft(ctx, id, utils.HashToken(token))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrDraftNotFound
+	}
+	return draft, err
+}
+
+func (s *draftService) SaveStep(ctx context.Context, id uuid.UUID, token, step string, fields map[string]json.RawMessage) (*models.RegistrationDraft, *validator.Failure, error) {
+	scope, err := validator.StepScope(step)
+	if err != nil {
+		return nil, nil, ErrUnknownDraftStep
+	}
+	draft, err := s.Get(ctx, id, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged := make(map[string]json.RawMessage, len(draft.Data)+len(fields))
+	for k, v := range draft.Data {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		if scope.Includes(k) {
+			merged[k] = v
+		}
+	}
+
+	req, err := toRegistrationRequest(merged)
+	if err != nil {
+		return nil, nil, err
+	}
+	failure, err := s.pipeline.RunScoped(ctx, req, scope)
+	if err != nil || failure != nil {
+		return nil, failure, err
+	}
+
+	for _, f := range passwordFields {
+		delete(merged, f)
+	}
+	draft.Data = merged
+	if !slices.Contains(draft.CompletedSteps, step) {
+		draft.CompletedSteps = append(draft.CompletedSteps, step)
+	}
+	draft.ExpiresAt = time.Now().Add(s.ttl)
+
+	saved, err := s.drafts.UpdateDraft(ctx, draft)
+	if errors.Is(err, repositories.ErrNotFound) {
+		// Expired between the read and the write
+		return nil, nil, ErrDraftNotFound
+	}
+	return saved, nil, err
+}
+
+func (s *draftService) Registration(ctx context.Context, id uuid.UUID, token string, password models.FinalizeDraftRequest) (*models.RegistrationRequest, error) {
+	draft, err := s.Get(ctx, id, token)
+	if err != nil {
+		return nil, err
+	}
+	req, err := toRegistrationRequest(draft.Data)
+	if err != nil {
+		return nil, err
+	}
+	req.Password = password.Password
+	req.ConfirmPassword = password.ConfirmPassword
+	return req, nil
+}
+
+func (s *draftService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.drafts.DeleteExpiredDrafts(ctx)
+}
+
+func toRegistrationRequest(data map[string]json.RawMessage) (*models.RegistrationRequest, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var req models.RegistrationRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, ErrInvalidDraftData
+	}
+	return &req, nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/outbox"
+)
+
+// SendVerification emails the verification link for a newly registered user
+func SendVerification(verification VerificationService) outbox.Handler {
+	return func(ctx context.Context, event models.OutboxEvent) error {
+		var payload models.VerificationRequestedPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("%w: invalid %s payload: %v", outbox.ErrPermanent, event.Type, err)
+		}
+		userID, err := uuid.Parse(payload.UserID)
+		if err != nil {
+			return fmt.Errorf("%w: invalid user id: %v", outbox.ErrPermanent, err)
+		}
+		return verification.SendVerification(ctx, userID)
+	}
+}
+
+// SendWelcome emails the welcome message to a user who verified their address
+func SendWelcome(verification VerificationService) outbox.Handler {
+	return func(ctx context.Context, event models.OutboxEvent) error {
+		var payload models.WelcomeRequestedPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("%w: invalid %s payload: %v", outbox.ErrPermanent, event.Type, err)
+		}
+		userID, err := uuid.Parse(payload.UserID)
+		if err != nil {
+			return fmt.Errorf("%w: invalid user id: %v", outbox.ErrPermanent, err)
+		}
+		return verification.SendWelcome(ctx, userID)
+	}
+}
+
+// SendPasswordReset emails a reset link requested through the forgot password endpoint
+func SendPasswordReset(passwords PasswordService) outbox.Handler {
+	return func(ctx context.Context, event models.OutboxEvent) error {
+		var payload models.PasswordResetRequestedPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("%w: invalid %s payload: %v", outbox.ErrPermanent, event.Type, err)
+		}
+		userID, err := uuid.Parse(payload.UserID)
+		if err != nil {
+			return fmt.Errorf("%w: invalid user id: %v", outbox.ErrPermanent, err)
+		}
+		return passwords.SendReset(ctx, userID)
+	}
+}
+
+// FanOutWebhooks forwards a user lifecycle event to every subscribed webhook endpoint
+func FanOutWebhooks(webhooks WebhookService) outbox.Handler {
+	return func(ctx context.Context, event models.OutboxEvent) error {
+		return webhooks.FanOut(ctx, event)
+	}
+}
+
+// DeliverWebhook sends one queued webhook delivery. Failed deliveries are retried by the
+// outbox with backoff.
+func DeliverWebhook(webhooks WebhookService) outbox.Handler {
+	return func(ctx context.Context, event models.OutboxEvent) error {
+		var payload models.WebhookDeliveryPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("%w: invalid %s payload: %v", outbox.ErrPermanent, event.Type, err)
+		}
+		return webhooks.Deliver(ctx, payload, event.Attempts+1)
+	}
+}
+
+// BuildDataExport generates a data export queued by a user too large to export inline
+func BuildDataExport(exports ExportService) outbox.Handler {
+	return func(ctx context.Context, event models.OutboxEvent) error {
+		var payload models.DataExportRequestedPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("%w: invalid %s payload: %v", outbox.ErrPermanent, event.Type, err)
+		}
+		exportID, err := uuid.Parse(payload.ExportID)
+		if err != nil {
+			return fmt.Errorf("%w: invalid export id: %v", outbox.ErrPermanent, err)
+		}
+		return exports.Build(ctx, exportID)
+	}
+}
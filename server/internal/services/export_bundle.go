@@ -0,0 +1,103 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"tyk-registration-server/internal/models"
+)
+
+// NewExportBundle assembles a data export from what is stored about user. Consents are
+// given at registration, so they are dated with the account.
+func NewExportBundle(user *models.User, sessions []models.Session, events []models.AuditEvent, generatedAt time.Time) *models.ExportBundle {
+	bundle := &models.ExportBundle{
+		GeneratedAt: generatedAt.UTC(),
+		User: models.ExportUser{
+			ID:              user.ID.String(),
+			FirstName:       user.FirstName,
+			LastName:        user.LastName,
+			Email:           user.Email,
+			EmailVerifiedAt: user.EmailVerifiedAt,
+			Phone:           user.Phone,
+			PhoneRegion:     user.PhoneRegion,
+			PhoneType:       user.PhoneType,
+			Street:          user.Street,
+			City:            user.City,
+			State:           user.State,
+			Country:         user.Country,
+			Username:        user.Username,
+			CreatedAt:       user.CreatedAt,
+			UpdatedAt:       user.UpdatedAt,
+		},
+		Consents: []models.ExportConsent{
+			{Type: "terms", Granted: user.TermsAccepted, RecordedAt: user.CreatedAt},
+			{Type: "newsletter", Granted: user.Newsletter, RecordedAt: user.CreatedAt},
+		},
+		Sessions:    make([]models.ExportSession, len(sessions)),
+		AuditEvents: events,
+	}
+	for i, session := range sessions {
+		bundle.Sessions[i] = models.ExportSession{
+			ID:        session.ID.String(),
+			UserAgent: session.UserAgent,
+			IPAddress: session.IPAddress,
+			CreatedAt: session.CreatedAt,
+			ExpiresAt: session.ExpiresAt,
+		}
+	}
+	if bundle.AuditEvents == nil {
+		bundle.AuditEvents = []models.AuditEvent{}
+	}
+	return bundle
+}
+
+// EncodeExportBundle renders bundle as one JSON document, or as a ZIP archive with a JSON
+// file per section
+func EncodeExportBundle(bundle *models.ExportBundle, format string) ([]byte, error) {
+	switch format {
+	case models.ExportFormatJSON:
+		return json.MarshalIndent(bundle, "", "  ")
+	case models.ExportFormatZIP:
+		return zipExportBundle(bundle)
+	default:
+		return nil, ErrUnknownExportFormat
+	}
+}
+
+func zipExportBundle(bundle *models.ExportBundle) ([]byte, error) {
+	sections := []struct {
+		name    string
+		content any
+	}{
+		{"user.json", bundle.User},
+		{"consents.json", bundle.Consents},
+		{"sessions.json", bundle.Sessions},
+		{"audit_events.json", bundle.AuditEvents},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, section := range sections {
+		data, err := json.MarshalIndent(section.content, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		w, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     section.name,
+			Method:   zip.Deflate,
+			Modified: bundle.GeneratedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
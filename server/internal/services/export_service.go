@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/repositories"
+)
+
+// ErrUnknownExportFormat is returned for formats other than json and zip
+var ErrUnknownExportFormat = errors.New("unknown export format")
+
+// pendingExportTimeout is how long a background export may stay pending before another
+// request queues a new one. The outbox retries a failing build in the meantime.
+const pendingExportTimeout = time.Hour
+
+type ExportService interface {
+	// Export returns the user's data in format. Small accounts are exported inline.
+	// Larger ones are built in the background: Export queues the job and returns the
+	// pending export, then the ready one until it expires or the user's data changes.
+	Export(ctx context.Context, userID uuid.UUID, format string) (*models.DataExport, error)
+	// Build generates the bundle of a queued export
+	Build(ctx context.Context, exportID uuid.UUID) error
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+type ExportConfig struct {
+	// TTL is how long a background export stays downloadable
+	TTL time.Duration
+	// InlineMaxRecords is the most sessions and audit events an inline export may hold
+	InlineMaxRecords int64
+}
+
+type exportService struct {
+	users    repositories.UserRepository
+	sessions repositories.SessionRepository
+	audit    repositories.AuditRepository
+	exports  repositories.DataExportRepository
+	cfg      ExportConfig
+}
+
+func NewExportService(users repositories.UserRepository, sessions repositories.SessionRepository, audit repositories.AuditRepository, exports repositories.DataExportRepository, cfg ExportConfig) ExportService {
+	return &exportService{users: users, sessions: sessions, audit: audit, exports: exports, cfg: cfg}
+}
+
+func (s *exportService) Export(ctx context.Context, userID uuid.UUID, format string) (*models.DataExport, error) {
+	if format != models.ExportFormatJSON && format != models.ExportFormatZIP {
+		return nil, ErrUnknownExportFormat
+	}
+
+	latest, err := s.exports.GetLatestDataExport(ctx, userID, format)
+	switch {
+	case err == nil && latest.Status == models.ExportStatusReady:
+		current, err := s.isCurrent(ctx, latest)
+		if err != nil {
+			return nil, err
+		}
+		if current {
+			return latest, nil
+		}
+		// The data changed since it was built; don't keep the old copy around
+		if err := s.exports.DeleteDataExport(ctx, latest.ID); err != nil {
+			return nil, err
+		}
+	case err == nil:
+		if time.Since(latest.CreatedAt) < pendingExportTimeout {
+			return latest, nil
+		}
+	case !errors.Is(err, repositories.ErrNotFound):
+		return nil, err
+	}
+
+	records, err := s.exports.CountUserExportRecords(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if records <= s.cfg.InlineMaxRecords {
+		return s.exportInline(ctx, userID, format)
+	}
+	return s.queue(ctx, userID, format)
+}
+
+// isCurrent reports whether a ready export still matches the user's data. It is compared
+// with when the export was queued, since its build may have read data changed after that.
+func (s *exportService) isCurrent(ctx context.Context, export *models.DataExport) (bool, error) {
+	changedAt, err := s.exports.DataChangedAt(ctx, export.UserID)
+	if err != nil {
+		return false, err
+	}
+	return changedAt.Before(export.CreatedAt), nil
+}
+
+func (s *exportService) exportInline(ctx context.Context, userID uuid.UUID, format string) (*models.DataExport, error) {
+	now := time.Now()
+	payload, err := s.generate(ctx, userID, format, now)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DataExport{
+		UserID:      userID,
+		Format:      format,
+		Status:      models.ExportStatusReady,
+		Payload:     payload,
+		CreatedAt:   now,
+		CompletedAt: &now,
+	}, nil
+}
+
+func (s *exportService) queue(ctx context.Context, userID uuid.UUID, format string) (*models.DataExport, error) {
+	now := time.Now()
+	export := &models.DataExport{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Format:    format,
+		Status:    models.ExportStatusPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.cfg.TTL),
+	}
+	event, err := models.NewOutboxEvent(models.EventDataExportRequested, userID, models.DataExportRequestedPayload{
+		ExportID: export.ID.String(),
+		UserID:   userID.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.exports.WithTx(ctx, func(exports repositories.DataExportRepository, outbox repositories.OutboxRepository) error {
+		if err := exports.CreateDataExport(ctx, export); err != nil {
+			return err
+		}
+		return outbox.Enqueue(ctx, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (s *exportService) Build(ctx context.Context, exportID uuid.UUID) error {
+	export, err := s.exports.GetDataExport(ctx, exportID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		// Expired or the account is gone
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if export.Status == models.ExportStatusReady {
+		return nil
+	}
+
+	payload, err := s.generate(ctx, export.UserID, export.Format, time.Now())
+	if err != nil {
+		return err
+	}
+	_, err = s.exports.CompleteDataExport(ctx, export.ID, payload)
+	return err
+}
+
+func (s *exportService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.exports.DeleteExpiredDataExports(ctx)
+}
+
+func (s *exportService) generate(ctx context.Context, userID uuid.UUID, format string, now time.Time) ([]byte, error) {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.sessions.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.audit.ListUserAuditEvents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeExportBundle(NewExportBundle(user, sessions, events, now), format)
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tyk-registration-server/internal/auth"
+	"tyk-registration-server/internal/mailer"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/internal/utils"
+	"tyk-registration-server/internal/validator"
+)
+
+// ErrInvalidResetToken covers unknown, expired and already-used reset tokens
+var ErrInvalidResetToken = errors.New("invalid password reset token")
+
+// PasswordRejectedError is returned when the new password fails the registration password
+// rule, or is too guessable or breached; Message explains why
+type PasswordRejectedError struct {
+	Message string
+}
+
+func (e *PasswordRejectedError) Error() string {
+	return "password rejected: " + e.Message
+}
+
+const resetTokenBytes = 32
+
+type PasswordService interface {
+	// RequestReset queues a reset link for the user the address belongs to. Unknown
+	// addresses are not an error so callers can't probe which emails exist.
+	RequestReset(ctx context.Context, email string) error
+	// SendReset issues a fresh reset token for the user and emails the link; earlier links
+	// stop working
+	SendReset(ctx context.Context, userID uuid.UUID) error
+	// ResetPassword redeems a reset token, stores the new password and signs the user out
+	// everywhere, in one transaction
+	ResetPassword(ctx context.Context, token, newPassword string) error
+}
+
+type PasswordConfig struct {
+	BaseURL  string
+	TokenTTL time.Duration
+	// Rules are the registration field rules; a new password must pass the password field's
+	Rules *validator.RuleEngine
+	// Passwords holds the strength and breach checks; optional
+	Passwords *validator.PasswordChecker
+}
+
+type passwordService struct {
+	users  repositories.UserRepository
+	tokens repositories.UserTokenRepository
+	mail   mailer.Mailer
+	hasher auth.PasswordHasher
+	cfg    PasswordConfig
+}
+
+func NewPasswordService(users repositories.UserRepository, tokens repositories.UserTokenRepository, mail mailer.Mailer, hasher auth.PasswordHasher, cfg PasswordConfig) PasswordService {
+	return &passwordService{users: users, tokens: tokens, mail: mail, hasher: hasher, cfg: cfg}
+}
+
+// RequestReset does the same lookup for known and unknown addresses and leaves the token
+// and email to the outbox, so response time doesn't reveal which accounts exist
+func (s *passwordService) RequestReset(ctx context.Context, email string) error {
+	return s.users.WithTx(ctx, func(users repositories.UserRepository, outbox repositories.OutboxRepository) error {
+		user, err := users.GetUserByEmail(ctx, strings.TrimSpace(email))
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		event, err := models.NewOutboxEvent(models.EventPasswordResetRequested, user.ID, models.PasswordResetRequestedPayload{
+			UserID: user.ID.String(),
+		})
+		if err != nil {
+			return err
+		}
+		return outbox.Enqueue(ctx, event)
+	})
+}
+
+func (s *passwordService) SendReset(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		// Deleted since the request; there is nobody to email
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Only the most recent link should work
+	if err := s.tokens.InvalidateTokens(ctx, user.ID, models.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateToken(resetTokenBytes)
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.cfg.TokenTTL)
+	if err := s.tokens.CreateToken(ctx, user.ID, models.TokenPurposePasswordReset, utils.HashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	msg, err := mailer.Render(mailer.TemplatePasswordReset, user.Email, linkEmailData{
+		FirstName: user.FirstName,
+		Link:      s.cfg.BaseURL + "/reset-password?token=" + url.QueryEscape(token),
+		ExpiresIn: s.cfg.TokenTTL.String(),
+	})
+	if err != nil {
+		return err
+	}
+	return s.mail.Send(ctx, msg)
+}
+
+func (s *passwordService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	// Check the password first so a typo doesn't burn the single-use token
+	if msg := s.cfg.Rules.ValidateField(&models.RegistrationRequest{Password: newPassword}, "password"); msg != "" {
+		return &PasswordRejectedError{Message: msg}
+	}
+	if token == "" {
+		return ErrInvalidResetToken
+	}
+	tokenHash := utils.HashToken(token)
+
+	// The strength check needs the user's details, so look the token up without redeeming it
+	if s.cfg.Passwords != nil {
+		owner, err := s.tokens.TokenOwner(ctx, models.TokenPurposePasswordReset, tokenHash)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+		user, err := s.users.GetUserByID(ctx, owner)
+		if err != nil {
+			return err
+		}
+		msg, err := s.cfg.Passwords.Check(newPassword, user.FirstName, user.LastName, user.Username, user.Email)
+		if err != nil {
+			return err
+		}
+		if msg != "" {
+			return &PasswordRejectedError{Message: msg}
+		}
+	}
+
+	hash, err := s.hasher.Hash(ctx, newPassword)
+	if err != nil {
+		return err
+	}
+	// The token is only spent if the new password is stored, and whoever knew the old
+	// password loses their sessions with it
+	return s.users.WithTx(ctx, func(users repositories.UserRepository, _ repositories.OutboxRepository) error {
+		userID, err := users.ConsumeToken(ctx, models.TokenPurposePasswordReset, tokenHash)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+		if err := users.UpdatePasswordHash(ctx, userID, hash); err != nil {
+			return err
+		}
+		return users.SignOutEverywhere(ctx, userID)
+	})
+}
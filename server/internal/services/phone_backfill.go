@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/internal/utils"
+)
+
+// BackfillPhoneDetails normalizes phones stored before registration did (see migration
+// 000010), batchSize rows at a time, and returns how many rows it updated. Each
+// number is rewritten to its libphonenumber E.164 form with its region and type. A number
+// that does not parse keeps its stored form with type unknown. A number whose E.164 form
+// belongs to another account also keeps its stored form; those are listed in the returned
+// error so they can be resolved by hand.
+func BackfillPhoneDetails(ctx context.Context, users repositories.UserRepository, batchSize int) (int, error) {
+	updated := 0
+	var collisions []string
+	for {
+		phones, err := users.ListPhonesMissingDetails(ctx, batchSize)
+		if err != nil {
+			return updated, err
+		}
+		if len(phones) == 0 {
+			break
+		}
+
+		for _, up := range phones {
+			// Setting the type, even to unknown, takes the row out of the next batch
+			unchanged := utils.Phone{E164: up.Phone, Type: utils.PhoneTypeUnknown}
+			p, err := utils.ParsePhone(up.Phone)
+			if err != nil {
+				log.Printf("user %s: stored phone does not parse, keeping it as is", up.UserID)
+				p = unchanged
+			}
+
+			err = users.SetPhoneDetails(ctx, up.UserID, up.Phone, p)
+			var conflict *repositories.UniqueViolationError
+			if errors.As(err, &conflict) {
+				collisions = append(collisions, fmt.Sprintf("%s (%s)", up.UserID, p.E164))
+				unchanged.Region, unchanged.Type = p.Region, p.Type
+				err = users.SetPhoneDetails(ctx, up.UserID, up.Phone, unchanged)
+			}
+			if err != nil {
+				return updated, err
+			}
+			updated++
+		}
+	}
+
+	if len(collisions) > 0 {
+		return updated, fmt.Errorf("%d users have a phone another account has once normalized and were left unchanged: %s",
+			len(collisions), strings.Join(collisions, ", "))
+	}
+	return updated, nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/internal/utils"
+)
+
+// ErrInvalidSession covers unknown and expired session tokens
+var ErrInvalidSession = errors.New("invalid session")
+
+const sessionTokenBytes = 32
+
+type SessionService interface {
+	Create(ctx context.Context, userID uuid.UUID, userAgent, ipAddress string) (string, *models.Session, error)
+	// Authenticate returns the live session behind token
+	Authenticate(ctx context.Context, token string) (*models.Session, error)
+	Revoke(ctx context.Context, token string) error
+	RevokeAll(ctx context.Context, userID uuid.UUID) error
+}
+
+type sessionService struct {
+	repo repositories.SessionRepository
+	ttl  time.Duration
+}
+
+func NewSessionService(repo repositories.SessionRepository, ttl time.Duration) SessionService {
+	return &sessionService{repo: repo, ttl: ttl}
+}
+
+// Create stores a new session and returns the raw token to hand to the client.
+// Only the token's hash is persisted.
+func (s *sessionService) Create(ctx context.Context, userID uuid.UUID, userAgent, ipAddress string) (string, *models.Session, error) {
+	token, err := utils.GenerateToken(sessionTokenBytes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	session := &models.Session{
+		ID:        uuid.New(),
+		UserID:    userID,
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if err := s.repo.CreateSession(ctx, session, utils.HashToken(token)); err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+func (s *sessionService) Authenticate(ctx context.Context, token string) (*models.Session, error) {
+	if token == "" {
+		return nil, ErrInvalidSession
+	}
+	session, err := s.repo.GetSessionByTokenHash(ctx, utils.HashToken(token))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrInvalidSession
+	}
+	return session, err
+}
+
+// Revoke deletes the session behind token; unknown tokens are ignored
+func (s *sessionService) Revoke(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+	return s.repo.DeleteSession(ctx, utils.HashToken(token))
+}
+
+func (s *sessionService) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	return s.repo.DeleteUserSessions(ctx, userID)
+}
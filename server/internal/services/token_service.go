@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tyk-registration-server/internal/auth"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/internal/utils"
+)
+
+var (
+	// ErrInvalidRefreshToken covers unknown, expired and revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already-rotated token is presented again;
+	// the whole token family is revoked when this happens
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrInvalidAccessToken covers malformed, forged and expired access tokens
+	ErrInvalidAccessToken = errors.New("invalid access token")
+)
+
+const refreshTokenBytes = 32
+
+type TokenService interface {
+	// Issue mints an access token and starts a new refresh token family
+	Issue(ctx context.Context, userID uuid.UUID) (*models.TokenPair, error)
+	// Refresh rotates a refresh token and returns a fresh pair in the same family
+	Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error)
+	// Revoke invalidates the refresh token's whole family
+	Revoke(ctx context.Context, refreshToken string) error
+	// RevokeAll invalidates every refresh token the user holds
+	RevokeAll(ctx context.Context, userID uuid.UUID) error
+	// VerifyAccessToken checks an access token issued by Issue and returns its user
+	VerifyAccessToken(token string) (uuid.UUID, error)
+}
+
+type TokenConfig struct {
+	Issuer     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+type tokenService struct {
+	repo   repositories.RefreshTokenRepository
+	signer auth.Signer
+	cfg    TokenConfig
+}
+
+func NewTokenService(repo repositories.RefreshTokenRepository, signer auth.Signer, cfg TokenConfig) TokenService {
+	return &tokenService{repo: repo, signer: signer, cfg: cfg}
+}
+
+func (s *tokenService) Issue(ctx context.Context, userID uuid.UUID) (*models.TokenPair, error) {
+	return s.issue(ctx, userID, uuid.New())
+}
+
+func (s *tokenService) Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	stored, err := s.lookup(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if stored.UsedAt != nil {
+		return nil, s.revokeReused(ctx, stored)
+	}
+
+	// The conditional update is the rotation lock: of two concurrent refreshes only one wins
+	marked, err := s.repo.MarkUsed(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		return nil, s.revokeReused(ctx, stored)
+	}
+
+	return s.issue(ctx, stored.UserID, stored.FamilyID)
+}
+
+func (s *tokenService) Revoke(ctx context.Context, refreshToken string) error {
+	stored, err := s.lookup(ctx, refreshToken)
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.repo.RevokeFamily(ctx, stored.FamilyID)
+}
+
+func (s *tokenService) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	return s.repo.RevokeUserTokens(ctx, userID)
+}
+
+func (s *tokenService) VerifyAccessToken(token string) (uuid.UUID, error) {
+	claims, err := s.signer.Verify(token, s.cfg.Issuer)
+	if err != nil {
+		return uuid.Nil, ErrInvalidAccessToken
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, ErrInvalidAccessToken
+	}
+	return userID, nil
+}
+
+func (s *tokenService) lookup(ctx context.Context, refreshToken string) (*models.RefreshToken, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	stored, err := s.repo.GetRefreshTokenByHash(ctx, utils.HashToken(refreshToken))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	return stored, err
+}
+
+func (s *tokenService) revokeReused(ctx context.Context, stored *models.RefreshToken) error {
+	log.Printf("refresh token reuse detected for user %s, revoking family %s", stored.UserID, stored.FamilyID)
+	if err := s.repo.RevokeFamily(ctx, stored.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func (s *tokenService) issue(ctx context.Context, userID, familyID uuid.UUID) (*models.TokenPair, error) {
+	now := time.Now()
+	access, err := s.signer.Sign(auth.Claims{
+		Issuer:    s.cfg.Issuer,
+		Subject:   userID.String(),
+		ID:        uuid.NewString(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.cfg.AccessTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := utils.GenerateToken(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	stored := &models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		ExpiresAt: now.Add(s.cfg.RefreshTTL),
+	}
+	if err := s.repo.CreateRefreshToken(ctx, stored, utils.HashToken(refresh)); err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.cfg.AccessTTL.Seconds()),
+	}, nil
+}
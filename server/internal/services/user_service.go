@@ -2,31 +2,218 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
 
 	"github.com/google/uuid"
 
+	"tyk-registration-server/internal/auth"
 	"tyk-registration-server/internal/models"
 	"tyk-registration-server/internal/repositories"
-	"tyk-registration-server/internal/utils"
 )
 
+// ErrInvalidCredentials is returned for both unknown identifiers and wrong passwords
+// so callers cannot tell which accounts exist
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ConflictError is returned by Register when another account took one of the request's
+// unique fields after validation passed. Fields maps each field to a user-facing message.
+type ConflictError struct {
+	Fields map[string]string
+}
+
+func (e *ConflictError) Error() string {
+	return "registration conflicts with an existing account"
+}
+
+// conflictMessages match the business validation stage, so a lost race reads the same as
+// an ordinary duplicate
+var conflictMessages = map[string]string{
+	"email":    "Email is already registered",
+	"username": "Username is already taken",
+	"phone":    "Phone number is already registered",
+}
+
 type UserService interface {
 	Register(ctx context.Context, req *models.RegistrationRequest) (uuid.UUID, error)
+	// RegisterDraft is Register for a request assembled from a draft, which is deleted in
+	// the same transaction. It returns ErrDraftNotFound if the draft was already finalized
+	// or has expired, so a draft registers at most one user.
+	RegisterDraft(ctx context.Context, draftID uuid.UUID, req *models.RegistrationRequest) (uuid.UUID, error)
+	Authenticate(ctx context.Context, identifier, password string) (*models.User, error)
+	// PasswordKeyReport counts users per password hash algorithm and pepper key, to track
+	// a key rotation
+	PasswordKeyReport(ctx context.Context) (*models.PasswordKeyReport, error)
 }
 
 type userService struct {
-	repo repositories.UserRepository
+	repo   repositories.UserRepository
+	hasher auth.PasswordHasher
+
+	dummyHashOnce sync.Once
+	dummyHash     string
 }
 
-func NewUserService(repo repositories.UserRepository) UserService {
-	return &userService{repo: repo}
+func NewUserService(repo repositories.UserRepository, hasher auth.PasswordHasher) UserService {
+	return &userService{repo: repo, hasher: hasher}
 }
 
+// Register creates the user in the unverified state. Side effects such as the verification
+// email are recorded as outbox events in the same transaction and delivered asynchronously.
 func (s *userService) Register(ctx context.Context, req *models.RegistrationRequest) (uuid.UUID, error) {
-	hash, err := utils.HashPassword(req.Password)
+	return s.register(ctx, uuid.Nil, req)
+}
+
+func (s *userService) RegisterDraft(ctx context.Context, draftID uuid.UUID, req *models.RegistrationRequest) (uuid.UUID, error) {
+	return s.register(ctx, draftID, req)
+}
+
+// register deletes the draft first, when there is one: its row lock makes a concurrent
+// finalize of the same draft wait, then find it gone
+func (s *userService) register(ctx context.Context, draftID uuid.UUID, req *models.RegistrationRequest) (uuid.UUID, error) {
+	hash, err := s.hasher.Hash(ctx, req.Password)
 	if err != nil {
 		return uuid.Nil, err
 	}
 
-	return s.repo.CreateUser(ctx, req, hash)
+	var id uuid.UUID
+	err = s.repo.WithTx(ctx, func(users repositories.UserRepository, outbox repositories.OutboxRepository) error {
+		if draftID != uuid.Nil {
+			err := users.DeleteDraft(ctx, draftID)
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrDraftNotFound
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		var err error
+		id, err = users.CreateUser(ctx, req, hash)
+		if err != nil {
+			return conflict(err)
+		}
+
+		event, err := models.NewOutboxEvent(models.EventUserRegistered, id, models.UserRegisteredPayload{
+			UserID: id.String(),
+		})
+		if err != nil {
+			return err
+		}
+		if err := outbox.Enqueue(ctx, event); err != nil {
+			return err
+		}
+		verification, err := models.NewOutboxEvent(models.EventVerificationRequested, id, models.VerificationRequestedPayload{
+			UserID: id.String(),
+		})
+		if err != nil {
+			return err
+		}
+		if err := outbox.Enqueue(ctx, verification); err != nil {
+			return err
+		}
+
+		if !req.Newsletter {
+			return nil
+		}
+		subscribed, err := models.NewOutboxEvent(models.EventUserNewsletterSubscribed, id, models.UserNewsletterSubscribedPayload{
+			UserID: id.String(),
+		})
+		if err != nil {
+			return err
+		}
+		return outbox.Enqueue(ctx, subscribed)
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// conflict turns a unique violation on a known field into a ConflictError
+func conflict(err error) error {
+	var dup *repositories.UniqueViolationError
+	if errors.As(err, &dup) {
+		if msg, ok := conflictMessages[dup.Field]; ok {
+			return &ConflictError{Fields: map[string]string{dup.Field: msg}}
+		}
+	}
+	return err
+}
+
+// Authenticate looks the user up by email (if the identifier contains "@") or username
+// and verifies the password against the stored hash. A hash made with an older algorithm
+// or parameters is replaced while the plain password is at hand.
+func (s *userService) Authenticate(ctx context.Context, identifier, password string) (*models.User, error) {
+	identifier = strings.TrimSpace(identifier)
+
+	var user *models.User
+	var err error
+	if strings.Contains(identifier, "@") {
+		user, err = s.repo.GetUserByEmail(ctx, identifier)
+	} else {
+		user, err = s.repo.GetUserByUsername(ctx, identifier)
+	}
+	if errors.Is(err, repositories.ErrNotFound) {
+		// Burn the same hashing work as a real check so response time doesn't reveal unknown accounts
+		_, _, _ = s.hasher.Verify(ctx, s.timingHash(), password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ok, rehash, err := s.hasher.Verify(ctx, user.PasswordHash, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify password of user %s: %w", user.ID, err)
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	if rehash {
+		s.rehash(ctx, user, password)
+	}
+	return user, nil
+}
+
+// rehash upgrades the user's stored hash. Failing to is logged rather than failing the
+// login: the old hash still works and the next login tries again.
+func (s *userService) rehash(ctx context.Context, user *models.User, password string) {
+	hash, err := s.hasher.Hash(ctx, password)
+	if err != nil {
+		log.Printf("failed to rehash password of user %s: %v", user.ID, err)
+		return
+	}
+	replaced, err := s.repo.ReplacePasswordHash(ctx, user.ID, user.PasswordHash, hash)
+	if err != nil {
+		log.Printf("failed to store rehashed password of user %s: %v", user.ID, err)
+		return
+	}
+	if replaced {
+		user.PasswordHash = hash
+	}
+}
+
+func (s *userService) PasswordKeyReport(ctx context.Context) (*models.PasswordKeyReport, error) {
+	usage, err := s.repo.CountPasswordHashesByKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := s.hasher.KeyID()
+	for i := range usage {
+		usage[i].Current = usage[i].Algorithm == "argon2id" && usage[i].KeyID == current
+	}
+	return &models.PasswordKeyReport{CurrentKeyID: current, Hashes: usage}, nil
+}
+
+// timingHash is a hash in the current format to verify against for unknown accounts. It is
+// computed once for the process, so it isn't tied to the context of the login that asks first.
+func (s *userService) timingHash() string {
+	s.dummyHashOnce.Do(func() {
+		s.dummyHash, _ = s.hasher.Hash(context.Background(), "dummy-password-for-timing")
+	})
+	return s.dummyHash
 }
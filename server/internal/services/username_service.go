@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/utils"
+	"tyk-registration-server/internal/validator"
+)
+
+// MaxUsernameSuggestions caps the alternatives offered for an unavailable username
+const MaxUsernameSuggestions = 5
+
+// UsernameLookup finds which of a batch of usernames are taken, keyed by canonical form;
+// repositories.UserRepository implements it
+type UsernameLookup interface {
+	TakenUsernames(ctx context.Context, usernames []string) (map[string]bool, error)
+}
+
+type UsernameService interface {
+	// Availability reports whether username can be registered. If it can't, it suggests
+	// available alternatives built from the username and the optional first and last name.
+	Availability(ctx context.Context, username, firstName, lastName string) (*models.UsernameAvailabilityResponse, error)
+}
+
+type usernameService struct {
+	users  UsernameLookup
+	rules  *validator.RuleEngine
+	policy *validator.UsernamePolicy
+}
+
+// NewUsernameService checks usernames against the field rules and the username policy. The
+// lookup reports lookalikes of registered names as taken, which covers the policy's database
+// check for the whole batch.
+func NewUsernameService(users UsernameLookup, rules *validator.RuleEngine, policy *validator.UsernamePolicy) UsernameService {
+	return &usernameService{users: users, rules: rules, policy: policy}
+}
+
+func (s *usernameService) Availability(ctx context.Context, username, firstName, lastName string) (*models.UsernameAvailabilityResponse, error) {
+	username = strings.TrimSpace(username)
+	resp := &models.UsernameAvailabilityResponse{Username: username}
+
+	// The requested name and every candidate are looked up together in one query; only names
+	// the field rules accept are worth looking up
+	var batch, candidates []string
+	resp.Message = s.violation(username)
+	valid := resp.Message == ""
+	if valid {
+		batch = append(batch, username)
+	}
+	for _, c := range usernameCandidates(username, firstName, lastName) {
+		if s.violation(c) == "" {
+			candidates = append(candidates, c)
+		}
+	}
+	batch = append(batch, candidates...)
+	if len(batch) == 0 {
+		return resp, nil
+	}
+
+	taken, err := s.users.TakenUsernames(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Available = valid && !taken[utils.CanonicalUsername(username)]
+	if resp.Available {
+		return resp, nil
+	}
+	for _, c := range candidates {
+		if taken[utils.CanonicalUsername(c)] {
+			continue
+		}
+		resp.Suggestions = append(resp.Suggestions, c)
+		if len(resp.Suggestions) == MaxUsernameSuggestions {
+			break
+		}
+	}
+	return resp, nil
+}
+
+// violation returns the message of the first field rule or policy check username fails
+func (s *usernameService) violation(username string) string {
+	if msg := s.rules.ValidateField(&models.RegistrationRequest{Username: username}, "username"); msg != "" {
+		return msg
+	}
+	return s.policy.Violation(username)
+}
+
+// usernameCandidates lists alternatives to username in order of preference: handles built from
+// the person's name read most naturally, then the requested name (or the name) with a number.
+// Candidates are unique by canonical form and never the requested name itself.
+func usernameCandidates(username, firstName, lastName string) []string {
+	first, last := nameToken(firstName), nameToken(lastName)
+
+	seen := map[string]bool{utils.CanonicalUsername(username): true}
+	var out []string
+	add := func(c string) {
+		key := utils.CanonicalUsername(c)
+		if key == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		out = append(out, c)
+	}
+
+	if first != "" && last != "" {
+		add(first + last)
+		add(first + "." + last)
+		add(first + "_" + last)
+		add(initial(first) + last)
+		add(first + initial(last))
+		add(last + first)
+		add(last + "." + first)
+	}
+
+	// "johndoe123" is taken: number "johndoe" rather than "johndoe123"
+	stem := strings.TrimRightFunc(username, unicode.IsDigit)
+	if stem == "" {
+		stem = first + last
+	}
+	if stem == "" {
+		return out
+	}
+	for i := 1; i <= 9; i++ {
+		add(stem + strconv.Itoa(i))
+	}
+	for _, sep := range []string{"_", "."} {
+		add(stem + sep + strconv.Itoa(rand.IntN(90)+10))
+	}
+	// Three-digit numbers as a fallback for common names where the short ones are gone
+	for range 3 {
+		add(stem + strconv.Itoa(rand.IntN(900)+100))
+	}
+	return out
+}
+
+// nameToken reduces a name to lowercase letters and digits, e.g. "Mary-Jane" to "maryjane"
+func nameToken(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+func initial(s string) string {
+	for _, r := range s {
+		return string(r)
+	}
+	return ""
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tyk-registration-server/internal/mailer"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/internal/utils"
+)
+
+// ErrInvalidVerificationToken covers unknown, expired and already-used verification tokens
+var ErrInvalidVerificationToken = errors.New("invalid verification token")
+
+const verificationTokenBytes = 32
+
+type VerificationService interface {
+	// SendVerification issues a fresh single-use token and emails the verification link;
+	// earlier links stop working. Nothing is sent if the user is gone or already verified.
+	SendVerification(ctx context.Context, userID uuid.UUID) error
+	// VerifyEmail redeems a token and marks the owner's email as verified
+	VerifyEmail(ctx context.Context, token string) error
+	// SendWelcome emails the welcome message once the address is verified. Nothing is sent
+	// if the user is gone.
+	SendWelcome(ctx context.Context, userID uuid.UUID) error
+}
+
+type VerificationConfig struct {
+	BaseURL  string // Public base URL used to build the link, e.g. https://example.com
+	TokenTTL time.Duration
+}
+
+type verificationService struct {
+	users  repositories.UserRepository
+	tokens repositories.UserTokenRepository
+	mail   mailer.Mailer
+	cfg    VerificationConfig
+}
+
+func NewVerificationService(users repositories.UserRepository, tokens repositories.UserTokenRepository, mail mailer.Mailer, cfg VerificationConfig) VerificationService {
+	return &verificationService{users: users, tokens: tokens, mail: mail, cfg: cfg}
+}
+
+func (s *verificationService) SendVerification(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	// Only the most recent link should work
+	if err := s.tokens.InvalidateTokens(ctx, user.ID, models.TokenPurposeEmailVerification); err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateToken(verificationTokenBytes)
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.cfg.TokenTTL)
+	if err := s.tokens.CreateToken(ctx, user.ID, models.TokenPurposeEmailVerification, utils.HashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	msg, err := mailer.Render(mailer.TemplateVerification, user.Email, linkEmailData{
+		FirstName: user.FirstName,
+		Link:      s.cfg.BaseURL + "/api/verify-email?token=" + url.QueryEscape(token),
+		ExpiresIn: s.cfg.TokenTTL.String(),
+	})
+	if err != nil {
+		return err
+	}
+	return s.mail.Send(ctx, msg)
+}
+
+func (s *verificationService) VerifyEmail(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrInvalidVerificationToken
+	}
+	// The token is only spent if the verification commits, so a failure can be retried
+	// with the same link. The welcome email is queued with it and sent by the outbox.
+	return s.users.WithTx(ctx, func(users repositories.UserRepository, outbox repositories.OutboxRepository) error {
+		userID, err := users.ConsumeToken(ctx, models.TokenPurposeEmailVerification, utils.HashToken(token))
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		if err != nil {
+			return err
+		}
+		if err := users.MarkEmailVerified(ctx, userID); err != nil {
+			return err
+		}
+
+		verified, err := models.NewOutboxEvent(models.EventUserEmailVerified, userID, models.UserEmailVerifiedPayload{
+			UserID: userID.String(),
+		})
+		if err != nil {
+			return err
+		}
+		if err := outbox.Enqueue(ctx, verified); err != nil {
+			return err
+		}
+		welcome, err := models.NewOutboxEvent(models.EventWelcomeRequested, userID, models.WelcomeRequestedPayload{
+			UserID: userID.String(),
+		})
+		if err != nil {
+			return err
+		}
+		return outbox.Enqueue(ctx, welcome)
+	})
+}
+
+func (s *verificationService) SendWelcome(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	msg, err := mailer.Render(mailer.TemplateWelcome, user.Email, welcomeEmailData{
+		FirstName: user.FirstName,
+		Username:  user.Username,
+	})
+	if err != nil {
+		return err
+	}
+	return s.mail.Send(ctx, msg)
+}
+
+// linkEmailData feeds templates that carry a single-use link
+type linkEmailData struct {
+	FirstName string
+	Link      string
+	ExpiresIn string
+}
+
+type welcomeEmailData struct {
+	FirstName string
+	Username  string
+}
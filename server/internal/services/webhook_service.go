@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/outbox"
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/internal/utils"
+	"tyk-registration-server/internal/webhooks"
+)
+
+var ErrWebhookNotFound = errors.New("webhook endpoint not found")
+
+const webhookSecretBytes = 32
+
+type WebhookService interface {
+	// CreateEndpoint registers an endpoint. The returned endpoint carries the signing secret,
+	// which is generated when req.Secret is empty.
+	CreateEndpoint(ctx context.Context, req *models.CreateWebhookRequest) (*models.WebhookEndpoint, error)
+	ListEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, endpointID uuid.UUID, limit int) ([]models.WebhookDelivery, error)
+
+	// FanOut queues one delivery per endpoint subscribed to the event's type
+	FanOut(ctx context.Context, event models.OutboxEvent) error
+	// Deliver sends one queued delivery and records the attempt in the endpoint's log. The
+	// event's data is built from the user's current record; nothing is sent for a user
+	// that no longer exists.
+	Deliver(ctx context.Context, payload models.WebhookDeliveryPayload, attempt int) error
+}
+
+type webhookService struct {
+	webhooks repositories.WebhookRepository
+	outbox   repositories.OutboxRepository
+	users    repositories.UserRepository
+	sender   *webhooks.Sender
+}
+
+func NewWebhookService(webhookRepo repositories.WebhookRepository, outboxRepo repositories.OutboxRepository, users repositories.UserRepository, sender *webhooks.Sender) WebhookService {
+	return &webhookService{webhooks: webhookRepo, outbox: outboxRepo, users: users, sender: sender}
+}
+
+func (s *webhookService) CreateEndpoint(ctx context.Context, req *models.CreateWebhookRequest) (*models.WebhookEndpoint, error) {
+	secret := req.Secret
+	if secret == "" {
+		token, err := utils.GenerateToken(webhookSecretBytes)
+		if err != nil {
+			return nil, err
+		}
+		secret = "whsec_" + token
+	}
+	return s.webhooks.CreateEndpoint(ctx, req.URL, secret, req.Events)
+}
+
+func (s *webhookService) ListEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	return s.webhooks.ListEndpoints(ctx)
+}
+
+func (s *webhookService) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	err := s.webhooks.DeleteEndpoint(ctx, id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return ErrWebhookNotFound
+	}
+	return err
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, endpointID uuid.UUID, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.webhooks.GetEndpoint(ctx, endpointID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return s.webhooks.ListDeliveries(ctx, endpointID, limit)
+}
+
+func (s *webhookService) FanOut(ctx context.Context, event models.OutboxEvent) error {
+	endpoints, err := s.webhooks.ListSubscribedEndpoints(ctx, event.Type)
+	if err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	// The webhook event keeps the source event's id, so a repeated fan-out produces
+	// duplicates that receivers can recognise
+	webhookEvent := models.WebhookEvent{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: time.Now().UTC(),
+		Data:      event.Payload,
+	}
+
+	// Each endpoint gets its own outbox event so retries and dead-lettering are per endpoint.
+	// Its id is derived from the source event and the endpoint, so a fan-out retried after
+	// a partial failure skips the deliveries it already queued. It keeps the source event's
+	// aggregate, the user, so purging the user removes the deliveries still queued.
+	for _, endpoint := range endpoints {
+		delivery, err := models.NewOutboxEvent(models.EventWebhookDelivery, event.AggregateID, models.WebhookDeliveryPayload{
+			EndpointID: endpoint.ID.String(),
+			Event:      webhookEvent,
+		})
+		if err != nil {
+			return err
+		}
+		delivery.ID = uuid.NewSHA1(event.ID, endpoint.ID[:])
+		if err := s.outbox.Enqueue(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *webhookService) Deliver(ctx context.Context, payload models.WebhookDeliveryPayload, attempt int) error {
+	endpointID, err := uuid.Parse(payload.EndpointID)
+	if err != nil {
+		return fmt.Errorf("%w: invalid endpoint id: %v", outbox.ErrPermanent, err)
+	}
+	endpoint, err := s.webhooks.GetEndpoint(ctx, endpointID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		// Deleted since the event was queued; nothing left to deliver to
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !endpoint.Active {
+		return nil
+	}
+
+	event := payload.Event
+	event.Data, err = s.userEventData(ctx, event)
+	if errors.Is(err, repositories.ErrNotFound) {
+		// Deleted since the event was queued; their data is no longer ours to send
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%w: %v", outbox.ErrPermanent, err)
+	}
+
+	start := time.Now()
+	status, sendErr := s.sender.Send(ctx, endpoint.URL, endpoint.Secret, payload.Event.ID.String(), body)
+
+	record := models.WebhookDelivery{
+		EndpointID: endpoint.ID,
+		EventID:    payload.Event.ID,
+		EventType:  payload.Event.Type,
+		Attempt:    attempt,
+		Success:    sendErr == nil,
+		DurationMs: int(time.Since(start).Milliseconds()),
+	}
+	if status != 0 {
+		record.StatusCode = &status
+	}
+	if sendErr != nil {
+		msg := sendErr.Error()
+		record.Error = &msg
+	}
+	if err := s.webhooks.RecordDelivery(ctx, record); err != nil {
+		// Failing here would resend a delivery that may have succeeded
+		log.Printf("failed to record webhook delivery for endpoint %s: %v", endpoint.ID, err)
+	}
+	return sendErr
+}
+
+// userEventData builds the data of a user event from the user's record. The queued event
+// only carries the user's ID.
+func (s *webhookService) userEventData(ctx context.Context, event models.WebhookEvent) (json.RawMessage, error) {
+	var ref struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.Unmarshal(event.Data, &ref); err != nil {
+		return nil, fmt.Errorf("%w: invalid %s data: %v", outbox.ErrPermanent, event.Type, err)
+	}
+	userID, err := uuid.Parse(ref.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid user id: %v", outbox.ErrPermanent, err)
+	}
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := models.UserWebhookData{UserID: user.ID.String(), Email: user.Email}
+	switch event.Type {
+	case models.EventUserRegistered:
+		data.FirstName, data.Username, data.Newsletter = user.FirstName, user.Username, &user.Newsletter
+	case models.EventUserNewsletterSubscribed:
+		data.FirstName = user.FirstName
+	}
+	return json.Marshal(data)
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed email_providers.yaml
+var defaultEmailProviders []byte
+
+// EmailProvider describes how a mail provider treats variants of the same mailbox
+type EmailProvider struct {
+	Domains      []string `yaml:"domains"`        // The first is the primary domain; the rest are aliases of it
+	IgnoreDots   bool     `yaml:"ignore_dots"`    // Dots in the local part are not significant
+	StripPlusTag bool     `yaml:"strip_plus_tag"` // Everything from the first "+" in the local part is a tag
+}
+
+// EmailRules decides when two addresses reach the same mailbox
+type EmailRules struct {
+	// FoldLocalCase lowercases the local part. It is case-sensitive on paper, but no
+	// mainstream provider treats it that way. Domains are always case-insensitive.
+	FoldLocalCase bool
+
+	providers map[string]EmailProvider // Keyed by every domain the provider answers to
+}
+
+type emailProvidersFile struct {
+	Providers []EmailProvider `yaml:"providers"`
+}
+
+// ParseEmailRules builds rules from a providers file. See email_providers.yaml for the format.
+func ParseEmailRules(data []byte, foldLocalCase bool) (*EmailRules, error) {
+	var file emailProvidersFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse email providers: %w", err)
+	}
+
+	rules := &EmailRules{FoldLocalCase: foldLocalCase, providers: make(map[string]EmailProvider)}
+	for i, p := range file.Providers {
+		if len(p.Domains) == 0 {
+			return nil, fmt.Errorf("email provider %d: no domains", i)
+		}
+		for j, d := range p.Domains {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if _, dup := rules.providers[d]; dup {
+				return nil, fmt.Errorf("email provider %d: domain %q listed twice", i, d)
+			}
+			p.Domains[j] = d
+		}
+		for _, d := range p.Domains {
+			rules.providers[d] = p
+		}
+	}
+	return rules, nil
+}
+
+// LoadEmailRules reads the providers file at path, or the built-in list when path is empty
+func LoadEmailRules(path string, foldLocalCase bool) (*EmailRules, error) {
+	data := defaultEmailProviders
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("read email providers: %w", err)
+		}
+	}
+	return ParseEmailRules(data, foldLocalCase)
+}
+
+// DefaultEmailRules returns the built-in provider list with the local part case-folded
+func DefaultEmailRules() *EmailRules {
+	rules, err := ParseEmailRules(defaultEmailProviders, true)
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// Canonical returns the form of an address used to decide whether two addresses reach the
+// same mailbox. Provider rules fold variants such as "j.doe+news@googlemail.com" into
+// "jdoe@gmail.com"; other domains keep their local part as typed (apart from case, when
+// FoldLocalCase is set).
+//
+// Changing the rules changes stored canonical forms; run the canonicalize-users command
+// afterwards.
+func (r *EmailRules) Canonical(email string) string {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		if r.FoldLocalCase {
+			return strings.ToLower(email)
+		}
+		return email
+	}
+	local, domain := email[:at], strings.ToLower(email[at+1:])
+	if r.FoldLocalCase {
+		local = strings.ToLower(local)
+	}
+
+	if p, ok := r.providers[domain]; ok {
+		domain = p.Domains[0]
+		if p.StripPlusTag {
+			local, _, _ = strings.Cut(local, "+")
+		}
+		if p.IgnoreDots {
+			local = strings.ReplaceAll(local, ".", "")
+		}
+	}
+	return local + "@" + domain
+}
+
+// CanonicalUsername returns the form of a username used for uniqueness: NFKC folds
+// compatibility variants (full-width letters, ligatures) into their plain forms and full
+// case folding makes the comparison case-insensitive. Normalizing again afterwards keeps
+// the result stable, since folding can produce denormalized sequences.
+func CanonicalUsername(username string) string {
+	s := norm.NFKC.String(strings.TrimSpace(username))
+	return norm.NFKC.String(cases.Fold().String(s))
+}
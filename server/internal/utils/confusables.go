@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// confusables maps characters to the Latin prototype they are easily mistaken for. It is the
+// subset of the Unicode confusables data (UTS #39) that matters for case-folded usernames:
+// Cyrillic, Greek and Armenian lookalikes of Latin letters, and digits that pass for letters.
+// Full-width and other compatibility forms are already folded by NFKC in CanonicalUsername.
+//
+// Every entry maps one rune to one rune except m, so the backfill in migration 000011 can
+// mirror the table with translate(); keep the two in step.
+var confusables = map[rune]string{
+	// Cyrillic
+	'а': "a", 'е': "e", 'ѕ': "s", 'і': "i", 'ј': "j", 'о': "o", 'р': "p",
+	'с': "c", 'у': "y", 'х': "x", 'һ': "h", 'ԁ': "d", 'ԛ': "q", 'ԝ': "w", 'ӏ': "l",
+	// Greek
+	'α': "a", 'ο': "o", 'ν': "v", 'ρ': "p", 'ι': "i", 'υ': "u", 'γ': "y",
+	// Armenian
+	'ո': "n", 'ս': "u", 'օ': "o",
+	// Latin
+	'ı': "i", 'ɡ': "g", '0': "o", '1': "l", '|': "l",
+	// "rn" reads as "m"; the prototype is the two-letter form
+	'm': "rn",
+}
+
+// UsernameSkeleton returns the UTS #39 skeleton of a username's canonical form: two usernames
+// with the same skeleton look alike, e.g. "pаypal" with a Cyrillic "а" and "paypal".
+func UsernameSkeleton(username string) string {
+	s := norm.NFD.String(CanonicalUsername(username))
+	var b strings.Builder
+	for _, r := range s {
+		if proto, ok := confusables[r]; ok {
+			b.WriteString(proto)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return norm.NFD.String(b.String())
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// ErrInvalidPhone is returned by ParsePhone for numbers that don't exist in their region's
+// numbering plan
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// Phone number types stored alongside a number
+const (
+	PhoneTypeMobile            = "mobile"
+	PhoneTypeFixedLine         = "fixed_line"
+	PhoneTypeFixedLineOrMobile = "fixed_line_or_mobile" // Regions such as the US don't tell them apart
+	PhoneTypeTollFree          = "toll_free"
+	PhoneTypeVoIP              = "voip"
+	PhoneTypeOther             = "other"
+	PhoneTypeUnknown           = "unknown"
+)
+
+// Phone is a parsed international phone number
+type Phone struct {
+	E164   string // e.g. +442079460958
+	Region string // ISO 3166-1 alpha-2, e.g. GB
+	Type   string // One of the PhoneType constants
+}
+
+// ParsePhone parses a number in international format; a missing leading "+" is assumed.
+// Spacing and punctuation don't matter, so "+44 20 7946 0958" and "+442079460958" parse to
+// the same E.164 form.
+func ParsePhone(phone string) (Phone, error) {
+	phone = strings.TrimSpace(phone)
+	if !strings.HasPrefix(phone, "+") {
+		phone = "+" + phone
+	}
+
+	// No default region: the country calling code decides
+	num, err := phonenumbers.Parse(phone, "")
+	if err != nil || !phonenumbers.IsValidNumber(num) {
+		return Phone{}, ErrInvalidPhone
+	}
+
+	return Phone{
+		E164:   phonenumbers.Format(num, phonenumbers.E164),
+		Region: phonenumbers.GetRegionCodeForNumber(num),
+		Type:   phoneType(phonenumbers.GetNumberType(num)),
+	}, nil
+}
+
+func phoneType(t phonenumbers.PhoneNumberType) string {
+	switch t {
+	case phonenumbers.MOBILE:
+		return PhoneTypeMobile
+	case phonenumbers.FIXED_LINE:
+		return PhoneTypeFixedLine
+	case phonenumbers.FIXED_LINE_OR_MOBILE:
+		return PhoneTypeFixedLineOrMobile
+	case phonenumbers.TOLL_FREE:
+		return PhoneTypeTollFree
+	case phonenumbers.VOIP:
+		return PhoneTypeVoIP
+	case phonenumbers.UNKNOWN:
+		return PhoneTypeUnknown
+	default:
+		return PhoneTypeOther
+	}
+}
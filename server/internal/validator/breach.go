@@ -0,0 +1,308 @@
+package validator
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BreachedPasswordMessage is reported for passwords found in the breach corpus
+const BreachedPasswordMessage = "This password has appeared in a data breach, please choose another"
+
+// BreachChecker reports whether a password appears in a corpus of breached passwords
+type BreachChecker interface {
+	Breached(password string) (bool, error)
+}
+
+// LoadBreachChecker opens the breached-password corpus at path: a directory of HIBP range
+// files or a bloom filter written by BloomFilter.WriteTo. An empty path disables the check
+// and returns nil.
+func LoadBreachChecker(path string) (BreachChecker, error) {
+	if path == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password corpus: %w", err)
+	}
+	if info.IsDir() {
+		return NewRangeDirectory(path), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password filter: %w", err)
+	}
+	defer f.Close()
+	return ReadBloomFilter(bufio.NewReader(f))
+}
+
+// BreachHash is the SHA-1 digest the HIBP corpus is keyed by
+type BreachHash [sha1.Size]byte
+
+func HashBreachPassword(password string) BreachHash {
+	return sha1.Sum([]byte(password))
+}
+
+// RangeDirectory looks passwords up in HIBP range files: one file per 5 hex digit SHA-1
+// prefix, named by the prefix (optionally with a .txt extension), holding "SUFFIX:COUNT"
+// lines. Only the prefix of a password's hash selects the file, which is what the HIBP
+// range API serves, so a directory downloaded from it can be used as is.
+type RangeDirectory struct {
+	dir string
+}
+
+func NewRangeDirectory(dir string) *RangeDirectory {
+	return &RangeDirectory{dir: dir}
+}
+
+func (d *RangeDirectory) Breached(password string) (bool, error) {
+	digest := HashBreachPassword(password)
+	hash := strings.ToUpper(hex.EncodeToString(digest[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	f, err := d.open(prefix)
+	if errors.Is(err, os.ErrNotExist) {
+		// A partial download simply doesn't cover this prefix
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read breach range %s: %w", prefix, err)
+	}
+	defer f.Close()
+
+	found := false
+	err = scanRange(f, func(lineSuffix string, count int) error {
+		if count > 0 && strings.EqualFold(lineSuffix, suffix) {
+			found = true
+			return errStopScan
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopScan) {
+		return false, fmt.Errorf("failed to read breach range %s: %w", prefix, err)
+	}
+	return found, nil
+}
+
+func (d *RangeDirectory) open(prefix string) (*os.File, error) {
+	f, err := os.Open(filepath.Join(d.dir, prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		return os.Open(filepath.Join(d.dir, prefix+".txt"))
+	}
+	return f, err
+}
+
+var errStopScan = errors.New("stop scan")
+
+// scanRange calls fn for every "HASH:COUNT" line of r. Lines without a count (plain hash
+// lists) count as 1; HIBP pads range responses with count 0 entries.
+func scanRange(r io.Reader, fn func(hash string, count int) error) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		hash, countText, hasCount := strings.Cut(line, ":")
+		count := 1
+		if hasCount {
+			n, err := strconv.Atoi(strings.TrimSpace(countText))
+			if err != nil {
+				return fmt.Errorf("invalid count in line %q", line)
+			}
+			count = n
+		}
+		if err := fn(hash, count); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// ForEachBreachHash calls fn with every hash seen at least minCount times in an HIBP
+// download at path: either a single file of full "HASH:COUNT" lines, or a range directory
+// (see RangeDirectory)
+func ForEachBreachHash(path string, minCount int, fn func(BreachHash) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return forEachHashInFile(path, "", minCount, fn)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		prefix := strings.TrimSuffix(entry.Name(), ".txt")
+		if entry.IsDir() || len(prefix) != 5 || !isHex(prefix) {
+			continue
+		}
+		if err := forEachHashInFile(filepath.Join(path, entry.Name()), prefix, minCount, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func forEachHashInFile(path, prefix string, minCount int, fn func(BreachHash) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = scanRange(f, func(hash string, count int) error {
+		if count < minCount || count == 0 {
+			return nil
+		}
+		var digest BreachHash
+		full := prefix + hash
+		if len(full) != hex.EncodedLen(len(digest)) {
+			return fmt.Errorf("invalid SHA-1 hash %q", full)
+		}
+		if _, err := hex.Decode(digest[:], []byte(full)); err != nil {
+			return fmt.Errorf("invalid SHA-1 hash %q", full)
+		}
+		return fn(digest)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomMagic starts a serialized BloomFilter
+const bloomMagic = "PWBLOOM1"
+
+// BloomFilter is a compact, probabilistic breached-password set: it never misses a password
+// that was added, and wrongly reports a password as breached at roughly the false positive
+// rate it was sized for. Its k bit positions are derived from the SHA-1 digest, which is
+// already uniformly distributed, by double hashing.
+type BloomFilter struct {
+	bits []uint64
+	m    uint64 // number of bits
+	k    uint32 // bits set per entry
+}
+
+// NewBloomFilter sizes a filter for n entries at false positive rate p
+func NewBloomFilter(n uint64, p float64) (*BloomFilter, error) {
+	if n == 0 {
+		return nil, errors.New("bloom filter needs at least one entry")
+	}
+	if p <= 0 || p >= 1 {
+		return nil, fmt.Errorf("false positive rate %v must be between 0 and 1", p)
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return newBloomFilter(m, k), nil
+}
+
+func newBloomFilter(m uint64, k uint32) *BloomFilter {
+	return &BloomFilter{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+func (f *BloomFilter) Add(hash BreachHash) {
+	h1, h2 := bloomHashes(hash)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (f *BloomFilter) Contains(hash BreachHash) bool {
+	h1, h2 := bloomHashes(hash)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *BloomFilter) Breached(password string) (bool, error) {
+	return f.Contains(HashBreachPassword(password)), nil
+}
+
+func bloomHashes(hash BreachHash) (uint64, uint64) {
+	// Forcing the step odd keeps it from being 0, which would set the same bit k times
+	return binary.BigEndian.Uint64(hash[0:8]), binary.BigEndian.Uint64(hash[8:16]) | 1
+}
+
+// WriteTo serializes the filter: magic, m and k (big-endian), then the bit words
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, len(bloomMagic)+12)
+	copy(header, bloomMagic)
+	binary.BigEndian.PutUint64(header[len(bloomMagic):], f.m)
+	binary.BigEndian.PutUint32(header[len(bloomMagic)+8:], f.k)
+	n, err := w.Write(header)
+	total := int64(n)
+	if err != nil {
+		return total, err
+	}
+
+	buf := make([]byte, 8*1024)
+	for i := 0; i < len(f.bits); {
+		chunk := buf[:0]
+		for ; i < len(f.bits) && len(chunk) < len(buf); i++ {
+			chunk = binary.BigEndian.AppendUint64(chunk, f.bits[i])
+		}
+		n, err := w.Write(chunk)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ReadBloomFilter reads a filter written by WriteTo
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	header := make([]byte, len(bloomMagic)+12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("invalid bloom filter: %w", err)
+	}
+	if string(header[:len(bloomMagic)]) != bloomMagic {
+		return nil, errors.New("invalid bloom filter: bad magic")
+	}
+	m := binary.BigEndian.Uint64(header[len(bloomMagic):])
+	k := binary.BigEndian.Uint32(header[len(bloomMagic)+8:])
+	if m == 0 || k == 0 {
+		return nil, errors.New("invalid bloom filter: empty")
+	}
+
+	f := newBloomFilter(m, k)
+	buf := make([]byte, 8*1024)
+	for i := 0; i < len(f.bits); {
+		chunk := buf[:min(len(buf), 8*(len(f.bits)-i))]
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return nil, fmt.Errorf("invalid bloom filter: %w", err)
+		}
+		for ; len(chunk) > 0; chunk, i = chunk[8:], i+1 {
+			f.bits[i] = binary.BigEndian.Uint64(chunk)
+		}
+	}
+	return f, nil
+}
@@ -3,9 +3,8 @@ package validator
 import (
 	"regexp"
 	"strings"
-	"unicode"
 
-	"github.com/nyaruka/phonenumbers"
+	"tyk-registration-server/internal/utils"
 )
 
 var emailRegex = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
@@ -14,44 +13,17 @@ func ValidateEmail(email string) bool {
 	return emailRegex.MatchString(strings.TrimSpace(email))
 }
 
+// ValidatePhone accepts an empty string (phone is optional) or a valid international number
 func ValidatePhone(phone string) bool {
-	phone = strings.TrimSpace(phone)
-	if phone == "" {
+	if strings.TrimSpace(phone) == "" {
 		return true
 	}
-
-	if !strings.HasPrefix(phone, "+") {
-		phone = "+" + phone
-	}
-
-	// Parse the phone number - library will auto-detect country from the country code
-	num, err := phonenumbers.Parse(phone, "")
-	if err != nil {
-		return false
-	}
-
-	// Validate the phone number against its detected country's format
-	return phonenumbers.IsValidNumber(num)
+	_, err := utils.ParsePhone(phone)
+	return err == nil
 }
 
 func ValidatePassword(password string) bool {
-	if len(password) < 8 {
-		return false
-	}
-	var hasUpper, hasLower, hasDigit, hasSpecial bool
-	for _, r := range password {
-		switch {
-		case unicode.IsUpper(r):
-			hasUpper = true
-		case unicode.IsLower(r):
-			hasLower = true
-		case unicode.IsDigit(r):
-			hasDigit = true
-		default:
-			hasSpecial = true
-		}
-	}
-	return hasUpper && hasLower && hasDigit && hasSpecial
+	return DefaultPasswordPolicy.Allows(password)
 }
 
 // isoToTldExceptions maps ISO country codes to TLDs where ISO code does NOT equal TLD
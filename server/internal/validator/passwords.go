@@ -0,0 +1,30 @@
+package validator
+
+import "fmt"
+
+// PasswordChecker holds the checks a password must pass beyond the field rules
+type PasswordChecker struct {
+	// MinScore is the lowest EstimateStrength score accepted, from 0 (anything) to 4
+	MinScore int
+	// Breaches rejects passwords found in a breach corpus; optional
+	Breaches BreachChecker
+}
+
+// Check returns the message for a rejected password, or "" if it passes. userInputs (the
+// user's names, username and email) make passwords built from them score lower.
+func (p *PasswordChecker) Check(password string, userInputs ...string) (string, error) {
+	if p.Breaches != nil {
+		breached, err := p.Breaches.Breached(password)
+		if err != nil {
+			return "", fmt.Errorf("failed to check breached passwords: %w", err)
+		}
+		if breached {
+			return BreachedPasswordMessage, nil
+		}
+	}
+
+	if strength := EstimateStrength(password, userInputs...); strength.Score < p.MinScore {
+		return strength.Message(), nil
+	}
+	return "", nil
+}
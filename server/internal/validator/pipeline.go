@@ -0,0 +1,214 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"tyk-registration-server/internal/metrics"
+	"tyk-registration-server/internal/models"
+)
+
+// Stage names of the built-in validators
+const (
+	StageField    = "field"
+	StageCross    = "cross"
+	StageBusiness = "business"
+)
+
+// Mode decides whether the pipeline stops at the first failing stage
+type Mode string
+
+const (
+	ModeShortCircuit Mode = "short_circuit"
+	ModeCollect      Mode = "collect"
+)
+
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case ModeShortCircuit, ModeCollect:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown validation mode %q (want %s or %s)", s, ModeShortCircuit, ModeCollect)
+	}
+}
+
+// Failure is a rejected request. Business failures concern existing data (a taken
+// username) rather than the shape of the input.
+type Failure struct {
+	Message  string
+	Fields   map[string]string
+	Business bool
+}
+
+// Validator is one named stage of the registration pipeline. A nil Failure means the
+// request passed; a non-nil error means the stage could not decide. Only rules that concern
+// fields in scope are checked.
+type Validator interface {
+	Name() string
+	Validate(ctx context.Context, req *models.RegistrationRequest, scope Scope) (*Failure, error)
+}
+
+// Registry holds the validators a pipeline can be built from
+type Registry struct {
+	validators map[string]Validator
+}
+
+func NewRegistry(validators ...Validator) *Registry {
+	r := &Registry{validators: map[string]Validator{}}
+	for _, v := range validators {
+		r.validators[v.Name()] = v
+	}
+	return r
+}
+
+type PipelineConfig struct {
+	// Stages lists validator names in run order; validators not listed are disabled
+	Stages []string
+	Mode   Mode
+	// Metrics receives per-stage counters and latencies; optional
+	Metrics *metrics.Registry
+}
+
+// Pipeline runs a configured sequence of validators
+type Pipeline struct {
+	stages []Validator
+	mode   Mode
+
+	outcomes *metrics.Counter
+	duration *metrics.Histogram
+}
+
+// Pipeline builds a pipeline from the registered validators
+func (r *Registry) Pipeline(cfg PipelineConfig) (*Pipeline, error) {
+	if cfg.Mode == "" {
+		cfg.Mode = ModeShortCircuit
+	}
+	if _, err := ParseMode(string(cfg.Mode)); err != nil {
+		return nil, err
+	}
+
+	p := &Pipeline{mode: cfg.Mode}
+	seen := map[string]bool{}
+	for _, name := range cfg.Stages {
+		v, ok := r.validators[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown validation stage %q (available: %s)", name, strings.Join(r.names(), ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("validation stage %q listed twice", name)
+		}
+		seen[name] = true
+		p.stages = append(p.stages, v)
+	}
+
+	if cfg.Metrics != nil {
+		p.outcomes = cfg.Metrics.Counter("registration_validation_stage_total",
+			"Registration validation stage runs by outcome (pass, fail, error).", "stage", "outcome")
+		p.duration = cfg.Metrics.Histogram("registration_validation_stage_duration_seconds",
+			"Time spent in each registration validation stage.", metrics.DefaultBuckets, "stage")
+	}
+	return p, nil
+}
+
+func (r *Registry) names() []string {
+	names := make([]string, 0, len(r.validators))
+	for name := range r.validators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stages returns the names of the enabled stages in run order
+func (p *Pipeline) Stages() []string {
+	names := make([]string, len(p.stages))
+	for i, v := range p.stages {
+		names[i] = v.Name()
+	}
+	return names
+}
+
+// Run validates the whole of req. In short-circuit mode it returns the first failure; in
+// collect mode it runs every stage and merges their failures, keeping the earlier stage's
+// message when two stages flag the same field. A stage error always stops the run.
+func (p *Pipeline) Run(ctx context.Context, req *models.RegistrationRequest) (*Failure, error) {
+	return p.RunScoped(ctx, req, AllFields)
+}
+
+// RunScoped is Run restricted to the rules that concern fields in scope
+func (p *Pipeline) RunScoped(ctx context.Context, req *models.RegistrationRequest, scope Scope) (*Failure, error) {
+	var failures []*Failure
+	for _, v := range p.stages {
+		failure, err := p.runStage(ctx, v, req, scope)
+		if err != nil {
+			return nil, fmt.Errorf("validation stage %s: %w", v.Name(), err)
+		}
+		if failure == nil {
+			continue
+		}
+		if p.mode == ModeShortCircuit {
+			return failure, nil
+		}
+		failures = append(failures, failure)
+	}
+	return merge(failures), nil
+}
+
+func (p *Pipeline) runStage(ctx context.Context, v Validator, req *models.RegistrationRequest, scope Scope) (*Failure, error) {
+	start := time.Now()
+	failure, err := v.Validate(ctx, req, scope)
+	elapsed := time.Since(start)
+
+	outcome := "pass"
+	switch {
+	case err != nil:
+		outcome = "error"
+		log.Printf("validation stage %s: error after %s: %v", v.Name(), elapsed, err)
+	case failure != nil:
+		outcome = "fail"
+		log.Printf("validation stage %s: rejected fields [%s] in %s", v.Name(), strings.Join(fieldNames(failure.Fields), ", "), elapsed)
+	}
+
+	if p.outcomes != nil {
+		p.outcomes.Inc(v.Name(), outcome)
+		p.duration.Observe(elapsed.Seconds(), v.Name())
+	}
+	return failure, err
+}
+
+func merge(failures []*Failure) *Failure {
+	switch len(failures) {
+	case 0:
+		return nil
+	case 1:
+		return failures[0]
+	}
+
+	merged := &Failure{
+		Message:  "There are validation errors",
+		Fields:   map[string]string{},
+		Business: true,
+	}
+	for _, f := range failures {
+		for field, msg := range f.Fields {
+			if _, ok := merged.Fields[field]; !ok {
+				merged.Fields[field] = msg
+			}
+		}
+		merged.Business = merged.Business && f.Business
+	}
+	return merged
+}
+
+func fieldNames(fields map[string]string) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
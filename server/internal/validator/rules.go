@@ -0,0 +1,348 @@
+package validator
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed rules/registration.yaml
+var defaultRegistrationRules []byte
+
+// Check names, usable as keys of FieldRule.Messages
+const (
+	CheckRequired       = "required"
+	CheckMinLength      = "min_length"
+	CheckMaxLength      = "max_length"
+	CheckPattern        = "pattern"
+	CheckEnum           = "enum"
+	CheckFormat         = "format"
+	CheckPasswordPolicy = "password_policy"
+)
+
+// Values for FieldRule.Format
+const (
+	FormatEmail = "email"
+	FormatPhone = "phone"
+)
+
+type PasswordPolicy struct {
+	MinLength int  `yaml:"min_length"`
+	Upper     bool `yaml:"upper"`
+	Lower     bool `yaml:"lower"`
+	Digit     bool `yaml:"digit"`
+	Special   bool `yaml:"special"` // Anything that isn't a letter or digit
+}
+
+// DefaultPasswordPolicy is the policy ValidatePassword enforces. Character classes aren't
+// required: how guessable a password is is up to PasswordChecker.
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 8}
+
+func (p PasswordPolicy) Allows(password string) bool {
+	if len(password) < p.MinLength {
+		return false
+	}
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSpecial = true
+		}
+	}
+	return (hasUpper || !p.Upper) && (hasLower || !p.Lower) && (hasDigit || !p.Digit) && (hasSpecial || !p.Special)
+}
+
+// FieldRule lists the checks for one field. Checks run in a fixed order, required,
+// min_length, max_length, pattern, enum, format, password_policy, whatever their order in
+// the rules document, and the first failure is the field's error.
+type FieldRule struct {
+	// Required rejects blank strings, nil pointers and false booleans
+	Required       bool            `yaml:"required"`
+	MinLength      *int            `yaml:"min_length"`
+	MaxLength      *int            `yaml:"max_length"`
+	Pattern        string          `yaml:"pattern"`
+	Enum           []string        `yaml:"enum"`
+	Format         string          `yaml:"format"`
+	PasswordPolicy *PasswordPolicy `yaml:"password_policy"`
+
+	// Message is reported for any failed check without its own entry in Messages
+	Message  string            `yaml:"message"`
+	Messages map[string]string `yaml:"messages"`
+
+	pattern *regexp.Regexp
+	index   []int
+	kind    reflect.Kind
+}
+
+// RuleSet is a parsed rules file bound to the struct type it validates
+type RuleSet struct {
+	Fields map[string]*FieldRule `yaml:"fields"`
+}
+
+// ParseRules reads a YAML or JSON rules document and binds it to the struct type of target,
+// whose fields are matched by their json tag. Unknown keys, unknown fields and checks that
+// don't apply to a field's type are errors.
+func ParseRules(data []byte, target any) (*RuleSet, error) {
+	// JSON is valid YAML, so one decoder covers both formats
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	var rs RuleSet
+	if err := dec.Decode(&rs); err != nil {
+		return nil, fmt.Errorf("invalid rules document: %w", err)
+	}
+	if len(rs.Fields) == 0 {
+		return nil, errors.New("rules document defines no fields")
+	}
+
+	fields := jsonFields(reflect.TypeOf(target))
+	for name, rule := range rs.Fields {
+		if rule == nil {
+			return nil, fmt.Errorf("field %q: empty rule", name)
+		}
+		field, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("field %q: no such field", name)
+		}
+		if err := rule.bind(field); err != nil {
+			return nil, fmt.Errorf("field %q: %w", name, err)
+		}
+	}
+	return &rs, nil
+}
+
+func (r *FieldRule) bind(field reflect.StructField) error {
+	t := field.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+	case reflect.Bool:
+		if r.MinLength != nil || r.MaxLength != nil || r.Pattern != "" || r.Enum != nil || r.Format != "" || r.PasswordPolicy != nil {
+			return errors.New("boolean fields only support required")
+		}
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type)
+	}
+
+	if r.MinLength != nil && r.MaxLength != nil && *r.MinLength > *r.MaxLength {
+		return errors.New("min_length is greater than max_length")
+	}
+	if r.Pattern != "" {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		r.pattern = re
+	}
+	switch r.Format {
+	case "", FormatEmail, FormatPhone:
+	default:
+		return fmt.Errorf("unknown format %q", r.Format)
+	}
+	for check := range r.Messages {
+		switch check {
+		case CheckRequired, CheckMinLength, CheckMaxLength, CheckPattern, CheckEnum, CheckFormat, CheckPasswordPolicy:
+		default:
+			return fmt.Errorf("message for unknown check %q", check)
+		}
+	}
+
+	r.index = field.Index
+	r.kind = t.Kind()
+	return nil
+}
+
+// Validate checks v, a value or pointer of the bound struct type, and returns a message per
+// failing field
+func (rs *RuleSet) Validate(v any) map[string]string {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	fields := map[string]string{}
+	for name, rule := range rs.Fields {
+		if check := rule.failedCheck(val.FieldByIndex(rule.index)); check != "" {
+			fields[name] = rule.message(check)
+		}
+	}
+	return fields
+}
+
+// ValidateField checks a single field of v and returns its message, or "" if the field passes
+// or has no rule
+func (rs *RuleSet) ValidateField(v any, name string) string {
+	rule, ok := rs.Fields[name]
+	if !ok {
+		return ""
+	}
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if check := rule.failedCheck(val.FieldByIndex(rule.index)); check != "" {
+		return rule.message(check)
+	}
+	return ""
+}
+
+// failedCheck returns the name of the first failing check, or "" if the value passes
+func (r *FieldRule) failedCheck(v reflect.Value) string {
+	present := true
+	if v.Kind() == reflect.Pointer {
+		present = !v.IsNil()
+		v = reflect.Indirect(v)
+	}
+
+	if r.kind == reflect.Bool {
+		if r.Required && (!present || !v.Bool()) {
+			return CheckRequired
+		}
+		return ""
+	}
+
+	var s string
+	if present {
+		s = v.String()
+	}
+	if strings.TrimSpace(s) == "" {
+		if r.Required {
+			return CheckRequired
+		}
+		// Optional and absent: nothing else to check
+		return ""
+	}
+
+	n := utf8.RuneCountInString(s)
+	switch {
+	case r.MinLength != nil && n < *r.MinLength:
+		return CheckMinLength
+	case r.MaxLength != nil && n > *r.MaxLength:
+		return CheckMaxLength
+	case r.pattern != nil && !r.pattern.MatchString(s):
+		return CheckPattern
+	case r.Enum != nil && !slices.Contains(r.Enum, s):
+		return CheckEnum
+	case r.Format == FormatEmail && !ValidateEmail(s):
+		return CheckFormat
+	case r.Format == FormatPhone && !ValidatePhone(s):
+		return CheckFormat
+	case r.PasswordPolicy != nil && !r.PasswordPolicy.Allows(s):
+		return CheckPasswordPolicy
+	}
+	return ""
+}
+
+func (r *FieldRule) message(check string) string {
+	if msg := r.Messages[check]; msg != "" {
+		return msg
+	}
+	if r.Message != "" {
+		return r.Message
+	}
+
+	switch check {
+	case CheckRequired:
+		return "This field is required"
+	case CheckMinLength:
+		return fmt.Sprintf("Must be at least %d characters", *r.MinLength)
+	case CheckMaxLength:
+		return fmt.Sprintf("Must be at most %d characters", *r.MaxLength)
+	case CheckEnum:
+		return "Must be one of: " + strings.Join(r.Enum, ", ")
+	case CheckPasswordPolicy:
+		return "Password does not meet the password policy"
+	default:
+		return "Invalid value"
+	}
+}
+
+// RuleEngine holds the active RuleSet and swaps it atomically on Reload, so requests in
+// flight keep the rules they started with
+type RuleEngine struct {
+	path   string
+	target any
+	rules  atomic.Pointer[RuleSet]
+}
+
+// NewRuleEngine loads rules for target's struct type from path, or the embedded
+// registration rules if path is empty
+func NewRuleEngine(path string, target any) (*RuleEngine, error) {
+	e := &RuleEngine{path: path, target: target}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Reload re-reads the rules file. On error the current rules stay active.
+func (e *RuleEngine) Reload() error {
+	data := defaultRegistrationRules
+	if e.path != "" {
+		var err error
+		data, err = os.ReadFile(e.path)
+		if err != nil {
+			return fmt.Errorf("failed to read validation rules: %w", err)
+		}
+	}
+
+	rs, err := ParseRules(data, e.target)
+	if err != nil {
+		return err
+	}
+	e.rules.Store(rs)
+	return nil
+}
+
+// Source describes where the rules were loaded from
+func (e *RuleEngine) Source() string {
+	if e.path == "" {
+		return "embedded defaults"
+	}
+	return e.path
+}
+
+func (e *RuleEngine) Validate(v any) map[string]string {
+	return e.rules.Load().Validate(v)
+}
+
+func (e *RuleEngine) ValidateField(v any, name string) string {
+	return e.rules.Load().ValidateField(v, name)
+}
+
+// jsonFields indexes the exported fields of a struct type by their json name
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	fields := map[string]reflect.StructField{}
+	if t.Kind() != reflect.Struct {
+		return fields
+	}
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f
+	}
+	return fields
+}
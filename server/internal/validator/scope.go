@@ -0,0 +1,76 @@
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Scope is the set of JSON field names a validation run is about. The nil Scope,
+// AllFields, covers every field.
+type Scope map[string]bool
+
+var AllFields Scope
+
+func NewScope(fields ...string) Scope {
+	s := Scope{}
+	for _, f := range fields {
+		s[f] = true
+	}
+	return s
+}
+
+func (s Scope) Includes(field string) bool {
+	return s == nil || s[field]
+}
+
+// IncludesAny reports whether any of fields is in scope
+func (s Scope) IncludesAny(fields ...string) bool {
+	for _, f := range fields {
+		if s.Includes(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter drops the entries of fields that are out of scope
+func (s Scope) Filter(fields map[string]string) map[string]string {
+	if s == nil {
+		return fields
+	}
+	kept := map[string]string{}
+	for field, msg := range fields {
+		if s[field] {
+			kept[field] = msg
+		}
+	}
+	return kept
+}
+
+// Registration steps, matching the client's three-step form
+const (
+	StepPersonal = "personal"
+	StepAddress  = "address"
+	StepAccount  = "account"
+)
+
+var steps = map[string]Scope{
+	StepPersonal: NewScope("first_name", "last_name", "email", "phone"),
+	StepAddress:  NewScope("street", "city", "state", "country", "country_iso"),
+	StepAccount:  NewScope("username", "password", "confirm_password", "terms_accepted", "newsletter"),
+}
+
+// StepScope returns the fields collected by a registration step
+func StepScope(step string) (Scope, error) {
+	scope, ok := steps[step]
+	if !ok {
+		names := make([]string, 0, len(steps))
+		for name := range steps {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown step %q (want one of: %s)", step, strings.Join(names, ", "))
+	}
+	return scope, nil
+}
@@ -0,0 +1,150 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"tyk-registration-server/internal/models"
+)
+
+// NewFieldValidator checks each field on its own against the declarative rules, a username
+// that passes them against the username policy, and a password that passes them against the
+// password checker. policy and passwords may be nil.
+func NewFieldValidator(rules *RuleEngine, policy *UsernamePolicy, passwords *PasswordChecker) Validator {
+	return &fieldValidator{rules: rules, policy: policy, passwords: passwords}
+}
+
+type fieldValidator struct {
+	rules     *RuleEngine
+	policy    *UsernamePolicy
+	passwords *PasswordChecker
+}
+
+func (v *fieldValidator) Name() string { return StageField }
+
+func (v *fieldValidator) Validate(ctx context.Context, req *models.RegistrationRequest, scope Scope) (*Failure, error) {
+	fields := scope.Filter(v.rules.Validate(req))
+
+	if v.policy != nil && scope.Includes("username") && fields["username"] == "" && strings.TrimSpace(req.Username) != "" {
+		msg, err := v.policy.Check(ctx, req.Username)
+		if err != nil {
+			return nil, err
+		}
+		if msg != "" {
+			fields["username"] = msg
+		}
+	}
+
+	if v.passwords != nil && scope.Includes("password") && fields["password"] == "" && req.Password != "" {
+		msg, err := v.passwords.Check(req.Password, req.FirstName, req.LastName, req.Username, req.Email)
+		if err != nil {
+			return nil, err
+		}
+		if msg != "" {
+			fields["password"] = msg
+		}
+	}
+
+	if len(fields) > 0 {
+		return &Failure{Message: "There are validation errors", Fields: fields}, nil
+	}
+	return nil, nil
+}
+
+// NewCrossFieldValidator checks rules that span several fields
+func NewCrossFieldValidator() Validator {
+	return crossFieldValidator{}
+}
+
+type crossFieldValidator struct{}
+
+func (crossFieldValidator) Name() string { return StageCross }
+
+// A cross-field rule runs for a partial scope only when it touches a field in scope and the
+// request already carries every field it compares; otherwise a rule spanning two steps would
+// fail on the step that comes first.
+func (crossFieldValidator) Validate(ctx context.Context, req *models.RegistrationRequest, scope Scope) (*Failure, error) {
+	fields := map[string]string{}
+	applies := func(values map[string]string) bool {
+		if scope == nil {
+			return true
+		}
+		names := make([]string, 0, len(values))
+		for name, value := range values {
+			if value == "" {
+				return false
+			}
+			names = append(names, name)
+		}
+		return scope.IncludesAny(names...)
+	}
+
+	if applies(map[string]string{"password": req.Password, "confirm_password": req.ConfirmPassword}) &&
+		req.Password != req.ConfirmPassword {
+		fields["confirm_password"] = "Passwords must match"
+	}
+
+	if applies(map[string]string{"email": req.Email, "country_iso": req.CountryISO}) &&
+		!CountryEmailDomainValid(req.CountryISO, req.Email) {
+		fields["email"] = "Email domain must match the selected country's domain"
+	}
+
+	if len(fields) > 0 {
+		return &Failure{Message: "Cross-field validation failed", Fields: fields}, nil
+	}
+	return nil, nil
+}
+
+// UniquenessChecker looks up existing accounts; repositories.UserRepository implements it
+type UniquenessChecker interface {
+	EmailExists(ctx context.Context, email string) (bool, error)
+	UsernameExists(ctx context.Context, username string) (bool, error)
+	PhoneExists(ctx context.Context, phone string) (bool, error)
+}
+
+// NewBusinessValidator rejects emails, usernames and phone numbers that are already registered.
+// For a partial scope only the fields in it are looked up.
+func NewBusinessValidator(users UniquenessChecker) Validator {
+	return &businessValidator{users: users}
+}
+
+type businessValidator struct {
+	users UniquenessChecker
+}
+
+func (v *businessValidator) Name() string { return StageBusiness }
+
+func (v *businessValidator) Validate(ctx context.Context, req *models.RegistrationRequest, scope Scope) (*Failure, error) {
+	fields := map[string]string{}
+
+	if scope.Includes("email") {
+		if exists, err := v.users.EmailExists(ctx, req.Email); err != nil {
+			return nil, fmt.Errorf("failed to validate email uniqueness: %w", err)
+		} else if exists {
+			fields["email"] = "Email is already registered"
+		}
+	}
+
+	if scope.Includes("username") {
+		if exists, err := v.users.UsernameExists(ctx, req.Username); err != nil {
+			return nil, fmt.Errorf("failed to validate username uniqueness: %w", err)
+		} else if exists {
+			fields["username"] = "Username is already taken"
+		}
+	}
+
+	// Check phone availability only if phone is provided (it's optional)
+	if scope.Includes("phone") && req.Phone != nil && *req.Phone != "" {
+		if exists, err := v.users.PhoneExists(ctx, *req.Phone); err != nil {
+			return nil, fmt.Errorf("failed to validate phone uniqueness: %w", err)
+		} else if exists {
+			fields["phone"] = "Phone number is already registered"
+		}
+	}
+
+	if len(fields) > 0 {
+		return &Failure{Message: "Business validation failed", Fields: fields, Business: true}, nil
+	}
+	return nil, nil
+}
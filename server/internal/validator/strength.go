@@ -0,0 +1,927 @@
+package validator
+
+import (
+	_ "embed"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The estimator follows zxcvbn (Wheeler, "zxcvbn: Low-Budget Password Strength Estimation",
+// USENIX Security 2016): find every dictionary word, keyboard pattern, sequence, repeat and
+// date in the password, estimate the guesses each needs, and score the cheapest way to cover
+// the whole password with them and brute force.
+
+//go:embed rules/passwords.txt
+var commonPasswords []byte
+
+//go:embed rules/english.txt
+var englishWords []byte
+
+//go:embed rules/names.txt
+var commonNames []byte
+
+// Dictionary names
+const (
+	dictPasswords  = "passwords"
+	dictEnglish    = "english"
+	dictNames      = "names"
+	dictUserInputs = "user_inputs"
+)
+
+// Match patterns
+const (
+	patternDictionary = "dictionary"
+	patternSpatial    = "spatial"
+	patternRepeat     = "repeat"
+	patternSequence   = "sequence"
+	patternDate       = "date"
+	patternYear       = "year"
+	patternBruteforce = "bruteforce"
+)
+
+const (
+	// maxStrengthRunes bounds the work per password; longer passwords are scored on their
+	// first maxStrengthRunes runes, which is more than enough for the top score
+	maxStrengthRunes = 100
+
+	bruteforceCardinality   = 10
+	minSubmatchGuessesChar  = 10
+	minSubmatchGuessesMulti = 50
+	// minGuessesBeforeGrowing penalizes covering the password with more, smaller matches
+	minGuessesBeforeGrowing = 10000
+	minYearSpace            = 20
+)
+
+// Strength is an estimate of how hard a password is to guess
+type Strength struct {
+	// Score runs from 0 (too guessable) to 4 (very unguessable)
+	Score int
+	// Guesses is the estimated number of guesses an attacker needs
+	Guesses float64
+	// Warning says what makes the password weak; empty when there's nothing specific
+	Warning string
+	// Suggestions are ways to make the password stronger; empty for strong passwords
+	Suggestions []string
+}
+
+// Message describes a weak password for the user: the warning and suggestions as sentences
+func (s Strength) Message() string {
+	parts := []string{"Password is too easy to guess"}
+	if s.Warning != "" {
+		parts = append(parts, s.Warning)
+	}
+	parts = append(parts, s.Suggestions...)
+	return strings.Join(parts, ". ") + "."
+}
+
+type rankedDictionary struct {
+	name  string
+	ranks map[string]int
+}
+
+// rankedDictionaries are checked in order, so a word in several of them always gets the
+// same match and feedback
+var rankedDictionaries = []rankedDictionary{
+	{dictPasswords, rankWords(parseWordList(commonPasswords))},
+	{dictEnglish, rankWords(parseWordList(englishWords))},
+	{dictNames, rankWords(parseWordList(commonNames))},
+}
+
+func rankWords(words []string) map[string]int {
+	ranked := make(map[string]int, len(words))
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if _, ok := ranked[word]; !ok {
+			ranked[word] = i + 1
+		}
+	}
+	return ranked
+}
+
+// EstimateStrength scores password. userInputs (the user's names, username and email) are
+// treated as the first words an attacker tries.
+func EstimateStrength(password string, userInputs ...string) Strength {
+	runes := []rune(password)
+	if len(runes) > maxStrengthRunes {
+		runes = runes[:maxStrengthRunes]
+	}
+
+	// User inputs come first: reusing your own name is the more useful warning
+	dicts := append([]rankedDictionary{{dictUserInputs, rankWords(userInputWords(userInputs))}}, rankedDictionaries...)
+
+	e := estimator{dicts: dicts, year: time.Now().Year()}
+	guesses, sequence := e.mostGuessable(runes)
+	score := guessesToScore(guesses)
+	warning, suggestions := feedback(score, sequence)
+	return Strength{Score: score, Guesses: guesses, Warning: warning, Suggestions: suggestions}
+}
+
+// userInputWords splits inputs into the words worth matching: each input as a whole and its
+// alphanumeric parts ("john.doe@example.com" gives john, doe, example, com)
+func userInputWords(inputs []string) []string {
+	var words []string
+	add := func(w string) {
+		if utf8.RuneCountInString(w) >= 3 {
+			words = append(words, strings.ToLower(w))
+		}
+	}
+	for _, input := range inputs {
+		input = strings.TrimSpace(input)
+		add(input)
+		parts := strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(parts) > 1 {
+			for _, part := range parts {
+				add(part)
+			}
+		}
+	}
+	return words
+}
+
+func guessesToScore(guesses float64) int {
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return 0
+	case guesses < 1e6+delta:
+		return 1
+	case guesses < 1e8+delta:
+		return 2
+	case guesses < 1e10+delta:
+		return 3
+	default:
+		return 4
+	}
+}
+
+type match struct {
+	pattern string
+	i, j    int // first and last rune, inclusive
+	token   string
+	guesses float64
+
+	dictionary string
+	rank       int
+	reversed   bool
+	l33t       bool
+
+	turns int // spatial
+
+	baseToken string // repeat
+
+	ascending bool // sequence
+}
+
+type estimator struct {
+	dicts []rankedDictionary
+	year  int
+}
+
+func (e *estimator) matches(runes []rune) []*match {
+	var all []*match
+	all = append(all, e.dictionaryMatches(runes)...)
+	all = append(all, e.reverseDictionaryMatches(runes)...)
+	all = append(all, e.l33tMatches(runes)...)
+	all = append(all, spatialMatches(runes)...)
+	all = append(all, e.repeatMatches(runes)...)
+	all = append(all, sequenceMatches(runes)...)
+	all = append(all, e.dateMatches(runes)...)
+	for _, m := range all {
+		if m.guesses == 0 {
+			m.guesses = e.guesses(m)
+		}
+		min := float64(minSubmatchGuessesChar)
+		if m.j > m.i {
+			min = minSubmatchGuessesMulti
+		}
+		m.guesses = math.Max(m.guesses, min)
+	}
+	return all
+}
+
+// mostGuessable finds the sequence of non-overlapping matches, with brute force filling the
+// gaps, that needs the fewest guesses overall
+func (e *estimator) mostGuessable(runes []rune) (float64, []*match) {
+	n := len(runes)
+	if n == 0 {
+		return 1, nil
+	}
+
+	byEnd := make([][]*match, n)
+	for _, m := range e.matches(runes) {
+		byEnd[m.j] = append(byEnd[m.j], m)
+	}
+
+	// For each end position k and sequence length l: the last match, the product of the
+	// sequence's guesses, and its total guesses
+	best := make([]map[int]*match, n)
+	pi := make([]map[int]float64, n)
+	g := make([]map[int]float64, n)
+	for k := range best {
+		best[k], pi[k], g[k] = map[int]*match{}, map[int]float64{}, map[int]float64{}
+	}
+
+	update := func(m *match, l int) {
+		k := m.j
+		p := m.guesses
+		if l > 1 {
+			p *= pi[m.i-1][l-1]
+		}
+		total := factorial(l)*p + math.Pow(minGuessesBeforeGrowing, float64(l-1))
+		for other, otherG := range g[k] {
+			if other <= l && otherG <= total {
+				return
+			}
+		}
+		best[k][l], pi[k][l], g[k][l] = m, p, total
+	}
+	bruteforce := func(i, j int) *match {
+		return &match{pattern: patternBruteforce, i: i, j: j, token: string(runes[i : j+1]),
+			guesses: bruteforceGuesses(j - i + 1)}
+	}
+
+	for k := 0; k < n; k++ {
+		for _, m := range byEnd[k] {
+			if m.i == 0 {
+				update(m, 1)
+				continue
+			}
+			for l := range best[m.i-1] {
+				update(m, l+1)
+			}
+		}
+
+		update(bruteforce(0, k), 1)
+		for i := 1; i <= k; i++ {
+			m := bruteforce(i, k)
+			for l, last := range best[i-1] {
+				// Two brute force matches in a row are never better than one
+				if last.pattern != patternBruteforce {
+					update(m, l+1)
+				}
+			}
+		}
+	}
+
+	bestL, bestG := 0, math.Inf(1)
+	for l, total := range g[n-1] {
+		if total < bestG || (total == bestG && l < bestL) {
+			bestL, bestG = l, total
+		}
+	}
+	sequence := make([]*match, bestL)
+	for k, l := n-1, bestL; l > 0; l-- {
+		m := best[k][l]
+		sequence[l-1] = m
+		k = m.i - 1
+	}
+	return bestG, sequence
+}
+
+func (e *estimator) guesses(m *match) float64 {
+	switch m.pattern {
+	case patternDictionary:
+		guesses := float64(m.rank) * uppercaseVariations(m.token)
+		if m.reversed {
+			guesses *= 2
+		}
+		return guesses
+	case patternSpatial:
+		return spatialGuesses(m)
+	case patternSequence:
+		first, _ := utf8.DecodeRuneInString(m.token)
+		base := 26.0
+		switch {
+		case strings.ContainsRune("aAzZ019", first):
+			base = 4
+		case unicode.IsDigit(first):
+			base = 10
+		}
+		if !m.ascending {
+			base *= 2
+		}
+		return base * float64(utf8.RuneCountInString(m.token))
+	}
+	return 0
+}
+
+func bruteforceGuesses(length int) float64 {
+	// A little above the minimum of a real match, so matches win ties with brute force
+	min := float64(minSubmatchGuessesMulti + 1)
+	if length == 1 {
+		min = minSubmatchGuessesChar + 1
+	}
+	return math.Max(math.Pow(bruteforceCardinality, float64(length)), min)
+}
+
+func factorial(n int) float64 {
+	f := 1.0
+	for i := 2; i <= n; i++ {
+		f *= float64(i)
+	}
+	return f
+}
+
+// binomial returns n choose k
+func binomial(n, k int) float64 {
+	if k > n {
+		return 0
+	}
+	r := 1.0
+	for d := 1; d <= k; d++ {
+		r = r * float64(n-k+d) / float64(d)
+	}
+	return r
+}
+
+// variations counts the ways of choosing which of n+m characters are the n special ones,
+// for attackers that try few specials first
+func variations(special, plain int) float64 {
+	if special == 0 || plain == 0 {
+		return 2
+	}
+	var total float64
+	for i := 1; i <= min(special, plain); i++ {
+		total += binomial(special+plain, i)
+	}
+	return total
+}
+
+func uppercaseVariations(token string) float64 {
+	var upper, lower int
+	for _, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+	runes := []rune(token)
+	// The usual places for capitals: first letter, last letter, everything
+	firstOnly := unicode.IsUpper(runes[0]) && upper == 1
+	lastOnly := unicode.IsUpper(runes[len(runes)-1]) && upper == 1
+	if firstOnly || lastOnly || lower == 0 {
+		return 2
+	}
+	return variations(upper, lower)
+}
+
+func (e *estimator) dictionaryMatches(runes []rune) []*match {
+	lower := []rune(strings.ToLower(string(runes)))
+	if len(lower) != len(runes) {
+		// Lowercasing changed the length; fall back to rune-wise lowering
+		lower = make([]rune, len(runes))
+		for i, r := range runes {
+			lower[i] = unicode.ToLower(r)
+		}
+	}
+
+	var matches []*match
+	for i := range lower {
+		for j := i; j < len(lower); j++ {
+			word := string(lower[i : j+1])
+			for _, dict := range e.dicts {
+				if rank, ok := dict.ranks[word]; ok {
+					matches = append(matches, &match{
+						pattern: patternDictionary, i: i, j: j, token: string(runes[i : j+1]),
+						dictionary: dict.name, rank: rank,
+					})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+func (e *estimator) reverseDictionaryMatches(runes []rune) []*match {
+	n := len(runes)
+	reversed := make([]rune, n)
+	for i, r := range runes {
+		reversed[n-1-i] = r
+	}
+	var matches []*match
+	for _, m := range e.dictionaryMatches(reversed) {
+		// A palindrome is already found forwards
+		if m.j == m.i {
+			continue
+		}
+		m.i, m.j = n-1-m.j, n-1-m.i
+		m.token = string(runes[m.i : m.j+1])
+		m.reversed = true
+		if strings.EqualFold(m.token, reverseString(m.token)) {
+			continue
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+func reverseString(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+// l33tTable lists the characters commonly substituted for each letter
+var l33tTable = map[rune]string{
+	'a': "4@",
+	'b': "8",
+	'c': "({[<",
+	'e': "3",
+	'g': "69",
+	'i': "1!|",
+	'l': "1|7",
+	'o': "0",
+	's': "$5",
+	't': "+7",
+	'x': "%",
+	'z': "2",
+}
+
+// maxL33tSubstitutions bounds the substitution maps tried for one password
+const maxL33tSubstitutions = 64
+
+func (e *estimator) l33tMatches(runes []rune) []*match {
+	// Letters each substitute present in the password could stand for
+	candidates := map[rune][]rune{}
+	var subs []rune
+	for _, r := range runes {
+		if _, seen := candidates[r]; seen {
+			continue
+		}
+		for letter, chars := range l33tTable {
+			if strings.ContainsRune(chars, r) {
+				candidates[r] = append(candidates[r], letter)
+			}
+		}
+		if len(candidates[r]) > 0 {
+			subs = append(subs, r)
+		} else {
+			candidates[r] = nil
+		}
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	// Every way of reading the substitutes, e.g. '1' as both i and l
+	tables := []map[rune]rune{{}}
+	for _, sub := range subs {
+		var next []map[rune]rune
+		for _, table := range tables {
+			for _, letter := range candidates[sub] {
+				t := make(map[rune]rune, len(table)+1)
+				for k, v := range table {
+					t[k] = v
+				}
+				t[sub] = letter
+				next = append(next, t)
+			}
+		}
+		if len(next) > maxL33tSubstitutions {
+			next = next[:maxL33tSubstitutions]
+		}
+		tables = next
+	}
+
+	var matches []*match
+	seen := map[string]bool{}
+	for _, table := range tables {
+		translated := make([]rune, len(runes))
+		for i, r := range runes {
+			if letter, ok := table[r]; ok {
+				translated[i] = letter
+			} else {
+				translated[i] = r
+			}
+		}
+		for _, m := range e.dictionaryMatches(translated) {
+			token := runes[m.i : m.j+1]
+			used := map[rune]rune{}
+			for _, r := range token {
+				if letter, ok := table[r]; ok {
+					used[r] = letter
+				}
+			}
+			// Single characters and plain words are found without substitution
+			if len(used) == 0 || m.i == m.j {
+				continue
+			}
+			key := strconv.Itoa(m.i) + ":" + strconv.Itoa(m.j) + ":" + m.dictionary + ":" + strconv.Itoa(m.rank)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			m.token = string(token)
+			m.l33t = true
+			m.guesses = float64(m.rank) * uppercaseVariations(m.token) * l33tVariations(token, used)
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+func l33tVariations(token []rune, used map[rune]rune) float64 {
+	total := 1.0
+	for sub, letter := range used {
+		var subbed, unsubbed int
+		for _, r := range token {
+			switch {
+			case r == sub:
+				subbed++
+			case unicode.ToLower(r) == letter:
+				unsubbed++
+			}
+		}
+		if subbed == 0 || unsubbed == 0 {
+			total *= 2
+		} else {
+			total *= variations(subbed, unsubbed)
+		}
+	}
+	return total
+}
+
+// qwertyRows lays out a US keyboard, each key as its unshifted and shifted character. Rows
+// are slanted: key x of row y touches keys x and x+1 of the row above.
+var qwertyRows = []struct {
+	offset int
+	keys   string
+}{
+	{0, "`~ 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) -_ =+"},
+	{1, "qQ wW eE rR tT yY uU iI oO pP [{ ]} \\|"},
+	{1, "aA sS dD fF gG hH jJ kK lL ;: '\""},
+	{1, "zZ xX cC vV bB nN mM ,< .> /?"},
+}
+
+type keyboard struct {
+	// neighbors maps each character to its key's neighbors in a fixed direction order;
+	// empty strings stand for no key
+	neighbors map[rune][]string
+	shifted   map[rune]bool
+	// starts and degree are the number of keys and their average number of neighbors
+	starts float64
+	degree float64
+}
+
+var qwerty = buildKeyboard()
+
+func buildKeyboard() *keyboard {
+	type pos struct{ x, y int }
+	keys := map[pos]string{}
+	for y, row := range qwertyRows {
+		for i, key := range strings.Fields(row.keys) {
+			keys[pos{row.offset + i, y}] = key
+		}
+	}
+
+	kb := &keyboard{neighbors: map[rune][]string{}, shifted: map[rune]bool{}}
+	var edges int
+	for p, key := range keys {
+		adjacent := []pos{{p.x - 1, p.y}, {p.x, p.y - 1}, {p.x + 1, p.y - 1}, {p.x + 1, p.y}, {p.x, p.y + 1}, {p.x - 1, p.y + 1}}
+		neighbors := make([]string, len(adjacent))
+		for d, a := range adjacent {
+			neighbors[d] = keys[a]
+			if keys[a] != "" {
+				edges++
+			}
+		}
+		chars := []rune(key)
+		kb.neighbors[chars[0]] = neighbors
+		kb.neighbors[chars[1]] = neighbors
+		kb.shifted[chars[1]] = true
+	}
+	kb.starts = float64(len(keys))
+	kb.degree = float64(edges) / float64(len(keys))
+	return kb
+}
+
+func spatialMatches(runes []rune) []*match {
+	var matches []*match
+	shiftedCount := func(token []rune) int {
+		n := 0
+		for _, r := range token {
+			if qwerty.shifted[r] {
+				n++
+			}
+		}
+		return n
+	}
+
+	for i := 0; i < len(runes)-1; {
+		j := i + 1
+		lastDirection, turns := -1, 0
+		for ; j < len(runes); j++ {
+			direction := -1
+			for d, key := range qwerty.neighbors[runes[j-1]] {
+				if key != "" && strings.ContainsRune(key, runes[j]) {
+					direction = d
+					break
+				}
+			}
+			if direction < 0 {
+				break
+			}
+			if direction != lastDirection {
+				turns++
+				lastDirection = direction
+			}
+		}
+		if j-i > 2 {
+			token := runes[i:j]
+			m := &match{pattern: patternSpatial, i: i, j: j - 1, token: string(token), turns: turns}
+			m.guesses = spatialGuesses(m) * shiftVariations(shiftedCount(token), len(token))
+			matches = append(matches, m)
+		}
+		i = j
+	}
+	return matches
+}
+
+func spatialGuesses(m *match) float64 {
+	length := utf8.RuneCountInString(m.token)
+	var guesses float64
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= min(m.turns, i-1); j++ {
+			guesses += binomial(i-1, j-1) * qwerty.starts * math.Pow(qwerty.degree, float64(j))
+		}
+	}
+	return guesses
+}
+
+func shiftVariations(shifted, length int) float64 {
+	if shifted == 0 {
+		return 1
+	}
+	if shifted == length {
+		return 2
+	}
+	return variations(shifted, length-shifted)
+}
+
+func (e *estimator) repeatMatches(runes []rune) []*match {
+	var matches []*match
+	for i := 0; i < len(runes)-1; {
+		bestBase, bestCount := 0, 0
+		for base := 1; i+2*base <= len(runes); base++ {
+			count := 1
+			for i+(count+1)*base <= len(runes) && string(runes[i+count*base:i+(count+1)*base]) == string(runes[i:i+base]) {
+				count++
+			}
+			if count > 1 && base*count > bestBase*bestCount {
+				bestBase, bestCount = base, count
+			}
+		}
+		if bestCount == 0 {
+			i++
+			continue
+		}
+
+		base := runes[i : i+bestBase]
+		baseGuesses, _ := e.mostGuessable(base)
+		end := i + bestBase*bestCount
+		matches = append(matches, &match{
+			pattern: patternRepeat, i: i, j: end - 1, token: string(runes[i:end]),
+			baseToken: string(base), guesses: baseGuesses * float64(bestCount),
+		})
+		i = end
+	}
+	return matches
+}
+
+func sequenceMatches(runes []rune) []*match {
+	const maxDelta = 5
+	var matches []*match
+	add := func(i, j int, delta rune) {
+		if j-i < 2 || delta == 0 || delta > maxDelta || delta < -maxDelta {
+			return
+		}
+		matches = append(matches, &match{pattern: patternSequence, i: i, j: j, token: string(runes[i : j+1]), ascending: delta > 0})
+	}
+
+	if len(runes) < 3 {
+		return nil
+	}
+	i := 0
+	delta := runes[1] - runes[0]
+	for k := 2; k < len(runes); k++ {
+		if d := runes[k] - runes[k-1]; d != delta {
+			add(i, k-1, delta)
+			i, delta = k-1, d
+		}
+	}
+	add(i, len(runes)-1, delta)
+	return matches
+}
+
+func (e *estimator) dateMatches(runes []rune) []*match {
+	var matches []*match
+	for i := range runes {
+		for j := i + 3; j < len(runes) && j < i+10; j++ {
+			token := string(runes[i : j+1])
+			if year, ok := e.parseYear(token); ok {
+				matches = append(matches, &match{pattern: patternYear, i: i, j: j, token: token,
+					guesses: math.Max(math.Abs(float64(year-e.year)), minYearSpace)})
+			}
+			if year, separated, ok := e.parseDate(token); ok {
+				guesses := math.Max(math.Abs(float64(year-e.year)), minYearSpace) * 365
+				if separated {
+					guesses *= 4
+				}
+				matches = append(matches, &match{pattern: patternDate, i: i, j: j, token: token, guesses: guesses})
+			}
+		}
+	}
+	return matches
+}
+
+func (e *estimator) parseYear(token string) (int, bool) {
+	if len(token) != 4 || !(strings.HasPrefix(token, "19") || strings.HasPrefix(token, "20")) {
+		return 0, false
+	}
+	year, err := strconv.Atoi(token)
+	return year, err == nil
+}
+
+// parseDate reads token as a day, month and year in any order, either as 4-8 digits or as
+// three numbers split by one repeated separator. It returns the year closest to now.
+func (e *estimator) parseDate(token string) (year int, separated bool, ok bool) {
+	var splits [][3]string
+	if isDigits(token) {
+		if len(token) > 8 {
+			return 0, false, false
+		}
+		// Every split into three parts of 1-4 digits
+		for a := 1; a < len(token)-1; a++ {
+			for b := a + 1; b < len(token); b++ {
+				splits = append(splits, [3]string{token[:a], token[a:b], token[b:]})
+			}
+		}
+	} else {
+		sep := strings.IndexFunc(token, func(r rune) bool { return !unicode.IsDigit(r) })
+		if sep <= 0 || !strings.ContainsRune(" /\\_.-", rune(token[sep])) {
+			return 0, false, false
+		}
+		parts := strings.Split(token, token[sep:sep+1])
+		if len(parts) != 3 || !isDigits(parts[0]) || !isDigits(parts[1]) || !isDigits(parts[2]) {
+			return 0, false, false
+		}
+		splits = [][3]string{{parts[0], parts[1], parts[2]}}
+		separated = true
+	}
+
+	bestDistance := -1
+	for _, s := range splits {
+		// The year comes first or last
+		for _, order := range [][3]int{{0, 1, 2}, {0, 2, 1}, {2, 0, 1}, {2, 1, 0}} {
+			y, okY := e.dateYear(s[order[0]])
+			m, okM := dateNumber(s[order[1]], 12)
+			d, okD := dateNumber(s[order[2]], 31)
+			if !okY || !okM || !okD || m == 0 || d == 0 {
+				continue
+			}
+			distance := y - e.year
+			if distance < 0 {
+				distance = -distance
+			}
+			if bestDistance < 0 || distance < bestDistance {
+				year, bestDistance = y, distance
+			}
+		}
+	}
+	return year, separated, bestDistance >= 0
+}
+
+func (e *estimator) dateYear(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	switch {
+	case err != nil:
+		return 0, false
+	case len(s) == 2:
+		// Two-digit years are read as the nearest century
+		if n > e.year%100+10 {
+			return 1900 + n, true
+		}
+		return 2000 + n, true
+	case len(s) == 4 && n >= 1000 && n <= e.year+25:
+		return n, true
+	}
+	return 0, false
+}
+
+func dateNumber(s string, max int) (int, bool) {
+	if len(s) > 2 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil && n <= max
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func feedback(score int, sequence []*match) (string, []string) {
+	if len(sequence) == 0 {
+		return "", []string{"Use a few words, avoid common phrases", "No need for symbols, digits, or uppercase letters"}
+	}
+	if score > 2 {
+		return "", nil
+	}
+
+	longest := sequence[0]
+	for _, m := range sequence[1:] {
+		if len(m.token) > len(longest.token) {
+			longest = m
+		}
+	}
+	warning, suggestions := matchFeedback(longest, len(sequence) == 1)
+	return warning, append([]string{"Add another word or two. Uncommon words are better"}, suggestions...)
+}
+
+func matchFeedback(m *match, soleMatch bool) (string, []string) {
+	switch m.pattern {
+	case patternDictionary:
+		return dictionaryFeedback(m, soleMatch)
+	case patternSpatial:
+		if m.turns == 1 {
+			return "Straight rows of keys are easy to guess", []string{"Use a longer keyboard pattern with more turns"}
+		}
+		return "Short keyboard patterns are easy to guess", []string{"Use a longer keyboard pattern with more turns"}
+	case patternRepeat:
+		if utf8.RuneCountInString(m.baseToken) == 1 {
+			return `Repeats like "aaa" are easy to guess`, []string{"Avoid repeated words and characters"}
+		}
+		return `Repeats like "abcabcabc" are only slightly harder to guess than "abc"`, []string{"Avoid repeated words and characters"}
+	case patternSequence:
+		return "Sequences like abc or 6543 are easy to guess", []string{"Avoid sequences"}
+	case patternYear:
+		return "Recent years are easy to guess", []string{"Avoid recent years", "Avoid years that are associated with you"}
+	case patternDate:
+		return "Dates are often easy to guess", []string{"Avoid dates and years that are associated with you"}
+	}
+	return "", nil
+}
+
+func dictionaryFeedback(m *match, soleMatch bool) (string, []string) {
+	var warning string
+	switch m.dictionary {
+	case dictPasswords:
+		switch {
+		case soleMatch && !m.l33t && !m.reversed && m.rank <= 10:
+			warning = "This is a top-10 common password"
+		case soleMatch && !m.l33t && !m.reversed && m.rank <= 100:
+			warning = "This is a top-100 common password"
+		case soleMatch && !m.l33t && !m.reversed:
+			warning = "This is a very common password"
+		case math.Log10(m.guesses) <= 4:
+			warning = "This is similar to a commonly used password"
+		}
+	case dictEnglish:
+		if soleMatch {
+			warning = "A word by itself is easy to guess"
+		}
+	case dictNames:
+		if soleMatch {
+			warning = "Names and surnames by themselves are easy to guess"
+		} else {
+			warning = "Common names and surnames are easy to guess"
+		}
+	case dictUserInputs:
+		warning = "Avoid using your name, username or email address"
+	}
+
+	var suggestions []string
+	runes := []rune(m.token)
+	switch {
+	case strings.ToUpper(m.token) == m.token && strings.ToLower(m.token) != m.token:
+		suggestions = append(suggestions, "All-uppercase is almost as easy to guess as all-lowercase")
+	case unicode.IsUpper(runes[0]):
+		suggestions = append(suggestions, "Capitalization doesn't help very much")
+	}
+	if m.reversed && len(runes) >= 4 {
+		suggestions = append(suggestions, "Reversed words aren't much harder to guess")
+	}
+	if m.l33t {
+		suggestions = append(suggestions, "Predictable substitutions like '@' instead of 'a' don't help very much")
+	}
+	return warning, suggestions
+}
@@ -0,0 +1,190 @@
+package validator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"tyk-registration-server/internal/utils"
+)
+
+//go:embed rules/reserved_usernames.txt
+var reservedUsernames []byte
+
+//go:embed rules/profanity.txt
+var defaultProfanity []byte
+
+// Username policy messages
+const (
+	UsernameCharsetMessage   = "Username may only contain letters, digits, '.', '_' and '-', and must start with a letter or digit"
+	UsernameReservedMessage  = "This username is reserved"
+	UsernameProfaneMessage   = "Username contains inappropriate language"
+	UsernameLookalikeMessage = "Username is too similar to an existing username"
+)
+
+// LookalikeChecker finds existing usernames that look like a new one;
+// repositories.UserRepository implements it
+type LookalikeChecker interface {
+	UsernameLookalikeExists(ctx context.Context, username string) (bool, error)
+}
+
+// UsernamePolicy decides which usernames may be registered beyond the field rules: an
+// allowed character set, reserved names, profanity, and lookalikes (UTS #39 confusable
+// skeletons) of existing usernames
+type UsernamePolicy struct {
+	reserved   map[string]bool
+	profanity  []string
+	lookalikes LookalikeChecker
+}
+
+// NewUsernamePolicy builds a policy with the built-in reserved names and the given profanity
+// list (see LoadProfanityList). lookalikes may be nil to skip the lookalike check.
+func NewUsernamePolicy(profanity []string, lookalikes LookalikeChecker) *UsernamePolicy {
+	p := &UsernamePolicy{reserved: map[string]bool{}, lookalikes: lookalikes}
+	for _, name := range parseWordList(reservedUsernames) {
+		p.reserved[policyFold(name)] = true
+	}
+	for _, word := range profanity {
+		if folded := policyFold(word); folded != "" {
+			p.profanity = append(p.profanity, folded)
+		}
+	}
+	return p
+}
+
+// LoadProfanityList reads a word list, one word per line with # comments, or returns the
+// built-in list if path is empty
+func LoadProfanityList(path string) ([]string, error) {
+	if path == "" {
+		return parseWordList(defaultProfanity), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profanity list: %w", err)
+	}
+	return parseWordList(data), nil
+}
+
+// Violation runs the checks that need no database and returns the message of the first one
+// that fails, or "" if username passes them
+func (p *UsernamePolicy) Violation(username string) string {
+	username = strings.TrimSpace(username)
+	if !usernameCharsetAllowed(username) {
+		return UsernameCharsetMessage
+	}
+
+	forms := policyForms(username)
+	for _, form := range forms {
+		if p.reserved[form] {
+			return UsernameReservedMessage
+		}
+	}
+	// A listed word must begin or end a word of the username, or the whole username with
+	// separators removed; matching anywhere inside a word would reject names such as
+	// "Scunthorpe"
+	for _, word := range append(forms, policyWords(username)...) {
+		for _, entry := range p.profanity {
+			if strings.HasPrefix(word, entry) || strings.HasSuffix(word, entry) {
+				return UsernameProfaneMessage
+			}
+		}
+	}
+	return ""
+}
+
+// Check runs every check, including the lookup of existing lookalike usernames
+func (p *UsernamePolicy) Check(ctx context.Context, username string) (string, error) {
+	if msg := p.Violation(username); msg != "" {
+		return msg, nil
+	}
+	if p.lookalikes == nil {
+		return "", nil
+	}
+	exists, err := p.lookalikes.UsernameLookalikeExists(ctx, username)
+	if err != nil {
+		return "", fmt.Errorf("failed to check for lookalike usernames: %w", err)
+	}
+	if exists {
+		return UsernameLookalikeMessage, nil
+	}
+	return "", nil
+}
+
+// usernameCharsetAllowed accepts letters (with combining marks) and digits of any script plus
+// '.', '_' and '-', starting with a letter or digit
+func usernameCharsetAllowed(username string) bool {
+	for i, r := range username {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+		case i > 0 && (unicode.IsMark(r) || r == '.' || r == '_' || r == '-'):
+		default:
+			return false
+		}
+	}
+	return username != ""
+}
+
+// leetReplacer undoes the digit-for-letter swaps ("4dmin", "5hit") the confusable skeleton
+// leaves alone. 0 and 1 are not here: the skeleton already maps them to o and l.
+var leetReplacer = strings.NewReplacer("3", "e", "4", "a", "5", "s", "7", "t", "8", "b")
+
+// policyForms returns the folded forms of username that reserved names are matched against:
+// as written, and with digit-for-letter swaps undone
+func policyForms(username string) []string {
+	return foldedVariants(utils.CanonicalUsername(username))
+}
+
+// policyWords returns the folded forms of each word of username, its parts between
+// separators
+func policyWords(username string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(utils.CanonicalUsername(username), isUsernameSeparator) {
+		words = append(words, foldedVariants(word)...)
+	}
+	return words
+}
+
+func foldedVariants(s string) []string {
+	folded := policyFold(s)
+	leet := policyFold(leetReplacer.Replace(s))
+	if leet == folded {
+		return []string{folded}
+	}
+	return []string{folded, leet}
+}
+
+// policyFold reduces s to its confusable skeleton without separators, so lookalike letters
+// and punctuation can't dodge a match. The skeleton maps 1 to l, and 1 stands in for i as
+// often, so i is folded into l as well and "sh1t" matches "shit".
+func policyFold(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case isUsernameSeparator(r):
+			return -1
+		case r == 'i':
+			return 'l'
+		}
+		return r
+	}, utils.UsernameSkeleton(s))
+}
+
+func isUsernameSeparator(r rune) bool {
+	return r == '.' || r == '_' || r == '-'
+}
+
+func parseWordList(data []byte) []string {
+	var words []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			words = append(words, line)
+		}
+	}
+	return words
+}
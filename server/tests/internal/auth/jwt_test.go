@@ -0,0 +1,129 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tyk-registration-server/internal/auth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testClaims(ttl time.Duration) auth.Claims {
+	now := time.Now()
+	return auth.Claims{
+		Issuer:    "test-issuer",
+		Subject:   "user-123",
+		ID:        "token-1",
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+}
+
+func newEdDSASigner(t *testing.T) auth.Signer {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return auth.NewEdDSASigner(priv)
+}
+
+func TestSigner_RoundTrip(t *testing.T) {
+	signers := map[string]auth.Signer{
+		"HS256": auth.NewHS256Signer([]byte("test-secret")),
+		"EdDSA": newEdDSASigner(t),
+	}
+
+	for name, signer := range signers {
+		t.Run(name, func(t *testing.T) {
+			token, err := signer.Sign(testClaims(time.Minute))
+			require.NoError(t, err)
+			assert.Len(t, strings.Split(token, "."), 3)
+
+			claims, err := signer.Verify(token, "test-issuer")
+			require.NoError(t, err)
+			assert.Equal(t, "user-123", claims.Subject)
+			assert.Equal(t, "token-1", claims.ID)
+		})
+	}
+}
+
+func TestSigner_RejectsTamperedToken(t *testing.T) {
+	signer := auth.NewHS256Signer([]byte("test-secret"))
+	token, err := signer.Sign(testClaims(time.Minute))
+	require.NoError(t, err)
+
+	other, err := auth.NewHS256Signer([]byte("other-secret")).Sign(testClaims(time.Minute))
+	require.NoError(t, err)
+
+	// Swap in another token's payload
+	parts := strings.Split(token, ".")
+	otherParts := strings.Split(other, ".")
+	forged := parts[0] + "." + otherParts[1] + "." + parts[2]
+	forged = forged[:len(forged)-2] + "xx"
+
+	_, err = signer.Verify(forged, "")
+	assert.ErrorIs(t, err, auth.ErrInvalidSignature)
+
+	_, err = signer.Verify(other, "")
+	assert.ErrorIs(t, err, auth.ErrInvalidSignature)
+}
+
+func TestSigner_RejectsAlgorithmMismatch(t *testing.T) {
+	token, err := newEdDSASigner(t).Sign(testClaims(time.Minute))
+	require.NoError(t, err)
+
+	_, err = auth.NewHS256Signer([]byte("test-secret")).Verify(token, "")
+	assert.ErrorIs(t, err, auth.ErrInvalidSignature)
+}
+
+func TestSigner_RejectsExpiredToken(t *testing.T) {
+	signer := auth.NewHS256Signer([]byte("test-secret"))
+	token, err := signer.Sign(testClaims(-time.Hour))
+	require.NoError(t, err)
+
+	_, err = signer.Verify(token, "")
+	assert.ErrorIs(t, err, auth.ErrTokenExpired)
+}
+
+func TestSigner_RejectsWrongIssuer(t *testing.T) {
+	signer := auth.NewHS256Signer([]byte("test-secret"))
+	token, err := signer.Sign(testClaims(time.Minute))
+	require.NoError(t, err)
+
+	_, err = signer.Verify(token, "someone-else")
+	assert.ErrorIs(t, err, auth.ErrInvalidIssuer)
+}
+
+func TestSigner_RejectsMalformedToken(t *testing.T) {
+	signer := auth.NewHS256Signer([]byte("test-secret"))
+
+	_, err := signer.Verify("not-a-jwt", "")
+	assert.ErrorIs(t, err, auth.ErrMalformedToken)
+}
+
+func TestNewSigner_EdDSAFromKeyFile(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	signer, err := auth.NewSigner(auth.AlgEdDSA, "", path)
+	require.NoError(t, err)
+	assert.Equal(t, auth.AlgEdDSA, signer.Algorithm())
+
+	_, err = auth.NewSigner(auth.AlgEdDSA, "", "")
+	assert.Error(t, err)
+
+	_, err = auth.NewSigner("RS256", "", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,213 @@
+package auth_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"tyk-registration-server/internal/auth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheapParams keep the tests fast; production cost comes from DefaultArgon2idParams
+var cheapParams = auth.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func newTestHasher(t *testing.T, params auth.Argon2idParams) auth.PasswordHasher {
+	return newPepperedHasher(t, params, nil)
+}
+
+func newPepperedHasher(t *testing.T, params auth.Argon2idParams, pepper *auth.Pepper) auth.PasswordHasher {
+	h, err := auth.NewPasswordHasher(params, pepper, 0)
+	require.NoError(t, err)
+	return h
+}
+
+func TestPasswordHasher_HashIsPHCArgon2id(t *testing.T) {
+	h := newTestHasher(t, cheapParams)
+
+	hash, err := h.Hash(context.Background(), "Test123!@#")
+	require.NoError(t, err)
+
+	parts := strings.Split(hash, "$")
+	require.Len(t, parts, 6)
+	assert.Equal(t, "argon2id", parts[1])
+	assert.Equal(t, "v=19", parts[2])
+	assert.Equal(t, "m=64,t=1,p=1", parts[3])
+	assert.NotContains(t, parts[4]+parts[5], "=", "PHC strings use unpadded base64")
+}
+
+func TestPasswordHasher_SaltsEachHash(t *testing.T) {
+	h := newTestHasher(t, cheapParams)
+
+	hash1, err := h.Hash(context.Background(), "Test123!@#")
+	require.NoError(t, err)
+	hash2, err := h.Hash(context.Background(), "Test123!@#")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash1, hash2)
+}
+
+func TestPasswordHasher_Verify(t *testing.T) {
+	h := newTestHasher(t, cheapParams)
+	hash, err := h.Hash(context.Background(), "Test123!@#")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{"correct password", "Test123!@#", true},
+		{"wrong case", "test123!@#", false},
+		{"empty password", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash, err := h.Verify(context.Background(), hash, tt.password)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ok)
+			assert.False(t, rehash)
+		})
+	}
+}
+
+func TestPasswordHasher_DistinguishesLongPasswords(t *testing.T) {
+	h := newTestHasher(t, cheapParams)
+	prefix := strings.Repeat("a", 72)
+
+	hash, err := h.Hash(context.Background(), prefix+"one")
+	require.NoError(t, err)
+
+	ok, _, err := h.Verify(context.Background(), hash, prefix+"two")
+	require.NoError(t, err)
+	assert.False(t, ok, "bytes past bcrypt's 72-byte limit must still count")
+}
+
+func TestPasswordHasher_VerifiesLegacyBcrypt(t *testing.T) {
+	h := newTestHasher(t, cheapParams)
+	legacy, err := bcrypt.GenerateFromPassword([]byte("Test123!@#"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	ok, rehash, err := h.Verify(context.Background(), string(legacy), "Test123!@#")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, rehash, "bcrypt hashes should be upgraded")
+
+	ok, rehash, err = h.Verify(context.Background(), string(legacy), "wrong")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, rehash)
+}
+
+func TestPasswordHasher_RehashOnParameterChange(t *testing.T) {
+	old := newTestHasher(t, cheapParams)
+	hash, err := old.Hash(context.Background(), "Test123!@#")
+	require.NoError(t, err)
+
+	stronger := cheapParams
+	stronger.Iterations = 2
+	current := newTestHasher(t, stronger)
+
+	ok, rehash, err := current.Verify(context.Background(), hash, "Test123!@#")
+	require.NoError(t, err)
+	assert.True(t, ok, "hashes made with old parameters still verify")
+	assert.True(t, rehash)
+
+	upgraded, err := current.Hash(context.Background(), "Test123!@#")
+	require.NoError(t, err)
+	ok, rehash, err = current.Verify(context.Background(), upgraded, "Test123!@#")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+}
+
+func TestPasswordHasher_UnknownFormat(t *testing.T) {
+	h := newTestHasher(t, cheapParams)
+
+	for _, hash := range []string{"", "not-a-hash", "$argon2i$v=19$m=64,t=1,p=1$c2FsdA$a2V5", "$argon2id$v=19$broken"} {
+		ok, _, err := h.Verify(context.Background(), hash, "Test123!@#")
+		assert.ErrorIs(t, err, auth.ErrUnknownHashFormat, hash)
+		assert.False(t, ok)
+	}
+}
+
+func TestNewPasswordHasher_RejectsWeakParams(t *testing.T) {
+	tests := map[string]func(p *auth.Argon2idParams){
+		"no iterations":  func(p *auth.Argon2idParams) { p.Iterations = 0 },
+		"no parallelism": func(p *auth.Argon2idParams) { p.Parallelism = 0 },
+		"too little memory": func(p *auth.Argon2idParams) {
+			p.Parallelism = 4
+			p.Memory = 16
+		},
+		"short salt":          func(p *auth.Argon2idParams) { p.SaltLength = 4 },
+		"short key":           func(p *auth.Argon2idParams) { p.KeyLength = 8 },
+		"too much memory":     func(p *auth.Argon2idParams) { p.Memory = 2 << 20 },
+		"too many iterations": func(p *auth.Argon2idParams) { p.Iterations = 1000 },
+		"long salt":           func(p *auth.Argon2idParams) { p.SaltLength = 1024 },
+		"long key":            func(p *auth.Argon2idParams) { p.KeyLength = 1024 },
+	}
+
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			params := cheapParams
+			mutate(&params)
+			_, err := auth.NewPasswordHasher(params, nil, 0)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := auth.NewPasswordHasher(cheapParams, nil, -1)
+	assert.Error(t, err, "negative concurrency")
+}
+
+// A stored hash can't ask Verify for more than the bounds, however it was produced
+func TestPasswordHasher_RejectsCostlyStoredParams(t *testing.T) {
+	h := newTestHasher(t, cheapParams)
+	hash, err := h.Hash(context.Background(), "Test123!@#")
+	require.NoError(t, err)
+
+	for _, params := range []string{"m=4194304,t=1,p=1", "m=64,t=100000,p=1"} {
+		costly := strings.Replace(hash, "m=64,t=1,p=1", params, 1)
+		ok, _, err := h.Verify(context.Background(), costly, "Test123!@#")
+		assert.ErrorIs(t, err, auth.ErrUnknownHashFormat, params)
+		assert.False(t, ok)
+	}
+}
+
+func TestPasswordHasher_ConcurrentVerify(t *testing.T) {
+	h, err := auth.NewPasswordHasher(cheapParams, nil, 1)
+	require.NoError(t, err)
+	hash, err := h.Hash(context.Background(), "Test123!@#")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, _, err := h.Verify(context.Background(), hash, "Test123!@#")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPasswordHasher_GivesUpWhenContextEnds(t *testing.T) {
+	h := newTestHasher(t, cheapParams)
+	hash, err := h.Hash(context.Background(), "Test123!@#")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = h.Hash(ctx, "Test123!@#")
+	assert.ErrorIs(t, err, context.Canceled)
+	ok, _, err := h.Verify(ctx, hash, "Test123!@#")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, ok)
+}
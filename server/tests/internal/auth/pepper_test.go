@@ -0,0 +1,130 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tyk-registration-server/internal/auth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pepperKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func TestLoadPepper(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "pepper.keys")
+	require.NoError(t, os.WriteFile(keyFile, []byte("# rotated 2026-10\n2026-10:"+pepperKey('b')+"\n\n"), 0o600))
+
+	t.Run("no keys disables the pepper", func(t *testing.T) {
+		pepper, err := auth.LoadPepper("", "", "")
+		require.NoError(t, err)
+		assert.Nil(t, pepper)
+	})
+
+	t.Run("single key is current", func(t *testing.T) {
+		pepper, err := auth.LoadPepper("2026-01:"+pepperKey('a'), "", "")
+		require.NoError(t, err)
+		assert.Equal(t, "2026-01", pepper.CurrentID)
+	})
+
+	t.Run("inline and file keys combine", func(t *testing.T) {
+		pepper, err := auth.LoadPepper("2026-01:"+pepperKey('a'), keyFile, "2026-10")
+		require.NoError(t, err)
+		assert.Equal(t, "2026-10", pepper.CurrentID)
+		assert.Len(t, pepper.Keys, 2)
+	})
+
+	errorCases := map[string][3]string{
+		"several keys without a current one": {"a:" + pepperKey('a') + ",b:" + pepperKey('b'), "", ""},
+		"current key not configured":         {"a:" + pepperKey('a'), "", "b"},
+		"current key without any keys":       {"", "", "a"},
+		"duplicate ID":                       {"2026-10:" + pepperKey('a'), keyFile, ""},
+		"missing separator":                  {pepperKey('a'), "", ""},
+		"invalid ID":                         {"a$b:" + pepperKey('a'), "", ""},
+		"not base64":                         {"a:not base64!", "", ""},
+		"short key":                          {"a:" + base64.StdEncoding.EncodeToString([]byte("short")), "", ""},
+		"missing file":                       {"", filepath.Join(dir, "missing"), ""},
+	}
+	for name, args := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := auth.LoadPepper(args[0], args[1], args[2])
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPasswordHasher_PepperKeyIDInHash(t *testing.T) {
+	pepper, err := auth.LoadPepper("k1:"+pepperKey('a'), "", "")
+	require.NoError(t, err)
+	h := newPepperedHasher(t, cheapParams, pepper)
+	assert.Equal(t, "k1", h.KeyID())
+
+	hash, err := h.Hash(context.Background(), "Test123!@#")
+	require.NoError(t, err)
+	assert.Equal(t, "m=64,t=1,p=1,kid=k1", strings.Split(hash, "$")[3])
+
+	ok, rehash, err := h.Verify(context.Background(), hash, "Test123!@#")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+
+	ok, _, err = h.Verify(context.Background(), hash, "wrong")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestPasswordHasher_PepperedHashNeedsKey(t *testing.T) {
+	pepper, err := auth.LoadPepper("k1:"+pepperKey('a'), "", "")
+	require.NoError(t, err)
+	hash, err := newPepperedHasher(t, cheapParams, pepper).Hash(context.Background(), "Test123!@#")
+	require.NoError(t, err)
+
+	// Same ID, different secret: the hash no longer verifies
+	other, err := auth.LoadPepper("k1:"+pepperKey('z'), "", "")
+	require.NoError(t, err)
+	ok, _, err := newPepperedHasher(t, cheapParams, other).Verify(context.Background(), hash, "Test123!@#")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Key removed from config
+	_, _, err = newTestHasher(t, cheapParams).Verify(context.Background(), hash, "Test123!@#")
+	assert.ErrorIs(t, err, auth.ErrUnknownPepperKey)
+}
+
+func TestPasswordHasher_PepperRotation(t *testing.T) {
+	oldPepper, err := auth.LoadPepper("k1:"+pepperKey('a'), "", "")
+	require.NoError(t, err)
+	oldHash, err := newPepperedHasher(t, cheapParams, oldPepper).Hash(context.Background(), "Test123!@#")
+	require.NoError(t, err)
+	unpeppered, err := newTestHasher(t, cheapParams).Hash(context.Background(), "Test123!@#")
+	require.NoError(t, err)
+
+	rotated, err := auth.LoadPepper("k1:"+pepperKey('a')+",k2:"+pepperKey('b'), "", "k2")
+	require.NoError(t, err)
+	h := newPepperedHasher(t, cheapParams, rotated)
+
+	for name, hash := range map[string]string{"old key": oldHash, "no pepper": unpeppered} {
+		t.Run(name, func(t *testing.T) {
+			ok, rehash, err := h.Verify(context.Background(), hash, "Test123!@#")
+			require.NoError(t, err)
+			assert.True(t, ok, "hashes under a retiring key keep verifying")
+			assert.True(t, rehash, "and are re-peppered with the current key")
+		})
+	}
+
+	newHash, err := h.Hash(context.Background(), "Test123!@#")
+	require.NoError(t, err)
+	assert.Contains(t, newHash, ",kid=k2$")
+	ok, rehash, err := h.Verify(context.Background(), newHash, "Test123!@#")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+}
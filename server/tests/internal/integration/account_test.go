@@ -0,0 +1,199 @@
+package integration_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/internal/router"
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+func deleteAccount(t *testing.T, app *fiber.App, authorize func(*http.Request)) *http.Response {
+	httpReq := httptest.NewRequest(http.MethodDelete, "/api/me", nil)
+	if authorize != nil {
+		authorize(httpReq)
+	}
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	return resp
+}
+
+func postRegister(t *testing.T, app *fiber.App, req *models.RegistrationRequest) *http.Response {
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	return resp
+}
+
+// setupAccountTest builds a full server so the purger can be run directly
+func setupAccountTest(t *testing.T) *router.Server {
+	srv := router.NewServer(testhelpers.LoadTestConfig(t))
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	testhelpers.CleanupUsersTable(t, pool)
+	testhelpers.CleanupOutboxTable(t, pool)
+	return srv
+}
+
+func TestAPI_DeleteAccount_RequiresLogin(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	resp := deleteAccount(t, app, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestAPI_DeleteAccount(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+	req, creds := loginTestUser(t, app)
+
+	resp := deleteAccount(t, app, creds.withCookie)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	var result models.AccountDeletionResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.False(t, result.PurgeAfter.IsZero())
+
+	var cleared bool
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "session_id" {
+			cleared = cookie.Value == ""
+		}
+	}
+	assert.True(t, cleared, "the session cookie is cleared")
+
+	// Signed out everywhere, and the account can't sign in or be deleted again
+	assert.Equal(t, http.StatusUnauthorized, getExport(t, app, "", creds.withCookie).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, getExport(t, app, "", creds.withBearer).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, deleteAccount(t, app, creds.withCookie).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, postLogin(t, app, req.Username, req.Password).StatusCode)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	var events int
+	err := pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM audit_events WHERE type = $1`, models.AuditAccountDeleted).Scan(&events)
+	require.NoError(t, err)
+	assert.Equal(t, 1, events)
+
+	// The sign-outs committed with the soft delete
+	var sessions, liveTokens int
+	require.NoError(t, pool.QueryRow(context.Background(), `SELECT COUNT(*) FROM sessions`).Scan(&sessions))
+	require.NoError(t, pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM refresh_tokens WHERE revoked_at IS NULL`).Scan(&liveTokens))
+	assert.Zero(t, sessions)
+	assert.Zero(t, liveTokens)
+}
+
+func TestAPI_DeleteAccount_IdentifierPolicy(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   int
+	}{
+		{models.DeletedIdentifiersReserve, http.StatusUnprocessableEntity},
+		{models.DeletedIdentifiersRelease, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			t.Setenv("ACCOUNT_DELETION_IDENTIFIERS", tt.policy)
+			app := setupTest(t)
+			defer cleanupTest(t)
+			req, creds := loginTestUser(t, app)
+			require.Equal(t, http.StatusAccepted, deleteAccount(t, app, creds.withBearer).StatusCode)
+
+			resp := postRegister(t, app, req)
+			assert.Equal(t, tt.want, resp.StatusCode)
+		})
+	}
+}
+
+func TestAccountPurge(t *testing.T) {
+	t.Setenv("ACCOUNT_DELETION_GRACE", "0s")
+
+	t.Run(models.PurgeModeDelete, func(t *testing.T) {
+		t.Setenv("ACCOUNT_PURGE_MODE", models.PurgeModeDelete)
+		srv := setupAccountTest(t)
+		defer cleanupTest(t)
+		pool := testhelpers.GetTestDBPool(t)
+		defer pool.Close()
+		req, creds := loginTestUser(t, srv.App)
+
+		// A draft started with the same email, in another case
+		drafts := repositories.NewDraftRepository(pool, nil, nil)
+		draft, err := drafts.CreateDraft(context.Background(), "token-hash", time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		email, _ := json.Marshal(strings.ToUpper(req.Email))
+		draft.Data = map[string]json.RawMessage{"email": email}
+		_, err = drafts.UpdateDraft(context.Background(), draft)
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusAccepted, deleteAccount(t, srv.App, creds.withCookie).StatusCode)
+
+		purged, err := srv.Accounts.PurgeDeleted(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+
+		var users, remainingDrafts int
+		require.NoError(t, pool.QueryRow(context.Background(), `SELECT COUNT(*) FROM users`).Scan(&users))
+		require.NoError(t, pool.QueryRow(context.Background(), `SELECT COUNT(*) FROM registration_drafts`).Scan(&remainingDrafts))
+		assert.Zero(t, users)
+		assert.Zero(t, remainingDrafts)
+
+		var types []string
+		rows, err := pool.Query(context.Background(), `SELECT type FROM audit_events`)
+		require.NoError(t, err)
+		for rows.Next() {
+			var eventType string
+			require.NoError(t, rows.Scan(&eventType))
+			types = append(types, eventType)
+		}
+		require.NoError(t, rows.Err())
+		assert.Equal(t, []string{models.AuditAccountPurged}, types, "only the tombstone is kept")
+
+		purged, err = srv.Accounts.PurgeDeleted(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, purged)
+	})
+
+	t.Run(models.PurgeModeAnonymize, func(t *testing.T) {
+		t.Setenv("ACCOUNT_PURGE_MODE", models.PurgeModeAnonymize)
+		srv := setupAccountTest(t)
+		defer cleanupTest(t)
+		req, creds := loginTestUser(t, srv.App)
+		require.Equal(t, http.StatusAccepted, deleteAccount(t, srv.App, creds.withCookie).StatusCode)
+
+		purged, err := srv.Accounts.PurgeDeleted(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+
+		pool := testhelpers.GetTestDBPool(t)
+		defer pool.Close()
+		var username, email string
+		var sessions int
+		err = pool.QueryRow(context.Background(),
+			`SELECT username, email, (SELECT COUNT(*) FROM sessions) FROM users WHERE purged_at IS NOT NULL`).
+			Scan(&username, &email, &sessions)
+		require.NoError(t, err)
+		assert.NotEqual(t, req.Username, username)
+		assert.Empty(t, email)
+		assert.Zero(t, sessions)
+
+		// Anonymized rows don't hold their identifiers
+		assert.Equal(t, http.StatusCreated, postRegister(t, srv.App, req).StatusCode)
+	})
+}
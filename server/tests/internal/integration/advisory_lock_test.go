@@ -0,0 +1,38 @@
+package integration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/db"
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+func TestWithAdvisoryLock_OneHolderAtATime(t *testing.T) {
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	ctx := context.Background()
+
+	var innerRan bool
+	ran, err := db.WithAdvisoryLock(ctx, pool, "test lock", func(ctx context.Context) error {
+		// Another session, as a second instance would use, can't take the lock meanwhile
+		locked, err := db.WithAdvisoryLock(ctx, pool, "test lock", func(context.Context) error {
+			innerRan = true
+			return nil
+		})
+		require.NoError(t, err)
+		assert.False(t, locked)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, ran)
+	assert.False(t, innerRan)
+
+	// Released afterwards
+	ran, err = db.WithAdvisoryLock(ctx, pool, "test lock", func(context.Context) error { return nil })
+	require.NoError(t, err)
+	assert.True(t, ran)
+}
@@ -2,6 +2,7 @@ package integration_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -25,6 +26,9 @@ func setupTest(t *testing.T) *fiber.App {
 	pool := testhelpers.GetTestDBPool(t)
 	defer pool.Close()
 	testhelpers.CleanupUsersTable(t, pool)
+	testhelpers.CleanupOutboxTable(t, pool)
+	testhelpers.CleanupDraftsTable(t, pool)
+	testhelpers.CleanupIdempotencyKeysTable(t, pool)
 
 	return app
 }
@@ -34,6 +38,9 @@ func cleanupTest(t *testing.T) {
 	pool := testhelpers.GetTestDBPool(t)
 	defer pool.Close()
 	testhelpers.CleanupUsersTable(t, pool)
+	testhelpers.CleanupOutboxTable(t, pool)
+	testhelpers.CleanupDraftsTable(t, pool)
+	testhelpers.CleanupIdempotencyKeysTable(t, pool)
 }
 
 func TestAPI_HealthEndpoint(t *testing.T) {
@@ -210,3 +217,31 @@ func TestAPI_UsernameAvailability_TooShort(t *testing.T) {
 	assert.Equal(t, "short", result["username"])
 	assert.Equal(t, false, result["available"])
 }
+
+func TestAPI_Register_EnqueuesOutboxEvent(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	body, _ := json.Marshal(req)
+
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var result map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+
+	// The event was committed with the user row and waits for the dispatcher
+	var eventType, status string
+	err = pool.QueryRow(context.Background(),
+		"SELECT event_type, status FROM outbox WHERE aggregate_id = $1", result["user_id"]).Scan(&eventType, &status)
+	require.NoError(t, err)
+	assert.Equal(t, "user.registered", eventType)
+	assert.Equal(t, "pending", status)
+}
@@ -0,0 +1,140 @@
+package integration_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+// registerTestUser registers the default fixture user and returns the request used
+func registerTestUser(t *testing.T, app *fiber.App) *models.RegistrationRequest {
+	req := testhelpers.CreateTestRegistrationRequest()
+	body, _ := json.Marshal(req)
+
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	return req
+}
+
+func postLogin(t *testing.T, app *fiber.App, identifier, password string) *http.Response {
+	body, _ := json.Marshal(models.LoginRequest{Identifier: identifier, Password: password})
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestAPI_Login_WithUsername(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := registerTestUser(t, app)
+
+	resp := postLogin(t, app, req.Username, req.Password)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result map[string]interface{}
+	err := json.NewDecoder(resp.Body).Decode(&result)
+	require.NoError(t, err)
+	assert.NotEmpty(t, result["user_id"])
+	assert.Equal(t, req.Username, result["username"])
+
+	var sessionCookie *http.Cookie
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "session_id" {
+			sessionCookie = cookie
+		}
+	}
+	require.NotNil(t, sessionCookie)
+	assert.NotEmpty(t, sessionCookie.Value)
+	assert.True(t, sessionCookie.HttpOnly)
+}
+
+func TestAPI_Login_WithEmail(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := registerTestUser(t, app)
+
+	resp := postLogin(t, app, req.Email, req.Password)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAPI_Login_WrongPassword(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := registerTestUser(t, app)
+
+	resp := postLogin(t, app, req.Username, "Wrong123!@#")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Empty(t, resp.Cookies())
+}
+
+func TestAPI_Login_UnknownUser(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	resp := postLogin(t, app, "nobody@example.us", "Test123!@#")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestAPI_Login_MissingFields(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	resp := postLogin(t, app, "", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var result map[string]map[string]interface{}
+	err := json.NewDecoder(resp.Body).Decode(&result)
+	require.NoError(t, err)
+	fields := result["error"]["field_errors"].(map[string]interface{})
+	assert.Contains(t, fields, "identifier")
+	assert.Contains(t, fields, "password")
+}
+
+func TestAPI_Login_RehashesLegacyBcrypt(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := registerTestUser(t, app)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	ctx := context.Background()
+
+	// Simulate an account created before argon2id was adopted
+	legacy, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.MinCost)
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, `UPDATE users SET password_hash = $1 WHERE username = $2`, string(legacy), req.Username)
+	require.NoError(t, err)
+
+	resp := postLogin(t, app, req.Username, req.Password)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var hash string
+	err = pool.QueryRow(ctx, `SELECT password_hash FROM users WHERE username = $1`, req.Username).Scan(&hash)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$"), "legacy hash should be replaced on login, got %q", hash)
+
+	// The upgraded hash keeps working
+	resp = postLogin(t, app, req.Username, req.Password)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
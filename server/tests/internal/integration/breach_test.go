@@ -0,0 +1,78 @@
+package integration_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/validator"
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+// useBreachedPasswords points the server at an HIBP range directory listing password
+func useBreachedPasswords(t *testing.T, password string) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, hash[:5]), []byte(hash[5:]+":1234\n"), 0o644))
+	t.Setenv("BREACHED_PASSWORDS_PATH", dir)
+}
+
+func TestAPI_Register_RejectsBreachedPassword(t *testing.T) {
+	useBreachedPasswords(t, "Password1!")
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	req.Password, req.ConfirmPassword = "Password1!", "Password1!"
+	resp, body := postRegistrationWithKey(t, app, "", req)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &result))
+	fields := result["error"]["field_errors"].(map[string]interface{})
+	assert.Equal(t, validator.BreachedPasswordMessage, fields["password"])
+
+	// The fixture password isn't in the corpus
+	resp, _ = postRegistrationWithKey(t, app, "", testhelpers.CreateTestRegistrationRequest())
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+}
+
+func TestAPI_ResetPassword_RejectsBreachedPasswordAndKeepsToken(t *testing.T) {
+	useBreachedPasswords(t, "Password1!")
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	login := loginForTokens(t, app)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	testhelpers.InsertTestUserToken(t, pool, login.UserID, models.TokenPurposePasswordReset, "reset-token")
+
+	resp := postJSON(t, app, "/api/password/reset", models.ResetPasswordRequest{
+		Token:           "reset-token",
+		Password:        "Password1!",
+		ConfirmPassword: "Password1!",
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), validator.BreachedPasswordMessage)
+
+	resp = postJSON(t, app, "/api/password/reset", models.ResetPasswordRequest{
+		Token:           "reset-token",
+		Password:        "NewPass456$%^",
+		ConfirmPassword: "NewPass456$%^",
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,147 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+func TestAPI_Register_EmailDifferingOnlyInCaseIsTaken(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := registerTestUser(t, app)
+
+	dup := testhelpers.CreateTestRegistrationRequestWithEmail(strings.ToUpper(req.Email))
+	dup.Username = "differentuser"
+	dup.Phone = nil
+	body, _ := json.Marshal(dup)
+
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	fields := result["error"]["field_errors"].(map[string]interface{})
+	assert.Equal(t, "Email is already registered", fields["email"])
+}
+
+func TestAPI_UsernameAvailability_MatchesCanonicalForm(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := registerTestUser(t, app)
+
+	// Upper case and full-width variants of the registered name
+	for _, variant := range []string{strings.ToUpper(req.Username), "ｊｏｈｎｄｏｅ123"} {
+		httpReq := httptest.NewRequest(http.MethodGet, "/api/username-availability?username="+url.QueryEscape(variant), nil)
+		resp, err := app.Test(httpReq)
+		require.NoError(t, err)
+
+		var result map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.Equal(t, false, result["available"], variant)
+	}
+}
+
+func TestAPI_Login_IdentifierIsCaseInsensitive(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := registerTestUser(t, app)
+
+	resp := postLogin(t, app, strings.ToUpper(req.Email), req.Password)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = postLogin(t, app, strings.ToUpper(req.Username), req.Password)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAPI_UsernameAvailability_SuggestsAlternatives(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := registerTestUser(t, app)
+
+	query := url.Values{"username": {req.Username}, "first_name": {req.FirstName}, "last_name": {req.LastName}}
+	httpReq := httptest.NewRequest(http.MethodGet, "/api/username-availability?"+query.Encode(), nil)
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Available   bool     `json:"available"`
+		Suggestions []string `json:"suggestions"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.False(t, result.Available)
+	require.NotEmpty(t, result.Suggestions)
+	assert.Equal(t, "johndoe", result.Suggestions[0])
+
+	// Every suggestion is actually free
+	for _, s := range result.Suggestions {
+		httpReq := httptest.NewRequest(http.MethodGet, "/api/username-availability?username="+url.QueryEscape(s), nil)
+		resp, err := app.Test(httpReq)
+		require.NoError(t, err)
+		var check map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&check))
+		assert.Equal(t, true, check["available"], s)
+	}
+}
+
+func TestAPI_Register_LookalikeUsernameIsRejected(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	registerTestUser(t, app)
+
+	// Cyrillic "о" in place of Latin "o"
+	spoof := testhelpers.CreateTestRegistrationRequestWithUsername("jоhndoe123")
+	spoof.Email = "other@example.us"
+	spoof.Phone = nil
+	body, _ := json.Marshal(spoof)
+
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	fields := result["error"]["field_errors"].(map[string]interface{})
+	assert.Equal(t, "Username is too similar to an existing username", fields["username"])
+
+	httpReq = httptest.NewRequest(http.MethodGet, "/api/username-availability?username="+url.QueryEscape(spoof.Username), nil)
+	resp, err = app.Test(httpReq)
+	require.NoError(t, err)
+	var check map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&check))
+	assert.Equal(t, false, check["available"])
+}
+
+func TestAPI_UsernameAvailability_ReservedName(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	httpReq := httptest.NewRequest(http.MethodGet, "/api/username-availability?username=administrator", nil)
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+
+	var result map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, false, result["available"])
+	assert.Equal(t, "This username is reserved", result["message"])
+}
@@ -0,0 +1,229 @@
+package integration_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/internal/services"
+	"tyk-registration-server/internal/utils"
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+func TestUserRepository_CreateUser_ReportsUniqueViolationField(t *testing.T) {
+	setupTest(t)
+	defer cleanupTest(t)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	repo := repositories.NewUserRepository(pool, nil, repositories.UserRepositoryOptions{})
+	ctx := context.Background()
+
+	_, err := repo.CreateUser(ctx, testhelpers.CreateTestRegistrationRequest(), "hash")
+	require.NoError(t, err)
+
+	tests := map[string]func() error{
+		"email": func() error {
+			req := testhelpers.CreateTestRegistrationRequestWithUsername("otheruser")
+			req.Phone = nil
+			_, err := repo.CreateUser(ctx, req, "hash")
+			return err
+		},
+		"username": func() error {
+			req := testhelpers.CreateTestRegistrationRequestWithEmail("other@example.us")
+			req.Phone = nil
+			_, err := repo.CreateUser(ctx, req, "hash")
+			return err
+		},
+		"phone": func() error {
+			req := testhelpers.CreateTestRegistrationRequestWithEmail("other@example.us")
+			req.Username = "otheruser"
+			_, err := repo.CreateUser(ctx, req, "hash")
+			return err
+		},
+	}
+	for field, create := range tests {
+		t.Run(field, func(t *testing.T) {
+			var dup *repositories.UniqueViolationError
+			require.True(t, errors.As(create(), &dup))
+			assert.Equal(t, field, dup.Field)
+		})
+	}
+}
+
+// Without the business stage nothing checks uniqueness up front, which is what the loser of
+// a concurrent registration sees: the insert itself fails
+func TestAPI_Register_UniqueViolationIsConflict(t *testing.T) {
+	t.Setenv("VALIDATION_PIPELINE", "field,cross")
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	register := func(payload interface{}) *http.Response {
+		body, _ := json.Marshal(payload)
+		httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+		httpReq.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(httpReq)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := register(testhelpers.CreateTestRegistrationRequest())
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = register(testhelpers.CreateTestRegistrationRequestWithEmail("other@example.us"))
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, "business_error", result["error"]["code"])
+	fields := result["error"]["field_errors"].(map[string]interface{})
+	assert.Len(t, fields, 1)
+}
+
+func TestAPI_Register_PhoneDifferingOnlyInFormattingIsTaken(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	registerTestUser(t, app)
+
+	dup := testhelpers.CreateTestRegistrationRequestWithPhone("+1 (202) 555-1234")
+	dup.Email = "other@example.us"
+	dup.Username = "otheruser"
+	body, _ := json.Marshal(dup)
+
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	fields := result["error"]["field_errors"].(map[string]interface{})
+	assert.Equal(t, "Phone number is already registered", fields["phone"])
+}
+
+func TestUserRepository_CreateUser_StoresPhoneInE164(t *testing.T) {
+	setupTest(t)
+	defer cleanupTest(t)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	repo := repositories.NewUserRepository(pool, nil, repositories.UserRepositoryOptions{})
+	ctx := context.Background()
+
+	req := testhelpers.CreateTestRegistrationRequestWithPhone("+1 (202) 555-1234")
+	id, err := repo.CreateUser(ctx, req, "hash")
+	require.NoError(t, err)
+
+	user, err := repo.GetUserByID(ctx, id)
+	require.NoError(t, err)
+	require.NotNil(t, user.Phone)
+	assert.Equal(t, "+12025551234", *user.Phone)
+	require.NotNil(t, user.PhoneRegion)
+	assert.Equal(t, "US", *user.PhoneRegion)
+	require.NotNil(t, user.PhoneType)
+	assert.Equal(t, "fixed_line_or_mobile", *user.PhoneType)
+}
+
+func TestUserRepository_CanonicalizeUsers(t *testing.T) {
+	setupTest(t)
+	defer cleanupTest(t)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	ctx := context.Background()
+
+	// Stored while local parts were case-sensitive, so both addresses were accepted
+	caseSensitive, err := utils.LoadEmailRules("", false)
+	require.NoError(t, err)
+	before := repositories.NewUserRepository(pool, nil, repositories.UserRepositoryOptions{EmailRules: caseSensitive})
+	var ids []uuid.UUID
+	for _, r := range []struct{ email, username string }{
+		{"John@example.com", "johnuser"},
+		{"john@example.com", "otheruser"},
+		{"anna@example.com", "Straße"},
+	} {
+		req := testhelpers.CreateTestRegistrationRequestWithEmail(r.email)
+		req.Username, req.Phone = r.username, nil
+		id, err := before.CreateUser(ctx, req, "hash")
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	// What the SQL backfill in migration 000009 stores for the username
+	_, err = pool.Exec(ctx, `UPDATE users SET username_canonical = lower(username) WHERE id = $1`, ids[2])
+	require.NoError(t, err)
+
+	repo := repositories.NewUserRepository(pool, nil, repositories.UserRepositoryOptions{})
+	var collisions []models.CanonicalCollision
+	for after := uuid.Nil; ; {
+		last, _, c, err := repo.CanonicalizeUsers(ctx, after, 1)
+		require.NoError(t, err)
+		collisions = append(collisions, c...)
+		if last == uuid.Nil {
+			break
+		}
+		after = last
+	}
+
+	// Only one of the two can have the folded address; the other is reported
+	require.Len(t, collisions, 1)
+	assert.Equal(t, "email", collisions[0].Field)
+	assert.Contains(t, ids[:2], collisions[0].UserID)
+
+	user, err := repo.GetUserByUsername(ctx, "STRASSE")
+	require.NoError(t, err)
+	assert.Equal(t, ids[2], user.ID)
+}
+
+func TestBackfillPhoneDetails_NormalizesAndReportsCollisions(t *testing.T) {
+	setupTest(t)
+	defer cleanupTest(t)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	repo := repositories.NewUserRepository(pool, nil, repositories.UserRepositoryOptions{})
+	ctx := context.Background()
+
+	// Stored before phones were normalized: as typed, with no region or type
+	create := func(email, username, phone string) uuid.UUID {
+		req := testhelpers.CreateTestRegistrationRequestWithEmail(email)
+		req.Username, req.Phone = username, nil
+		id, err := repo.CreateUser(ctx, req, "hash")
+		require.NoError(t, err)
+		_, err = pool.Exec(ctx, `UPDATE users SET phone = $2, phone_index = $2 WHERE id = $1`, id, phone)
+		require.NoError(t, err)
+		return id
+	}
+	normalized := create("a@example.us", "usera", "+44 20 7946 0958")
+	create("b@example.us", "userb", "+1 202 555 1234")
+	create("c@example.us", "userc", "+1 (202) 555-1234")
+
+	_, backfillErr := services.BackfillPhoneDetails(ctx, repo, 1)
+	require.Error(t, backfillErr)
+	assert.Contains(t, backfillErr.Error(), "1 users")
+
+	user, err := repo.GetUserByID(ctx, normalized)
+	require.NoError(t, err)
+	assert.Equal(t, "+442079460958", *user.Phone)
+	assert.Equal(t, "GB", *user.PhoneRegion)
+
+	// Only one of the two US accounts can have the number; the other is reported as is
+	exists, err := repo.PhoneExists(ctx, "+12025551234")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	var unchanged int
+	require.NoError(t, pool.QueryRow(ctx,
+		`SELECT count(*) FROM users WHERE phone LIKE '+1 %' AND phone_type IS NOT NULL`).Scan(&unchanged))
+	assert.Equal(t, 1, unchanged)
+}
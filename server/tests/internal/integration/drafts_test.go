@@ -0,0 +1,260 @@
+package integration_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/handlers"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+func draftRequest(t *testing.T, app *fiber.App, method, path, token string, payload interface{}) *http.Response {
+	var body []byte
+	if payload != nil {
+		body, _ = json.Marshal(payload)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set(handlers.DraftTokenHeader, token)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func createDraft(t *testing.T, app *fiber.App) models.RegistrationDraft {
+	resp := draftRequest(t, app, http.MethodPost, "/api/register/drafts", "", nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var draft models.RegistrationDraft
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&draft))
+	require.NotEmpty(t, draft.Token)
+	return draft
+}
+
+// draftSteps splits the test fixture into the client's three steps
+func draftSteps(req *models.RegistrationRequest) map[string]map[string]interface{} {
+	return map[string]map[string]interface{}{
+		"personal": {
+			"first_name": req.FirstName,
+			"last_name":  req.LastName,
+			"email":      req.Email,
+			"phone":      req.Phone,
+		},
+		"address": {
+			"street":      req.Street,
+			"city":        req.City,
+			"state":       req.State,
+			"country":     req.Country,
+			"country_iso": req.CountryISO,
+		},
+		"account": {
+			"username":         req.Username,
+			"password":         req.Password,
+			"confirm_password": req.ConfirmPassword,
+			"terms_accepted":   req.TermsAccepted,
+			"newsletter":       req.Newsletter,
+		},
+	}
+}
+
+func TestAPI_Drafts_ResumeAndFinalize(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	draft := createDraft(t, app)
+	base := "/api/register/drafts/" + draft.ID.String()
+
+	steps := draftSteps(req)
+	for _, step := range []string{"personal", "address", "account"} {
+		resp := draftRequest(t, app, http.MethodPut, base+"/steps/"+step, draft.Token, steps[step])
+		require.Equal(t, http.StatusOK, resp.StatusCode, step)
+	}
+
+	// Another device picks the draft up
+	resp := draftRequest(t, app, http.MethodGet, base, draft.Token, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var saved models.RegistrationDraft
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&saved))
+	assert.ElementsMatch(t, []string{"personal", "address", "account"}, saved.CompletedSteps)
+	assert.JSONEq(t, `"`+req.Username+`"`, string(saved.Data["username"]))
+	assert.NotContains(t, saved.Data, "password")
+	assert.NotContains(t, saved.Data, "confirm_password")
+
+	resp = draftRequest(t, app, http.MethodPost, base+"/finalize", draft.Token, models.FinalizeDraftRequest{
+		Password:        req.Password,
+		ConfirmPassword: req.ConfirmPassword,
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var registered models.RegistrationResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&registered))
+	assert.NotEmpty(t, registered.UserID)
+
+	// The draft is consumed
+	resp = draftRequest(t, app, http.MethodGet, base, draft.Token, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = postLogin(t, app, req.Username, req.Password)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAPI_Drafts_PasswordNeverStored(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	draft := createDraft(t, app)
+	resp := draftRequest(t, app, http.MethodPut, "/api/register/drafts/"+draft.ID.String()+"/steps/account", draft.Token, draftSteps(req)["account"])
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	var data string
+	err := pool.QueryRow(context.Background(), "SELECT data::text FROM registration_drafts WHERE id = $1", draft.ID.String()).Scan(&data)
+	require.NoError(t, err)
+	assert.NotContains(t, data, "password")
+	assert.NotContains(t, data, req.Password)
+}
+
+func TestAPI_Drafts_StepValidationErrors(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	draft := createDraft(t, app)
+	base := "/api/register/drafts/" + draft.ID.String()
+
+	resp := draftRequest(t, app, http.MethodPut, base+"/steps/personal", draft.Token, map[string]interface{}{
+		"first_name": "John",
+		"last_name":  "Doe",
+		"email":      "not-an-email",
+	})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Contains(t, result["error"]["field_errors"], "email")
+
+	// Nothing was saved
+	resp = draftRequest(t, app, http.MethodGet, base, draft.Token, nil)
+	var saved models.RegistrationDraft
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&saved))
+	assert.Empty(t, saved.Data)
+	assert.Empty(t, saved.CompletedSteps)
+
+	resp = draftRequest(t, app, http.MethodPut, base+"/steps/payment", draft.Token, map[string]interface{}{})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAPI_Drafts_FinalizeRunsFullValidation(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	draft := createDraft(t, app)
+	base := "/api/register/drafts/" + draft.ID.String()
+
+	// Only the first step is saved
+	resp := draftRequest(t, app, http.MethodPut, base+"/steps/personal", draft.Token, draftSteps(req)["personal"])
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = draftRequest(t, app, http.MethodPost, base+"/finalize", draft.Token, models.FinalizeDraftRequest{
+		Password:        req.Password,
+		ConfirmPassword: req.ConfirmPassword,
+	})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Contains(t, result["error"]["field_errors"], "username")
+	assert.Contains(t, result["error"]["field_errors"], "street")
+
+	// The draft survives a failed finalize
+	resp = draftRequest(t, app, http.MethodGet, base, draft.Token, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAPI_Drafts_Expired(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	draft := createDraft(t, app)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	_, err := pool.Exec(context.Background(),
+		"UPDATE registration_drafts SET expires_at = NOW() - INTERVAL '1 minute' WHERE id = $1", draft.ID.String())
+	require.NoError(t, err)
+
+	resp := draftRequest(t, app, http.MethodGet, "/api/register/drafts/"+draft.ID.String(), draft.Token, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = draftRequest(t, app, http.MethodPut, "/api/register/drafts/"+draft.ID.String()+"/steps/personal", draft.Token, map[string]interface{}{
+		"first_name": "John",
+	})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestAPI_Drafts_RequireToken(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	draft := createDraft(t, app)
+	base := "/api/register/drafts/" + draft.ID.String()
+	personal := draftSteps(testhelpers.CreateTestRegistrationRequest())["personal"]
+
+	// The id alone is not enough to read or change the draft
+	for _, token := range []string{"", "wrong-token"} {
+		resp := draftRequest(t, app, http.MethodGet, base, token, nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		resp = draftRequest(t, app, http.MethodPut, base+"/steps/personal", token, personal)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+
+	// The token is only shown on creation
+	resp := draftRequest(t, app, http.MethodGet, base, draft.Token, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var saved models.RegistrationDraft
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&saved))
+	assert.Empty(t, saved.Token)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	var tokenHash string
+	err := pool.QueryRow(context.Background(), "SELECT token_hash FROM registration_drafts WHERE id = $1", draft.ID.String()).Scan(&tokenHash)
+	require.NoError(t, err)
+	assert.NotEqual(t, draft.Token, tokenHash)
+}
+
+func TestAPI_Drafts_FinalizeOnce(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	draft := createDraft(t, app)
+	base := "/api/register/drafts/" + draft.ID.String()
+	steps := draftSteps(req)
+	for _, step := range []string{"personal", "address", "account"} {
+		resp := draftRequest(t, app, http.MethodPut, base+"/steps/"+step, draft.Token, steps[step])
+		require.Equal(t, http.StatusOK, resp.StatusCode, step)
+	}
+
+	password := models.FinalizeDraftRequest{Password: req.Password, ConfirmPassword: req.ConfirmPassword}
+	resp := draftRequest(t, app, http.MethodPost, base+"/finalize", draft.Token, password)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp = draftRequest(t, app, http.MethodPost, base+"/finalize", draft.Token, password)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	var users int
+	require.NoError(t, pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM users").Scan(&users))
+	assert.Equal(t, 1, users)
+}
@@ -0,0 +1,211 @@
+package integration_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/router"
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+type loginCredentials struct {
+	cookie      *http.Cookie
+	accessToken string
+}
+
+// loginTestUser registers and signs in the fixture user
+func loginTestUser(t *testing.T, app *fiber.App) (*models.RegistrationRequest, loginCredentials) {
+	req := registerTestUser(t, app)
+	resp := postLogin(t, app, req.Username, req.Password)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var creds loginCredentials
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "session_id" {
+			creds.cookie = cookie
+		}
+	}
+	require.NotNil(t, creds.cookie)
+	var login models.LoginResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&login))
+	creds.accessToken = login.AccessToken
+	return req, creds
+}
+
+func getExport(t *testing.T, app *fiber.App, query string, authorize func(*http.Request)) *http.Response {
+	httpReq := httptest.NewRequest(http.MethodGet, "/api/me/export"+query, nil)
+	if authorize != nil {
+		authorize(httpReq)
+	}
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	return resp
+}
+
+func (c loginCredentials) withCookie(r *http.Request) {
+	r.AddCookie(c.cookie)
+}
+
+func (c loginCredentials) withBearer(r *http.Request) {
+	r.Header.Set(fiber.HeaderAuthorization, "Bearer "+c.accessToken)
+}
+
+func TestAPI_Export_RequiresLogin(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	resp := getExport(t, app, "", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = getExport(t, app, "", func(r *http.Request) {
+		r.AddCookie(&http.Cookie{Name: "session_id", Value: "not-a-session"})
+	})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestAPI_Export_JSON(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+	req, creds := loginTestUser(t, app)
+
+	for name, authorize := range map[string]func(*http.Request){"cookie": creds.withCookie, "bearer": creds.withBearer} {
+		t.Run(name, func(t *testing.T) {
+			resp := getExport(t, app, "", authorize)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, fiber.MIMEApplicationJSON, resp.Header.Get(fiber.HeaderContentType))
+			assert.Contains(t, resp.Header.Get(fiber.HeaderContentDisposition), "attachment; filename=\"data-export-")
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.NotContains(t, string(body), "password")
+
+			var bundle models.ExportBundle
+			require.NoError(t, json.Unmarshal(body, &bundle))
+			assert.Equal(t, req.Email, bundle.User.Email)
+			assert.Equal(t, req.Username, bundle.User.Username)
+			assert.Len(t, bundle.Consents, 2)
+			assert.Len(t, bundle.Sessions, 1)
+			require.NotEmpty(t, bundle.AuditEvents)
+			assert.Equal(t, models.AuditLogin, bundle.AuditEvents[0].Type)
+		})
+	}
+
+	// Each download is itself audited
+	resp := getExport(t, app, "", creds.withCookie)
+	var bundle models.ExportBundle
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&bundle))
+	last := bundle.AuditEvents[len(bundle.AuditEvents)-1]
+	assert.Equal(t, models.AuditDataExportDownloaded, last.Type)
+	assert.Equal(t, "json", last.Details["format"])
+}
+
+func TestAPI_Export_ZIP(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+	req, creds := loginTestUser(t, app)
+
+	resp := getExport(t, app, "?format=zip", creds.withCookie)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/zip", resp.Header.Get(fiber.HeaderContentType))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	names := []string{}
+	for _, f := range archive.File {
+		names = append(names, f.Name)
+		if f.Name == "user.json" {
+			r, err := f.Open()
+			require.NoError(t, err)
+			var user models.ExportUser
+			require.NoError(t, json.NewDecoder(r).Decode(&user))
+			assert.Equal(t, req.Email, user.Email)
+		}
+	}
+	assert.ElementsMatch(t, []string{"user.json", "consents.json", "sessions.json", "audit_events.json"}, names)
+}
+
+func TestAPI_Export_InvalidFormat(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+	_, creds := loginTestUser(t, app)
+
+	resp := getExport(t, app, "?format=csv", creds.withCookie)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAPI_Export_BackgroundJob(t *testing.T) {
+	usePIIEncryption(t)
+	t.Setenv("EXPORT_INLINE_MAX_RECORDS", "0")
+	cfg := testhelpers.LoadTestConfig(t)
+	srv := router.NewServer(cfg)
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	testhelpers.CleanupUsersTable(t, pool)
+	testhelpers.CleanupOutboxTable(t, pool)
+	defer cleanupPIITest(t)
+
+	req, creds := loginTestUser(t, srv.App)
+
+	resp := getExport(t, srv.App, "", creds.withCookie)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get(fiber.HeaderRetryAfter))
+	var pending models.DataExportPendingResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&pending))
+	assert.Equal(t, models.ExportStatusPending, pending.Status)
+
+	// Polling doesn't queue another job
+	resp = getExport(t, srv.App, "", creds.withCookie)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	var again models.DataExportPendingResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&again))
+	assert.Equal(t, pending.ExportID, again.ExportID)
+
+	_, err := srv.Dispatcher.ProcessBatch(context.Background())
+	require.NoError(t, err)
+
+	var payload []byte
+	var encrypted bool
+	err = pool.QueryRow(context.Background(),
+		`SELECT payload, pii_key_id IS NOT NULL FROM data_exports WHERE id = $1`, pending.ExportID).
+		Scan(&payload, &encrypted)
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+	assert.NotContains(t, string(payload), req.Email)
+
+	resp = getExport(t, srv.App, "", creds.withCookie)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var bundle models.ExportBundle
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&bundle))
+	assert.Equal(t, req.Email, bundle.User.Email)
+
+	// Downloading doesn't make the export out of date
+	resp = getExport(t, srv.App, "", creds.withCookie)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// A new session does: the old export is dropped and another one queued
+	resp = postLogin(t, srv.App, req.Username, req.Password)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = getExport(t, srv.App, "", creds.withCookie)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	var requeued models.DataExportPendingResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&requeued))
+	assert.NotEqual(t, pending.ExportID, requeued.ExportID)
+
+	var old int
+	require.NoError(t, pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM data_exports WHERE id = $1`, pending.ExportID).Scan(&old))
+	assert.Zero(t, old)
+}
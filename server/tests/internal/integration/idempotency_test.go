@@ -0,0 +1,89 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+func postRegistrationWithKey(t *testing.T, app *fiber.App, key string, payload interface{}) (*http.Response, []byte) {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, respBody
+}
+
+func TestAPI_Register_IdempotentRetryReplaysResponse(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+
+	first, firstBody := postRegistrationWithKey(t, app, "retry-key-1", req)
+	require.Equal(t, http.StatusCreated, first.StatusCode)
+	assert.Empty(t, first.Header.Get("Idempotent-Replayed"))
+
+	retry, retryBody := postRegistrationWithKey(t, app, "retry-key-1", req)
+	assert.Equal(t, http.StatusCreated, retry.StatusCode)
+	assert.Equal(t, "true", retry.Header.Get("Idempotent-Replayed"))
+	assert.JSONEq(t, string(firstBody), string(retryBody))
+}
+
+func TestAPI_Register_IdempotencyKeyReusedWithDifferentBody(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	resp, _ := postRegistrationWithKey(t, app, "retry-key-2", req)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	other := testhelpers.CreateTestRegistrationRequestWithEmail("other@example.com")
+	resp, body := postRegistrationWithKey(t, app, "retry-key-2", other)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &result))
+	assert.Equal(t, "business_error", result["error"]["code"])
+}
+
+func TestAPI_Register_FailedResponseIsNotStored(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	invalid := testhelpers.CreateTestRegistrationRequestWithEmail("not-an-email")
+	resp, _ := postRegistrationWithKey(t, app, "retry-key-3", invalid)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// The key is released, so the corrected request can reuse it
+	resp, _ = postRegistrationWithKey(t, app, "retry-key-3", testhelpers.CreateTestRegistrationRequest())
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+}
+
+func TestAPI_Register_WithoutIdempotencyKey(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	resp, _ := postRegistrationWithKey(t, app, "", req)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// Without a key a retry is a new registration and hits the uniqueness checks
+	resp, _ = postRegistrationWithKey(t, app, "", req)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
@@ -0,0 +1,190 @@
+package integration_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/router"
+	"tyk-registration-server/internal/validator"
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+func postJSON(t *testing.T, app *fiber.App, path string, payload interface{}) *http.Response {
+	body, _ := json.Marshal(payload)
+	httpReq := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestAPI_ForgotPassword_AlwaysAccepted(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := registerTestUser(t, app)
+
+	known := postJSON(t, app, "/api/password/forgot", models.ForgotPasswordRequest{Email: req.Email})
+	unknown := postJSON(t, app, "/api/password/forgot", models.ForgotPasswordRequest{Email: "nobody@example.us"})
+
+	assert.Equal(t, http.StatusAccepted, known.StatusCode)
+	assert.Equal(t, http.StatusAccepted, unknown.StatusCode)
+}
+
+func TestAPI_ForgotPassword_InvalidEmail(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	resp := postJSON(t, app, "/api/password/forgot", models.ForgotPasswordRequest{Email: "not-an-email"})
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+}
+
+func TestAPI_ForgotPassword_SendsResetThroughOutbox(t *testing.T) {
+	srv := router.NewServer(testhelpers.LoadTestConfig(t))
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	testhelpers.CleanupUsersTable(t, pool)
+	testhelpers.CleanupOutboxTable(t, pool)
+	defer cleanupTest(t)
+
+	req := registerTestUser(t, srv.App)
+	resp := postJSON(t, srv.App, "/api/password/forgot", models.ForgotPasswordRequest{Email: req.Email})
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	countTokens := func() int {
+		var n int
+		err := pool.QueryRow(context.Background(),
+			`SELECT COUNT(*) FROM user_tokens WHERE purpose = $1`, models.TokenPurposePasswordReset).Scan(&n)
+		require.NoError(t, err)
+		return n
+	}
+	assert.Zero(t, countTokens(), "the token is issued by the outbox, not the request")
+
+	_, err := srv.Dispatcher.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, countTokens())
+}
+
+func TestAPI_ResetPassword_Success(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	login := loginForTokens(t, app)
+	req := testhelpers.CreateTestRegistrationRequest()
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	testhelpers.InsertTestUserToken(t, pool, login.UserID, models.TokenPurposePasswordReset, "reset-token")
+
+	resp := postJSON(t, app, "/api/password/reset", models.ResetPasswordRequest{
+		Token:           "reset-token",
+		Password:        "NewPass456$%^",
+		ConfirmPassword: "NewPass456$%^",
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Sessions were deleted with the password change
+	var sessions int
+	require.NoError(t, pool.QueryRow(context.Background(),
+		"SELECT COUNT(*) FROM sessions WHERE user_id = $1", login.UserID).Scan(&sessions))
+	assert.Zero(t, sessions)
+
+	// Old password no longer works, new one does
+	assert.Equal(t, http.StatusUnauthorized, postLogin(t, app, req.Username, req.Password).StatusCode)
+	assert.Equal(t, http.StatusOK, postLogin(t, app, req.Username, "NewPass456$%^").StatusCode)
+
+	// Existing refresh tokens were revoked
+	assert.Equal(t, http.StatusUnauthorized, postRefresh(t, app, login.RefreshToken).StatusCode)
+
+	// The token is single-use
+	resp = postJSON(t, app, "/api/password/reset", models.ResetPasswordRequest{
+		Token:           "reset-token",
+		Password:        "Another789&*(",
+		ConfirmPassword: "Another789&*(",
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAPI_ResetPassword_WeakPasswordKeepsToken(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	login := loginForTokens(t, app)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	testhelpers.InsertTestUserToken(t, pool, login.UserID, models.TokenPurposePasswordReset, "reset-token")
+
+	resp := postJSON(t, app, "/api/password/reset", models.ResetPasswordRequest{
+		Token:           "reset-token",
+		Password:        "weak",
+		ConfirmPassword: "weak",
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// The message is the registration rule's
+	rules, err := validator.NewRuleEngine("", models.RegistrationRequest{})
+	require.NoError(t, err)
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, map[string]interface{}{
+		"password": rules.ValidateField(&models.RegistrationRequest{Password: "weak"}, "password"),
+	}, result["error"]["field_errors"])
+
+	resp = postJSON(t, app, "/api/password/reset", models.ResetPasswordRequest{
+		Token:           "reset-token",
+		Password:        "NewPass456$%^",
+		ConfirmPassword: "NewPass456$%^",
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAPI_ResetPassword_InvalidToken(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	resp := postJSON(t, app, "/api/password/reset", models.ResetPasswordRequest{
+		Token:           "bogus",
+		Password:        "NewPass456$%^",
+		ConfirmPassword: "NewPass456$%^",
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAPI_ResetPassword_GuessablePasswordKeepsToken(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	login := loginForTokens(t, app)
+	req := testhelpers.CreateTestRegistrationRequest()
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	testhelpers.InsertTestUserToken(t, pool, login.UserID, models.TokenPurposePasswordReset, "reset-token")
+
+	// Long enough, but a common password and the user's own username
+	for _, password := range []string{"password123", req.Username + "1"} {
+		resp := postJSON(t, app, "/api/password/reset", models.ResetPasswordRequest{
+			Token:           "reset-token",
+			Password:        password,
+			ConfirmPassword: password,
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, password)
+	}
+
+	resp := postJSON(t, app, "/api/password/reset", models.ResetPasswordRequest{
+		Token:           "reset-token",
+		Password:        "correct horse battery staple",
+		ConfirmPassword: "correct horse battery staple",
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
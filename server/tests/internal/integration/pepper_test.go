@@ -0,0 +1,61 @@
+package integration_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/router"
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+func testPepperKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func storedPasswordHash(t *testing.T, username string) string {
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	var hash string
+	err := pool.QueryRow(context.Background(), `SELECT password_hash FROM users WHERE username = $1`, username).Scan(&hash)
+	require.NoError(t, err)
+	return hash
+}
+
+func TestAPI_PasswordPepper_RotatesOnLogin(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", testAdminKey)
+	t.Setenv("PASSWORD_PEPPER_KEYS", "k1:"+testPepperKey('a'))
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := registerTestUser(t, app)
+	assert.Contains(t, storedPasswordHash(t, req.Username), ",kid=k1$")
+
+	// Rotate: k2 peppers new hashes, k1 stays to verify existing ones
+	t.Setenv("PASSWORD_PEPPER_KEYS", "k1:"+testPepperKey('a')+",k2:"+testPepperKey('b'))
+	t.Setenv("PASSWORD_PEPPER_CURRENT", "k2")
+	app = router.New(testhelpers.LoadTestConfig(t))
+
+	resp := adminRequest(t, app, http.MethodGet, "/api/admin/password-keys", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var report models.PasswordKeyReport
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.Equal(t, "k2", report.CurrentKeyID)
+	assert.Equal(t, []models.PasswordKeyUsage{{Algorithm: "argon2id", KeyID: "k1", Users: 1, Current: false}}, report.Hashes)
+
+	resp = postLogin(t, app, req.Username, req.Password)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, storedPasswordHash(t, req.Username), ",kid=k2$")
+
+	resp = adminRequest(t, app, http.MethodGet, "/api/admin/password-keys", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.Equal(t, []models.PasswordKeyUsage{{Algorithm: "argon2id", KeyID: "k2", Users: 1, Current: true}}, report.Hashes)
+}
@@ -0,0 +1,235 @@
+package integration_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/pii"
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+// usePIIEncryption points PII_MASTER_KEY_FILE at a fresh master key
+func usePIIEncryption(t *testing.T) string {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "pii-master.key")
+	require.NoError(t, os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)), 0o600))
+	t.Setenv("PII_MASTER_KEY_FILE", path)
+	return path
+}
+
+// cleanupPIITest also drops the PII keys, which a later test's master key couldn't unwrap
+func cleanupPIITest(t *testing.T) {
+	cleanupTest(t)
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	testhelpers.CleanupPIIKeysTable(t, pool)
+}
+
+type storedPII struct {
+	FirstName, LastName, Email, Street, City, State string
+	Phone, EmailCanonical, PhoneIndex               *string
+	Encrypted                                       bool
+}
+
+func readStoredPII(t *testing.T, username string) storedPII {
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	var s storedPII
+	err := pool.QueryRow(context.Background(), `
+		SELECT first_name, last_name, email, street, city, state, phone, email_canonical, phone_index,
+		       pii_key_id IS NOT NULL
+		FROM users WHERE username = $1`, username).
+		Scan(&s.FirstName, &s.LastName, &s.Email, &s.Street, &s.City, &s.State,
+			&s.Phone, &s.EmailCanonical, &s.PhoneIndex, &s.Encrypted)
+	require.NoError(t, err)
+	return s
+}
+
+func assertEncrypted(t *testing.T, s storedPII) {
+	assert.True(t, s.Encrypted)
+	assert.NotEqual(t, "John", s.FirstName)
+	assert.NotEqual(t, "Doe", s.LastName)
+	assert.NotContains(t, s.Email, "example")
+	assert.NotContains(t, s.Street, "Main")
+	assert.NotEqual(t, "New York", s.City)
+	assert.NotEqual(t, "NY", s.State)
+	require.NotNil(t, s.Phone)
+	assert.NotContains(t, *s.Phone, "2025551234")
+	assert.NotContains(t, *s.EmailCanonical, "example")
+	assert.NotContains(t, *s.PhoneIndex, "2025551234")
+}
+
+func TestAPI_PII_EncryptedAtRest(t *testing.T) {
+	usePIIEncryption(t)
+	app := setupTest(t)
+	defer cleanupPIITest(t)
+
+	req := registerTestUser(t, app)
+	assertEncrypted(t, readStoredPII(t, req.Username))
+
+	// Lookups by email go through the blind index
+	resp := postLogin(t, app, req.Email, req.Password)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Uniqueness still holds for email and phone
+	dupEmail := testhelpers.CreateTestRegistrationRequestWithUsername("otheruser")
+	dupEmail.Phone = nil
+	dupPhone := testhelpers.CreateTestRegistrationRequestWithEmail("other@example.us")
+	dupPhone.Username = "otheruser"
+	for field, dup := range map[string]*models.RegistrationRequest{"email": dupEmail, "phone": dupPhone} {
+		body, _ := json.Marshal(dup)
+		httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+		httpReq.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(httpReq)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode, field)
+	}
+}
+
+func TestUserRepository_EncryptPlaintextUsers(t *testing.T) {
+	setupTest(t)
+	defer cleanupPIITest(t)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	ctx := context.Background()
+
+	// A user stored before encryption was enabled
+	plain := repositories.NewUserRepository(pool, nil, repositories.UserRepositoryOptions{})
+	fixture := testhelpers.CreateTestRegistrationRequest()
+	_, err := plain.CreateUser(ctx, fixture, "hash")
+	require.NoError(t, err)
+	assert.False(t, readStoredPII(t, fixture.Username).Encrypted)
+
+	cipher, err := pii.Load(ctx, usePIIEncryption(t), repositories.NewPIIKeyRepository(pool))
+	require.NoError(t, err)
+	encrypted := repositories.NewUserRepository(pool, cipher, repositories.UserRepositoryOptions{})
+
+	// Plaintext rows are still found before the migration reaches them
+	exists, err := encrypted.EmailExists(ctx, fixture.Email)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = encrypted.PhoneExists(ctx, *fixture.Phone)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	n, err := encrypted.EncryptPlaintextUsers(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = encrypted.EncryptPlaintextUsers(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "encrypted rows are skipped")
+
+	assertEncrypted(t, readStoredPII(t, fixture.Username))
+
+	user, err := encrypted.GetUserByEmail(ctx, fixture.Email)
+	require.NoError(t, err)
+	assert.Equal(t, fixture.FirstName, user.FirstName)
+	assert.Equal(t, fixture.LastName, user.LastName)
+	assert.Equal(t, fixture.Email, user.Email)
+	assert.Equal(t, fixture.Street, user.Street)
+	assert.Equal(t, fixture.City, user.City)
+	assert.Equal(t, fixture.State, user.State)
+	require.NotNil(t, user.Phone)
+	assert.Equal(t, *fixture.Phone, *user.Phone)
+
+	exists, err = encrypted.PhoneExists(ctx, *fixture.Phone)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestWebhookRepository_EncryptsSecrets(t *testing.T) {
+	setupTest(t)
+	defer cleanupPIITest(t)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	defer testhelpers.CleanupWebhooksTable(t, pool)
+	ctx := context.Background()
+
+	readSecret := func(id uuid.UUID) (string, bool) {
+		var secret string
+		var encrypted bool
+		err := pool.QueryRow(ctx, "SELECT secret, secret_key_id IS NOT NULL FROM webhook_endpoints WHERE id = $1", id).
+			Scan(&secret, &encrypted)
+		require.NoError(t, err)
+		return secret, encrypted
+	}
+
+	// An endpoint created before encryption was enabled
+	plain := repositories.NewWebhookRepository(pool, nil)
+	old, err := plain.CreateEndpoint(ctx, "https://crm.example.com/old", "whsec_old", []string{models.EventUserRegistered})
+	require.NoError(t, err)
+	stored, encrypted := readSecret(old.ID)
+	assert.Equal(t, "whsec_old", stored)
+	assert.False(t, encrypted)
+
+	cipher, err := pii.Load(ctx, usePIIEncryption(t), repositories.NewPIIKeyRepository(pool))
+	require.NoError(t, err)
+	webhooks := repositories.NewWebhookRepository(pool, cipher)
+
+	created, err := webhooks.CreateEndpoint(ctx, "https://crm.example.com/new", "whsec_new", []string{models.EventUserRegistered})
+	require.NoError(t, err)
+	assert.Equal(t, "whsec_new", created.Secret)
+	stored, encrypted = readSecret(created.ID)
+	assert.NotContains(t, stored, "whsec_new")
+	assert.True(t, encrypted)
+
+	n, err := webhooks.EncryptPlaintextSecrets(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	stored, encrypted = readSecret(old.ID)
+	assert.NotContains(t, stored, "whsec_old")
+	assert.True(t, encrypted)
+
+	endpoint, err := webhooks.GetEndpoint(ctx, old.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "whsec_old", endpoint.Secret)
+}
+
+func TestDraftRepository_EncryptsData(t *testing.T) {
+	setupTest(t)
+	defer cleanupPIITest(t)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	ctx := context.Background()
+
+	cipher, err := pii.Load(ctx, usePIIEncryption(t), repositories.NewPIIKeyRepository(pool))
+	require.NoError(t, err)
+	drafts := repositories.NewDraftRepository(pool, cipher, nil)
+
+	draft, err := drafts.CreateDraft(ctx, "token-hash", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	draft.Data = map[string]json.RawMessage{"email": json.RawMessage(`"john.doe@example.us"`)}
+	draft.CompletedSteps = []string{"personal"}
+	_, err = drafts.UpdateDraft(ctx, draft)
+	require.NoError(t, err)
+
+	var data, sealed []byte
+	err = pool.QueryRow(ctx, "SELECT data::text, sealed_data FROM registration_drafts WHERE id = $1", draft.ID).Scan(&data, &sealed)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "john.doe")
+	assert.NotContains(t, string(sealed), "john.doe")
+
+	got, err := drafts.GetDraft(ctx, draft.ID, "token-hash")
+	require.NoError(t, err)
+	assert.JSONEq(t, `"john.doe@example.us"`, string(got.Data["email"]))
+}
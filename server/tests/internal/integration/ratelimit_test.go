@@ -0,0 +1,83 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+func TestAPI_UsernameAvailability_RateLimited(t *testing.T) {
+	t.Setenv("RATE_LIMIT_USERNAME_AVAILABILITY", "2/1m")
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/username-availability?username=availableuser", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/username-availability?username=availableuser", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, "rate_limited", result["error"]["code"])
+}
+
+func TestAPI_RateLimitBudgetsAreSeparatePerRoute(t *testing.T) {
+	t.Setenv("RATE_LIMIT_USERNAME_AVAILABILITY", "1/1m")
+	t.Setenv("RATE_LIMIT_REGISTER", "1/1m")
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/username-availability?username=availableuser", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Spending the username budget leaves registration untouched
+	resp, _ = postRegistrationWithKey(t, app, "", testhelpers.CreateTestRegistrationRequest())
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("RateLimit-Limit"))
+
+	resp, _ = postRegistrationWithKey(t, app, "", testhelpers.CreateTestRegistrationRequestWithEmail("second@example.com"))
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+func TestAPI_Login_RateLimited(t *testing.T) {
+	t.Setenv("RATE_LIMIT_LOGIN", "2/1m")
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	for i := 0; i < 2; i++ {
+		resp := postLogin(t, app, "nobody", "wrong-password")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+	resp := postLogin(t, app, "nobody", "wrong-password")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+func TestAPI_RegistrationSteps_ShareOneBudget(t *testing.T) {
+	t.Setenv("RATE_LIMIT_REGISTRATION_STEPS", "2/1m")
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	personal := map[string]string{"first_name": "John", "last_name": "Doe", "email": "john.doe@example.us"}
+	resp := postStepValidation(t, app, "personal", personal)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = draftRequest(t, app, http.MethodPost, "/api/register/drafts", "", nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = postStepValidation(t, app, "personal", personal)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	resp = draftRequest(t, app, http.MethodPost, "/api/register/drafts", "", nil)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
@@ -0,0 +1,97 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/models"
+)
+
+func loginForTokens(t *testing.T, app *fiber.App) models.LoginResponse {
+	req := registerTestUser(t, app)
+	resp := postLogin(t, app, req.Username, req.Password)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result models.LoginResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	require.NotEmpty(t, result.AccessToken)
+	require.NotEmpty(t, result.RefreshToken)
+	return result
+}
+
+func postRefresh(t *testing.T, app *fiber.App, refreshToken string) *http.Response {
+	body, _ := json.Marshal(models.RefreshRequest{RefreshToken: refreshToken})
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/token/refresh", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestAPI_TokenRefresh_Rotates(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	login := loginForTokens(t, app)
+
+	resp := postRefresh(t, app, login.RefreshToken)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var pair models.TokenPair
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&pair))
+	assert.NotEmpty(t, pair.AccessToken)
+	assert.NotEqual(t, login.RefreshToken, pair.RefreshToken)
+	assert.Equal(t, "Bearer", pair.TokenType)
+}
+
+func TestAPI_TokenRefresh_ReuseRevokesFamily(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	login := loginForTokens(t, app)
+
+	resp := postRefresh(t, app, login.RefreshToken)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var rotated models.TokenPair
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rotated))
+
+	// Replaying the first token is treated as theft...
+	resp = postRefresh(t, app, login.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// ...so the legitimately rotated token stops working too
+	resp = postRefresh(t, app, rotated.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestAPI_TokenRefresh_UnknownToken(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	resp := postRefresh(t, app, "not-a-real-token")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestAPI_Logout_RevokesRefreshToken(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	login := loginForTokens(t, app)
+
+	body, _ := json.Marshal(models.LogoutRequest{RefreshToken: login.RefreshToken})
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/logout", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = postRefresh(t, app, login.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,191 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+func TestAPI_Register_CollectModeReportsAllStages(t *testing.T) {
+	t.Setenv("VALIDATION_MODE", "collect")
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	req.FirstName = ""
+	req.ConfirmPassword = "Different123!"
+	body, _ := json.Marshal(req)
+
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	fields := result["error"]["field_errors"].(map[string]interface{})
+	// Field and cross-field failures come back together
+	assert.Contains(t, fields, "first_name")
+	assert.Contains(t, fields, "confirm_password")
+}
+
+func TestAPI_Register_DisabledStageIsSkipped(t *testing.T) {
+	t.Setenv("VALIDATION_PIPELINE", "field,business")
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	// Would fail the cross-field country/TLD check
+	req := testhelpers.CreateTestRegistrationRequestWithEmail("john.doe@example.com")
+	body, _ := json.Marshal(req)
+
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+}
+
+func TestAPI_Metrics_ReportsValidationStages(t *testing.T) {
+	t.Setenv("METRICS_TOKEN", "test-metrics-token")
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	body, _ := json.Marshal(testhelpers.CreateTestRegistrationRequest())
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	metricsReq := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	metricsReq.Header.Set(fiber.HeaderAuthorization, "Bearer test-metrics-token")
+	resp, err = app.Test(metricsReq)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	out, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	for _, stage := range []string{"field", "cross", "business"} {
+		assert.Contains(t, string(out), `registration_validation_stage_total{stage="`+stage+`",outcome="pass"} 1`)
+	}
+}
+
+func postStepValidation(t *testing.T, app *fiber.App, step string, payload interface{}) *http.Response {
+	body, _ := json.Marshal(payload)
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/register/validate?step="+step, bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestAPI_ValidateStep_PersonalValid(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	resp := postStepValidation(t, app, "personal", map[string]interface{}{
+		"first_name": "John",
+		"last_name":  "Doe",
+		"email":      "john.doe@example.us",
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAPI_ValidateStep_EmailTaken(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = postStepValidation(t, app, "personal", map[string]interface{}{
+		"first_name": "Jane",
+		"last_name":  "Doe",
+		"email":      req.Email,
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, "business_error", result["error"]["code"])
+	assert.Equal(t, map[string]interface{}{"email": "Email is already registered"}, result["error"]["field_errors"])
+}
+
+func TestAPI_ValidateStep_AddressChecksEmailTLD(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	resp := postStepValidation(t, app, "address", map[string]interface{}{
+		"email":       "john.doe@example.com",
+		"street":      "123 Main St",
+		"city":        "London",
+		"state":       "England",
+		"country":     "United Kingdom",
+		"country_iso": "GB",
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Contains(t, result["error"]["field_errors"], "email")
+}
+
+func TestAPI_ValidateStep_DoesNotPersist(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	resp := postStepValidation(t, app, "account", req)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The same data still registers: nothing was stored by the step check
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+}
+
+func TestAPI_ValidateStep_UnknownStep(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	resp := postStepValidation(t, app, "payment", map[string]interface{}{})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAPI_Register_PasswordStrength(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	req.Password, req.ConfirmPassword = "Password1!", "Password1!"
+	resp, body := postRegistrationWithKey(t, app, "", req)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, string(body), "Password is too easy to guess")
+
+	// A passphrase without digits or symbols is accepted
+	req.Password, req.ConfirmPassword = "correct horse battery staple", "correct horse battery staple"
+	resp, _ = postRegistrationWithKey(t, app, "", req)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+}
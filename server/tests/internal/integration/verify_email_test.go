@@ -0,0 +1,104 @@
+package integration_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+func TestAPI_VerifyEmail_Success(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(httpReq)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var registered models.RegistrationResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&registered))
+
+	// New accounts start unverified
+	resp = postLogin(t, app, req.Username, req.Password)
+	var login models.LoginResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&login))
+	assert.False(t, login.EmailVerified)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	testhelpers.InsertTestUserToken(t, pool, registered.UserID, models.TokenPurposeEmailVerification, "known-token")
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/verify-email?token=known-token", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = postLogin(t, app, req.Username, req.Password)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&login))
+	assert.True(t, login.EmailVerified)
+
+	// The welcome email is queued with the verification rather than sent inline
+	var queued int
+	require.NoError(t, pool.QueryRow(context.Background(),
+		"SELECT COUNT(*) FROM outbox WHERE event_type = $1 AND aggregate_id = $2",
+		models.EventWelcomeRequested, registered.UserID).Scan(&queued))
+	assert.Equal(t, 1, queued)
+
+	// Tokens are single-use
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/verify-email?token=known-token", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAPI_VerifyEmail_InvalidToken(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/verify-email?token=bogus", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAPI_VerifyEmail_MissingToken(t *testing.T) {
+	app := setupTest(t)
+	defer cleanupTest(t)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/verify-email", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAPI_VerifyEmail_ResendInvalidatesEarlierLinks(t *testing.T) {
+	t.Setenv("MAIL_FILE_DIR", t.TempDir())
+	srv := setupAccountTest(t)
+	defer cleanupTest(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	resp := postRegister(t, srv.App, req)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var registered models.RegistrationResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&registered))
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	testhelpers.InsertTestUserToken(t, pool, registered.UserID, models.TokenPurposeEmailVerification, "earlier-token")
+
+	// Sending the verification email issues a new link
+	_, err := srv.Dispatcher.ProcessBatch(context.Background())
+	require.NoError(t, err)
+
+	resp, err = srv.App.Test(httptest.NewRequest(http.MethodGet, "/api/verify-email?token=earlier-token", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
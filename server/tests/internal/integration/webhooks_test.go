@@ -0,0 +1,291 @@
+package integration_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/internal/router"
+	"tyk-registration-server/internal/services"
+	"tyk-registration-server/internal/webhooks"
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+const testAdminKey = "test-admin-key"
+
+// setupWebhookTest returns the full server so tests can drive the outbox dispatcher by hand
+func setupWebhookTest(t *testing.T) *router.Server {
+	t.Setenv("ADMIN_API_KEY", testAdminKey)
+	cfg := testhelpers.LoadTestConfig(t)
+	srv := router.NewServer(cfg)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	testhelpers.CleanupUsersTable(t, pool)
+	testhelpers.CleanupOutboxTable(t, pool)
+	testhelpers.CleanupWebhooksTable(t, pool)
+
+	return srv
+}
+
+func cleanupWebhookTest(t *testing.T) {
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	testhelpers.CleanupWebhooksTable(t, pool)
+	testhelpers.CleanupUsersTable(t, pool)
+	testhelpers.CleanupOutboxTable(t, pool)
+}
+
+func adminRequest(t *testing.T, app *fiber.App, method, path string, payload interface{}) *http.Response {
+	var body io.Reader
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		require.NoError(t, err)
+		body = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Admin-Key", testAdminKey)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func createWebhook(t *testing.T, app *fiber.App, url string, events ...string) models.CreateWebhookResponse {
+	resp := adminRequest(t, app, http.MethodPost, "/api/admin/webhooks", models.CreateWebhookRequest{
+		URL:    url,
+		Events: events,
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var created models.CreateWebhookResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	return created
+}
+
+func TestAPI_AdminWebhooks_RequiresKey(t *testing.T) {
+	srv := setupWebhookTest(t)
+	defer cleanupWebhookTest(t)
+
+	resp, err := srv.App.Test(httptest.NewRequest(http.MethodGet, "/api/admin/webhooks", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/webhooks", nil)
+	req.Header.Set("X-Admin-Key", "wrong")
+	resp, err = srv.App.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestAPI_AdminWebhooks_CreateListDelete(t *testing.T) {
+	srv := setupWebhookTest(t)
+	defer cleanupWebhookTest(t)
+
+	created := createWebhook(t, srv.App, "https://crm.example.com/hooks", models.EventUserRegistered)
+	assert.NotEmpty(t, created.Secret)
+	assert.True(t, created.Active)
+
+	resp := adminRequest(t, srv.App, http.MethodGet, "/api/admin/webhooks", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var list map[string][]map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	require.Len(t, list["webhooks"], 1)
+	assert.Equal(t, created.ID.String(), list["webhooks"][0]["id"])
+	// The secret is only returned on creation
+	assert.NotContains(t, list["webhooks"][0], "secret")
+
+	resp = adminRequest(t, srv.App, http.MethodDelete, "/api/admin/webhooks/"+created.ID.String(), nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp = adminRequest(t, srv.App, http.MethodDelete, "/api/admin/webhooks/"+created.ID.String(), nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestAPI_AdminWebhooks_ValidationErrors(t *testing.T) {
+	srv := setupWebhookTest(t)
+	defer cleanupWebhookTest(t)
+
+	resp := adminRequest(t, srv.App, http.MethodPost, "/api/admin/webhooks", models.CreateWebhookRequest{
+		URL:    "ftp://crm.example.com",
+		Events: []string{"user.deleted_forever"},
+	})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	fields := result["error"]["field_errors"].(map[string]interface{})
+	assert.Contains(t, fields, "url")
+	assert.Contains(t, fields, "events")
+}
+
+func TestAPI_Webhooks_DeliversSignedEvents(t *testing.T) {
+	srv := setupWebhookTest(t)
+	defer cleanupWebhookTest(t)
+
+	type received struct {
+		headers http.Header
+		body    []byte
+	}
+	var mu sync.Mutex
+	var got []received
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		got = append(got, received{headers: r.Header.Clone(), body: body})
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	created := createWebhook(t, srv.App, receiver.URL, models.EventUserRegistered, models.EventUserNewsletterSubscribed)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	req.Newsletter = true
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := srv.App.Test(httpReq)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// First pass fans the user events out, second pass delivers them
+	ctx := context.Background()
+	_, err = srv.Dispatcher.ProcessBatch(ctx)
+	require.NoError(t, err)
+	_, err = srv.Dispatcher.ProcessBatch(ctx)
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, got, 2)
+
+	types := map[string]bool{}
+	for _, r := range got {
+		assert.NoError(t, webhooks.Verify(created.Secret, r.headers.Get(webhooks.HeaderSignature),
+			r.headers.Get(webhooks.HeaderTimestamp), r.body, time.Now(), time.Minute))
+
+		var event models.WebhookEvent
+		require.NoError(t, json.Unmarshal(r.body, &event))
+		assert.Equal(t, event.ID.String(), r.headers.Get(webhooks.HeaderID))
+		types[event.Type] = true
+
+		// Loaded from the user when sent
+		var data models.UserWebhookData
+		require.NoError(t, json.Unmarshal(event.Data, &data))
+		assert.Equal(t, req.Email, data.Email)
+		assert.Equal(t, req.FirstName, data.FirstName)
+	}
+	assert.True(t, types[models.EventUserRegistered])
+	assert.True(t, types[models.EventUserNewsletterSubscribed])
+
+	// The outbox itself only ever held the user's ID
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	rows, err := pool.Query(ctx, "SELECT payload::text FROM outbox")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var payload string
+		require.NoError(t, rows.Scan(&payload))
+		assert.NotContains(t, payload, req.Email)
+		assert.NotContains(t, payload, req.FirstName)
+	}
+	require.NoError(t, rows.Err())
+
+	resp = adminRequest(t, srv.App, http.MethodGet, "/api/admin/webhooks/"+created.ID.String()+"/deliveries", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var deliveries map[string][]models.WebhookDelivery
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&deliveries))
+	require.Len(t, deliveries["deliveries"], 2)
+	for _, d := range deliveries["deliveries"] {
+		assert.True(t, d.Success)
+		assert.Equal(t, 1, d.Attempt)
+		require.NotNil(t, d.StatusCode)
+		assert.Equal(t, http.StatusOK, *d.StatusCode)
+	}
+}
+
+func TestAPI_Webhooks_FailedDeliveryIsLoggedAndRetried(t *testing.T) {
+	srv := setupWebhookTest(t)
+	defer cleanupWebhookTest(t)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	created := createWebhook(t, srv.App, receiver.URL, models.EventUserRegistered)
+
+	body, _ := json.Marshal(testhelpers.CreateTestRegistrationRequest())
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := srv.App.Test(httpReq)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	ctx := context.Background()
+	_, err = srv.Dispatcher.ProcessBatch(ctx)
+	require.NoError(t, err)
+	_, err = srv.Dispatcher.ProcessBatch(ctx)
+	require.NoError(t, err)
+
+	resp = adminRequest(t, srv.App, http.MethodGet, "/api/admin/webhooks/"+created.ID.String()+"/deliveries", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var deliveries map[string][]models.WebhookDelivery
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&deliveries))
+	require.Len(t, deliveries["deliveries"], 1)
+	assert.False(t, deliveries["deliveries"][0].Success)
+	assert.NotNil(t, deliveries["deliveries"][0].Error)
+
+	// The delivery stays in the outbox with a backoff instead of being dropped
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	var status string
+	var attempts int
+	err = pool.QueryRow(ctx,
+		"SELECT status, attempts FROM outbox WHERE event_type = $1", models.EventWebhookDelivery).Scan(&status, &attempts)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", status)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestWebhookService_FanOutRetryQueuesEachDeliveryOnce(t *testing.T) {
+	setupWebhookTest(t)
+	defer cleanupWebhookTest(t)
+
+	pool := testhelpers.GetTestDBPool(t)
+	defer pool.Close()
+	ctx := context.Background()
+
+	webhookRepo := repositories.NewWebhookRepository(pool, nil)
+	_, err := webhookRepo.CreateEndpoint(ctx, "https://crm.example.com/hooks", "whsec_test", []string{models.EventUserRegistered})
+	require.NoError(t, err)
+	svc := services.NewWebhookService(webhookRepo, repositories.NewOutboxRepository(pool),
+		repositories.NewUserRepository(pool, nil, repositories.UserRepositoryOptions{}), webhooks.NewSender(time.Second))
+
+	event, err := models.NewOutboxEvent(models.EventUserRegistered, uuid.New(), models.UserRegisteredPayload{})
+	require.NoError(t, err)
+	require.NoError(t, svc.FanOut(ctx, event))
+	require.NoError(t, svc.FanOut(ctx, event), "a retried fan-out")
+
+	// Keyed by the user, so purging the account removes it
+	var queued int
+	err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM outbox WHERE event_type = $1 AND aggregate_id = $2",
+		models.EventWebhookDelivery, event.AggregateID).Scan(&queued)
+	require.NoError(t, err)
+	assert.Equal(t, 1, queued)
+}
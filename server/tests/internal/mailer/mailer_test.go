@@ -0,0 +1,136 @@
+package mailer_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"mime"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tyk-registration-server/internal/mailer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer_WritesEML(t *testing.T) {
+	dir := t.TempDir()
+	m, err := mailer.NewFileMailer("Sender <sender@example.com>", dir)
+	require.NoError(t, err)
+
+	err = m.Send(context.Background(), mailer.Message{
+		To:      "john@example.com",
+		Subject: "Grüße",
+		Text:    "Hello there",
+		HTML:    "<p>Hello there</p>",
+	})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+
+	// The file must parse as an RFC 5322 message
+	msg, err := mail.ReadMessage(f)
+	require.NoError(t, err)
+	assert.Equal(t, `"Sender" <sender@example.com>`, msg.Header.Get("From"))
+	assert.Equal(t, "<john@example.com>", msg.Header.Get("To"))
+	assert.NotEmpty(t, msg.Header.Get("Message-ID"))
+	assert.NotEmpty(t, msg.Header.Get("Date"))
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Grüße", subject)
+	assert.True(t, strings.HasPrefix(msg.Header.Get("Content-Type"), "multipart/alternative"))
+}
+
+func TestFileMailer_RequiresDir(t *testing.T) {
+	_, err := mailer.NewFileMailer("sender@example.com", "")
+	assert.Error(t, err)
+}
+
+func TestFileMailer_RejectsInvalidRecipient(t *testing.T) {
+	m, err := mailer.NewFileMailer("sender@example.com", t.TempDir())
+	require.NoError(t, err)
+
+	err = m.Send(context.Background(), mailer.Message{To: "not an address", Subject: "Hi", Text: "Body"})
+	assert.Error(t, err)
+}
+
+func TestNew_SelectsDriver(t *testing.T) {
+	_, err := mailer.New(mailer.Config{Driver: mailer.DriverLog})
+	assert.NoError(t, err)
+
+	_, err = mailer.New(mailer.Config{Driver: mailer.DriverFile, From: "a@example.com", FileDir: t.TempDir()})
+	assert.NoError(t, err)
+
+	_, err = mailer.New(mailer.Config{Driver: mailer.DriverSMTP})
+	assert.Error(t, err, "smtp without a host")
+
+	_, err = mailer.New(mailer.Config{Driver: "carrier-pigeon"})
+	assert.Error(t, err)
+
+	_, err = mailer.New(mailer.Config{})
+	assert.Error(t, err, "no driver")
+}
+
+func TestLogMailer_DoesNotLogBody(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	err := mailer.NewLogMailer().Send(context.Background(), mailer.Message{
+		To:      "john@example.com",
+		Subject: "Verify your email",
+		Text:    "https://example.com/api/verify-email?token=secret-token",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "Verify your email")
+	assert.NotContains(t, buf.String(), "secret-token")
+}
+
+func TestRender_Templates(t *testing.T) {
+	data := struct {
+		FirstName string
+		Link      string
+		ExpiresIn string
+	}{
+		FirstName: "<John>",
+		Link:      "https://example.com/verify?token=abc&x=1",
+		ExpiresIn: "48h0m0s",
+	}
+
+	msg, err := mailer.Render(mailer.TemplateVerification, "john@example.com", data)
+	require.NoError(t, err)
+	assert.Equal(t, "john@example.com", msg.To)
+	assert.Equal(t, "Verify your email address", msg.Subject)
+	assert.Contains(t, msg.Text, "Hi <John>,")
+	assert.Contains(t, msg.Text, data.Link)
+	// HTML output is escaped
+	assert.Contains(t, msg.HTML, "Hi &lt;John&gt;,")
+	assert.Contains(t, msg.HTML, "token=abc&amp;x=1")
+
+	msg, err = mailer.Render(mailer.TemplatePasswordReset, "john@example.com", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Reset your password", msg.Subject)
+
+	welcome := struct {
+		FirstName string
+		Username  string
+	}{FirstName: "John", Username: "johndoe"}
+	msg, err = mailer.Render(mailer.TemplateWelcome, "john@example.com", welcome)
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome, John!", msg.Subject)
+	assert.Contains(t, msg.Text, "johndoe")
+
+	_, err = mailer.Render("missing", "john@example.com", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,243 @@
+package mailer_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tyk-registration-server/internal/mailer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer speaks just enough SMTP (EHLO, STARTTLS, AUTH PLAIN, MAIL, RCPT, DATA, QUIT)
+// to exercise the driver and records what it received
+type fakeSMTPServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+
+	mu       sync.Mutex
+	usedTLS  bool
+	authLine string
+	mailFrom string
+	rcptTo   string
+	data     string
+}
+
+func startFakeSMTPServer(t *testing.T, tlsConfig *tls.Config) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := &fakeSMTPServer{listener: listener, tlsConfig: tlsConfig}
+	go srv.serve()
+	t.Cleanup(func() { listener.Close() })
+	return srv
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var rw net.Conn = conn
+	reader := bufio.NewReader(rw)
+	reply := func(line string) { _, _ = rw.Write([]byte(line + "\r\n")) }
+
+	reply("220 fake.smtp ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch cmd {
+		case "EHLO", "HELO":
+			reply("250-fake.smtp")
+			if s.tlsConfig != nil && rw == conn {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			rw = tlsConn
+			reader = bufio.NewReader(rw)
+			s.mu.Lock()
+			s.usedTLS = true
+			s.mu.Unlock()
+		case "AUTH":
+			s.mu.Lock()
+			s.authLine = line
+			s.mu.Unlock()
+			reply("235 authenticated")
+		case "MAIL":
+			s.mu.Lock()
+			s.mailFrom = line
+			s.mu.Unlock()
+			reply("250 ok")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcptTo = line
+			s.mu.Unlock()
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+// selfSignedTLS returns a server config for 127.0.0.1 and a client config that trusts it
+func selfSignedTLS(t *testing.T) (*tls.Config, *tls.Config) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	server := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	client := &tls.Config{RootCAs: roots}
+	return server, client
+}
+
+func TestSMTPMailer_StartTLSAndAuth(t *testing.T) {
+	serverTLS, clientTLS := selfSignedTLS(t)
+	srv := startFakeSMTPServer(t, serverTLS)
+
+	m := mailer.NewSMTPMailer("Sender <sender@example.com>", mailer.SMTPConfig{
+		Host:      "127.0.0.1",
+		Port:      srv.port(),
+		Username:  "user",
+		Password:  "secret",
+		StartTLS:  true,
+		TLSConfig: clientTLS,
+	})
+
+	err := m.Send(context.Background(), mailer.Message{
+		To:      "john@example.com",
+		Subject: "Hello",
+		Text:    "Plain body",
+		HTML:    "<p>HTML body</p>",
+	})
+	require.NoError(t, err)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	assert.True(t, srv.usedTLS)
+	assert.Contains(t, srv.mailFrom, "<sender@example.com>")
+	assert.Contains(t, srv.rcptTo, "<john@example.com>")
+
+	creds := strings.TrimPrefix(srv.authLine, "AUTH PLAIN ")
+	decoded, err := base64.StdEncoding.DecodeString(creds)
+	require.NoError(t, err)
+	assert.Equal(t, "\x00user\x00secret", string(decoded))
+
+	assert.Contains(t, srv.data, "Subject: Hello")
+	assert.Contains(t, srv.data, "multipart/alternative")
+	assert.Contains(t, srv.data, "Plain body")
+	assert.Contains(t, srv.data, "<p>HTML body</p>")
+}
+
+func TestSMTPMailer_StartTLSRequired(t *testing.T) {
+	// Server without STARTTLS support
+	srv := startFakeSMTPServer(t, nil)
+
+	m := mailer.NewSMTPMailer("sender@example.com", mailer.SMTPConfig{
+		Host:     "127.0.0.1",
+		Port:     srv.port(),
+		StartTLS: true,
+	})
+
+	err := m.Send(context.Background(), mailer.Message{To: "john@example.com", Subject: "Hi", Text: "Body"})
+	assert.ErrorContains(t, err, "STARTTLS")
+}
+
+func TestSMTPMailer_PlainWithoutAuth(t *testing.T) {
+	srv := startFakeSMTPServer(t, nil)
+
+	m := mailer.NewSMTPMailer("sender@example.com", mailer.SMTPConfig{
+		Host: "127.0.0.1",
+		Port: srv.port(),
+	})
+
+	err := m.Send(context.Background(), mailer.Message{To: "john@example.com", Subject: "Hi", Text: "Body"})
+	require.NoError(t, err)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	assert.False(t, srv.usedTLS)
+	assert.Empty(t, srv.authLine)
+	assert.Contains(t, srv.data, "Content-Type: text/plain")
+}
+
+func TestSMTPMailer_ConnectionRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	m := mailer.NewSMTPMailer("sender@example.com", mailer.SMTPConfig{
+		Host:    "127.0.0.1",
+		Port:    port,
+		Timeout: time.Second,
+	})
+	err = m.Send(context.Background(), mailer.Message{To: "john@example.com", Subject: "Hi", Text: "Body"})
+	assert.ErrorContains(t, err, "smtp dial")
+}
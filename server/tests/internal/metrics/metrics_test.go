@@ -0,0 +1,61 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/metrics"
+)
+
+func TestCounter_Exposition(t *testing.T) {
+	reg := metrics.NewRegistry()
+	c := reg.Counter("requests_total", "Requests.", "route", "status")
+	c.Inc("/api/register", "201")
+	c.Inc("/api/register", "201")
+	c.Add(3, "/api/login", "401")
+
+	assert.Equal(t, float64(2), c.Value("/api/register", "201"))
+
+	var out strings.Builder
+	require.NoError(t, reg.Write(&out))
+	assert.Equal(t, `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{route="/api/login",status="401"} 3
+requests_total{route="/api/register",status="201"} 2
+`, out.String())
+}
+
+func TestHistogram_Exposition(t *testing.T) {
+	reg := metrics.NewRegistry()
+	h := reg.Histogram("latency_seconds", "Latency.", []float64{0.1, 1}, "stage")
+	h.Observe(0.05, "field")
+	h.Observe(0.5, "field")
+	h.Observe(5, "field")
+
+	assert.Equal(t, uint64(3), h.Count("field"))
+
+	var out strings.Builder
+	require.NoError(t, reg.Write(&out))
+	assert.Equal(t, `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{stage="field",le="0.1"} 1
+latency_seconds_bucket{stage="field",le="1"} 2
+latency_seconds_bucket{stage="field",le="+Inf"} 3
+latency_seconds_sum{stage="field"} 5.55
+latency_seconds_count{stage="field"} 3
+`, out.String())
+}
+
+func TestRegistry_DuplicateNamePanics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.Counter("dup", "First.")
+	assert.Panics(t, func() { reg.Counter("dup", "Second.") })
+}
+
+func TestCounter_WrongLabelCountPanics(t *testing.T) {
+	c := metrics.NewRegistry().Counter("c", "C.", "a")
+	assert.Panics(t, func() { c.Inc() })
+}
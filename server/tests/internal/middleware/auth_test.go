@@ -0,0 +1,136 @@
+package middleware_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/middleware"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/repositories"
+	"tyk-registration-server/internal/services"
+)
+
+// fakeSessions knows one session token; other SessionService methods are unused
+type fakeSessions struct {
+	services.SessionService
+	token  string
+	userID uuid.UUID
+}
+
+func (f *fakeSessions) Authenticate(_ context.Context, token string) (*models.Session, error) {
+	if token == "" || token != f.token {
+		return nil, services.ErrInvalidSession
+	}
+	return &models.Session{UserID: f.userID}, nil
+}
+
+// fakeTokens maps access tokens to the users they were issued to
+type fakeTokens struct {
+	services.TokenService
+	users map[string]uuid.UUID
+}
+
+func (f *fakeTokens) VerifyAccessToken(token string) (uuid.UUID, error) {
+	userID, ok := f.users[token]
+	if !ok {
+		return uuid.Nil, services.ErrInvalidAccessToken
+	}
+	return userID, nil
+}
+
+// fakeUsers treats every account as active except those deleted
+type fakeUsers struct {
+	repositories.UserRepository
+	deleted map[uuid.UUID]bool
+}
+
+func (f *fakeUsers) IsActive(_ context.Context, id uuid.UUID) (bool, error) {
+	return !f.deleted[id], nil
+}
+
+func TestRequireUser(t *testing.T) {
+	sessionUser, tokenUser, deletedUser := uuid.New(), uuid.New(), uuid.New()
+	app := fiber.New()
+	app.Get("/me",
+		middleware.RequireUser(
+			&fakeSessions{token: "session-token", userID: sessionUser},
+			&fakeTokens{users: map[string]uuid.UUID{"access-token": tokenUser, "deleted-token": deletedUser}},
+			&fakeUsers{deleted: map[uuid.UUID]bool{deletedUser: true}},
+			"session_id",
+		),
+		func(c *fiber.Ctx) error {
+			return c.SendString(middleware.GetUserIDFromCtx(c).String())
+		})
+
+	tests := []struct {
+		name       string
+		cookie     string
+		auth       string
+		wantStatus int
+		wantUser   uuid.UUID
+	}{
+		{"session cookie", "session-token", "", http.StatusOK, sessionUser},
+		{"bearer token", "", "Bearer access-token", http.StatusOK, tokenUser},
+		{"bearer token wins over cookie", "session-token", "Bearer access-token", http.StatusOK, tokenUser},
+		{"invalid bearer token is not retried with the cookie", "session-token", "Bearer forged", http.StatusUnauthorized, uuid.Nil},
+		{"unexpired token of a deleted account", "", "Bearer deleted-token", http.StatusUnauthorized, uuid.Nil},
+		{"unknown session", "stale", "", http.StatusUnauthorized, uuid.Nil},
+		{"other authorization scheme", "", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, uuid.Nil},
+		{"no credentials", "", "", http.StatusUnauthorized, uuid.Nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "session_id", Value: tt.cookie})
+			}
+			if tt.auth != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.auth)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+
+			if tt.wantStatus == http.StatusOK {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantUser.String(), string(body))
+			}
+		})
+	}
+}
+
+func TestRequireMetricsToken(t *testing.T) {
+	newApp := func(token string) *fiber.App {
+		app := fiber.New()
+		app.Get("/metrics", middleware.RequireMetricsToken(token), func(c *fiber.Ctx) error {
+			return c.SendString("ok")
+		})
+		return app
+	}
+	get := func(app *fiber.App, authorization string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if authorization != "" {
+			req.Header.Set(fiber.HeaderAuthorization, authorization)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	app := newApp("scrape-token")
+	assert.Equal(t, http.StatusUnauthorized, get(app, ""))
+	assert.Equal(t, http.StatusUnauthorized, get(app, "Bearer wrong"))
+	assert.Equal(t, http.StatusUnauthorized, get(app, "scrape-token"))
+	assert.Equal(t, http.StatusOK, get(app, "Bearer scrape-token"))
+
+	assert.Equal(t, http.StatusForbidden, get(newApp(""), "Bearer "))
+}
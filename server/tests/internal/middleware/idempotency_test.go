@@ -0,0 +1,133 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/middleware"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/repositories"
+)
+
+// memoryIdempotencyRepository keeps keys in a map; expiry is ignored
+type memoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]*models.IdempotencyRecord
+}
+
+func newMemoryIdempotencyRepository() *memoryIdempotencyRepository {
+	return &memoryIdempotencyRepository{records: map[string]*models.IdempotencyRecord{}}
+}
+
+func (r *memoryIdempotencyRepository) Reserve(_ context.Context, scope, key, fingerprint string, _ time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.records[scope+"|"+key]; ok {
+		return false, nil
+	}
+	r.records[scope+"|"+key] = &models.IdempotencyRecord{Scope: scope, Key: key, Fingerprint: fingerprint}
+	return true, nil
+}
+
+func (r *memoryIdempotencyRepository) Get(_ context.Context, scope, key string) (*models.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[scope+"|"+key]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	copied := *record
+	return &copied, nil
+}
+
+func (r *memoryIdempotencyRepository) Complete(_ context.Context, scope, key string, status int, body []byte, _ time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if record, ok := r.records[scope+"|"+key]; ok {
+		record.Completed = true
+		record.ResponseStatus = status
+		record.ResponseBody = body
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Release(_ context.Context, scope, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.records, scope+"|"+key)
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) DeleteExpired(context.Context) (int64, error) {
+	return 0, nil
+}
+
+// newIdempotentApp counts how many requests reach the handler. Client IPs come from
+// X-Forwarded-For so tests can act as different clients.
+func newIdempotentApp(calls *int) *fiber.App {
+	app := fiber.New(fiber.Config{ProxyHeader: fiber.HeaderXForwardedFor})
+	app.Post("/items",
+		middleware.Idempotency(newMemoryIdempotencyRepository(), middleware.IdempotencyConfig{TTL: time.Hour}),
+		func(c *fiber.Ctx) error {
+			*calls++
+			return c.Status(http.StatusCreated).JSON(fiber.Map{"call": *calls})
+		})
+	return app
+}
+
+func postIdempotent(t *testing.T, app *fiber.App, ip, key, body string) *http.Response {
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(fiber.HeaderXForwardedFor, ip)
+	req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestIdempotency_SameJSONWithDifferentLayoutReplays(t *testing.T) {
+	var calls int
+	app := newIdempotentApp(&calls)
+
+	first := postIdempotent(t, app, "10.0.0.1", "key-1", `{"email":"a@example.com","age":30}`)
+	require.Equal(t, http.StatusCreated, first.StatusCode)
+
+	retry := postIdempotent(t, app, "10.0.0.1", "key-1", "{\n  \"age\": 30,\n  \"email\": \"a@example.com\"\n}")
+	assert.Equal(t, http.StatusCreated, retry.StatusCode)
+	assert.Equal(t, "true", retry.Header.Get(middleware.IdempotentReplayedHeader))
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_DifferentJSONIsConflict(t *testing.T) {
+	var calls int
+	app := newIdempotentApp(&calls)
+
+	postIdempotent(t, app, "10.0.0.1", "key-1", `{"age":30}`)
+	resp := postIdempotent(t, app, "10.0.0.1", "key-1", `{"age":30.0}`)
+
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_KeysAreScopedToClient(t *testing.T) {
+	var calls int
+	app := newIdempotentApp(&calls)
+
+	first := postIdempotent(t, app, "10.0.0.1", "shared-key", `{"age":30}`)
+	require.Equal(t, http.StatusCreated, first.StatusCode)
+
+	// Another client guessing the key gets its own request, not the first client's response
+	other := postIdempotent(t, app, "10.0.0.2", "shared-key", `{"age":30}`)
+	assert.Equal(t, http.StatusCreated, other.StatusCode)
+	assert.Empty(t, other.Header.Get(middleware.IdempotentReplayedHeader))
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,108 @@
+package middleware_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/middleware"
+)
+
+func TestMemoryRateLimitStore_BucketDrainsAndRefills(t *testing.T) {
+	store := middleware.NewMemoryRateLimitStore()
+	limit := middleware.RateLimit{Burst: 3, Period: 3 * time.Second}
+	now := time.Now()
+
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(context.Background(), "ip", limit, now)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result, err := store.Take(context.Background(), "ip", limit, now)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+
+	// One token per second comes back
+	result, err = store.Take(context.Background(), "ip", limit, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func TestMemoryRateLimitStore_KeysAreIndependent(t *testing.T) {
+	store := middleware.NewMemoryRateLimitStore()
+	limit := middleware.RateLimit{Burst: 1, Period: time.Minute}
+	now := time.Now()
+
+	first, _ := store.Take(context.Background(), "register:10.0.0.1", limit, now)
+	other, _ := store.Take(context.Background(), "register:10.0.0.2", limit, now)
+	again, _ := store.Take(context.Background(), "register:10.0.0.1", limit, now)
+
+	assert.True(t, first.Allowed)
+	assert.True(t, other.Allowed)
+	assert.False(t, again.Allowed)
+}
+
+func TestMemoryRateLimitStore_SweepsFullBuckets(t *testing.T) {
+	store := middleware.NewMemoryRateLimitStore()
+	limit := middleware.RateLimit{Burst: 5, Period: time.Second}
+	now := time.Now()
+
+	_, _ = store.Take(context.Background(), "a", limit, now)
+	_, _ = store.Take(context.Background(), "b", limit, now)
+	_, _ = store.Take(context.Background(), "c", limit, now.Add(2*time.Minute))
+
+	// a and b have long refilled and were swept; only c remains
+	assert.Equal(t, 1, store.Len())
+}
+
+func TestRateLimitByIP_Returns429WithHeaders(t *testing.T) {
+	app := fiber.New()
+	app.Get("/limited",
+		middleware.RateLimitByIP(middleware.NewMemoryRateLimitStore(), "limited", middleware.RateLimit{Burst: 2, Period: time.Minute}),
+		func(c *fiber.Ctx) error { return c.SendStatus(http.StatusOK) })
+
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/limited", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get("RateLimit-Limit"))
+		assert.Equal(t, "2;w=60", resp.Header.Get("RateLimit-Policy"))
+	}
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/limited", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", resp.Header.Get("RateLimit-Reset"))
+	assert.Equal(t, "30", resp.Header.Get("Retry-After"))
+
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, "rate_limited", result["error"]["code"])
+}
+
+func TestRateLimitByIP_ZeroLimitDisables(t *testing.T) {
+	app := fiber.New()
+	app.Get("/open",
+		middleware.RateLimitByIP(middleware.NewMemoryRateLimitStore(), "open", middleware.RateLimit{}),
+		func(c *fiber.Ctx) error { return c.SendStatus(http.StatusOK) })
+
+	for i := 0; i < 5; i++ {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/open", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("RateLimit-Limit"))
+	}
+}
@@ -0,0 +1,272 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/outbox"
+	"tyk-registration-server/internal/repositories"
+)
+
+type outcome struct {
+	status    string
+	nextRetry time.Time
+	lastErr   string
+}
+
+// fakeOutboxRepo keeps events in memory and records what the dispatcher decided for each
+type fakeOutboxRepo struct {
+	pending   []models.OutboxEvent
+	outcomes  map[uuid.UUID]outcome
+	failMarks map[uuid.UUID]bool
+	leaseEnd  time.Time
+	purgedTo  time.Time
+}
+
+func newFakeOutboxRepo(events ...models.OutboxEvent) *fakeOutboxRepo {
+	return &fakeOutboxRepo{pending: events, outcomes: map[uuid.UUID]outcome{}, failMarks: map[uuid.UUID]bool{}}
+}
+
+func (r *fakeOutboxRepo) Enqueue(ctx context.Context, event models.OutboxEvent) error {
+	r.pending = append(r.pending, event)
+	return nil
+}
+
+func (r *fakeOutboxRepo) ClaimPending(ctx context.Context, limit int, lockedUntil time.Time) ([]models.OutboxEvent, error) {
+	r.leaseEnd = lockedUntil
+	if limit > len(r.pending) {
+		limit = len(r.pending)
+	}
+	claimed := r.pending[:limit]
+	r.pending = r.pending[limit:]
+	return claimed, nil
+}
+
+func (r *fakeOutboxRepo) MarkDelivered(ctx context.Context, id uuid.UUID) error {
+	if r.failMarks[id] {
+		return errors.New("connection reset")
+	}
+	r.outcomes[id] = outcome{status: "delivered"}
+	return nil
+}
+
+func (r *fakeOutboxRepo) MarkRetry(ctx context.Context, id uuid.UUID, next time.Time, lastErr string) error {
+	r.outcomes[id] = outcome{status: "pending", nextRetry: next, lastErr: lastErr}
+	return nil
+}
+
+func (r *fakeOutboxRepo) MarkDead(ctx context.Context, id uuid.UUID, lastErr string) error {
+	r.outcomes[id] = outcome{status: "dead", lastErr: lastErr}
+	return nil
+}
+
+func (r *fakeOutboxRepo) DeleteDelivered(ctx context.Context, deliveredBefore time.Time) (int64, error) {
+	r.purgedTo = deliveredBefore
+	return 0, nil
+}
+
+func (r *fakeOutboxRepo) WithTx(ctx context.Context, fn func(outbox repositories.OutboxRepository) error) error {
+	return fn(r)
+}
+
+func testConfig() outbox.Config {
+	return outbox.Config{
+		PollInterval: 10 * time.Millisecond,
+		BatchSize:    10,
+		Lease:        time.Minute,
+		MaxAttempts:  3,
+		BaseBackoff:  time.Second,
+		MaxBackoff:   time.Minute,
+	}
+}
+
+func testEvent(t *testing.T, attempts int) models.OutboxEvent {
+	event, err := models.NewOutboxEvent(models.EventUserRegistered, uuid.New(), map[string]string{"k": "v"})
+	require.NoError(t, err)
+	event.Attempts = attempts
+	return event
+}
+
+func TestDispatcher_DeliversToHandler(t *testing.T) {
+	event := testEvent(t, 0)
+	repo := newFakeOutboxRepo(event)
+	d := outbox.NewDispatcher(repo, testConfig())
+
+	var calls int
+	d.Register(models.EventUserRegistered, func(ctx context.Context, e models.OutboxEvent) error {
+		calls++
+		assert.Equal(t, event.ID, e.ID)
+		return nil
+	})
+
+	n, err := d.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "delivered", repo.outcomes[event.ID].status)
+}
+
+func TestDispatcher_OneHandlerPerEventType(t *testing.T) {
+	d := outbox.NewDispatcher(newFakeOutboxRepo(), testConfig())
+	handler := func(ctx context.Context, e models.OutboxEvent) error { return nil }
+	d.Register(models.EventUserRegistered, handler)
+
+	assert.Panics(t, func() { d.Register(models.EventUserRegistered, handler) })
+	assert.NotPanics(t, func() { d.Register(models.EventVerificationRequested, handler) })
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	event := testEvent(t, 0)
+	repo := newFakeOutboxRepo(event)
+	d := outbox.NewDispatcher(repo, testConfig())
+	d.Register(models.EventUserRegistered, func(ctx context.Context, e models.OutboxEvent) error {
+		return errors.New("smtp unavailable")
+	})
+
+	before := time.Now()
+	_, err := d.ProcessBatch(context.Background())
+	require.NoError(t, err)
+
+	got := repo.outcomes[event.ID]
+	assert.Equal(t, "pending", got.status)
+	assert.Equal(t, "smtp unavailable", got.lastErr)
+	assert.True(t, got.nextRetry.After(before))
+	assert.True(t, got.nextRetry.Before(before.Add(2*time.Second)))
+}
+
+func TestDispatcher_DeadLettersAfterMaxAttempts(t *testing.T) {
+	event := testEvent(t, 2) // this is the third and last attempt
+	repo := newFakeOutboxRepo(event)
+	d := outbox.NewDispatcher(repo, testConfig())
+	d.Register(models.EventUserRegistered, func(ctx context.Context, e models.OutboxEvent) error {
+		return errors.New("still failing")
+	})
+
+	_, err := d.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "dead", repo.outcomes[event.ID].status)
+}
+
+func TestDispatcher_PermanentErrorSkipsRetries(t *testing.T) {
+	event := testEvent(t, 0)
+	repo := newFakeOutboxRepo(event)
+	d := outbox.NewDispatcher(repo, testConfig())
+	d.Register(models.EventUserRegistered, func(ctx context.Context, e models.OutboxEvent) error {
+		return fmt.Errorf("%w: bad payload", outbox.ErrPermanent)
+	})
+
+	_, err := d.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "dead", repo.outcomes[event.ID].status)
+}
+
+func TestDispatcher_RecoversHandlerPanic(t *testing.T) {
+	event := testEvent(t, 0)
+	repo := newFakeOutboxRepo(event)
+	d := outbox.NewDispatcher(repo, testConfig())
+	d.Register(models.EventUserRegistered, func(ctx context.Context, e models.OutboxEvent) error {
+		panic("boom")
+	})
+
+	_, err := d.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "pending", repo.outcomes[event.ID].status)
+	assert.Contains(t, repo.outcomes[event.ID].lastErr, "boom")
+}
+
+func TestDispatcher_RunStopsOnCancel(t *testing.T) {
+	event := testEvent(t, 0)
+	repo := newFakeOutboxRepo(event)
+	d := outbox.NewDispatcher(repo, testConfig())
+
+	delivered := make(chan struct{}, 1)
+	d.Register(models.EventUserRegistered, func(ctx context.Context, e models.OutboxEvent) error {
+		delivered <- struct{}{}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatal("event was not delivered")
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("dispatcher did not stop")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	base := time.Second
+	max := 10 * time.Second
+
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: max} {
+		got := outbox.Backoff(attempt, base, max)
+		// Jitter takes off at most 20%
+		assert.LessOrEqual(t, got, want, "attempt %d", attempt)
+		assert.GreaterOrEqual(t, got, want*4/5, "attempt %d", attempt)
+	}
+}
+
+func TestDispatcher_MarkFailureDoesNotUndoOtherEvents(t *testing.T) {
+	first, second := testEvent(t, 0), testEvent(t, 0)
+	repo := newFakeOutboxRepo(first, second)
+	repo.failMarks[first.ID] = true
+	d := outbox.NewDispatcher(repo, testConfig())
+	d.Register(models.EventUserRegistered, func(ctx context.Context, e models.OutboxEvent) error {
+		return nil
+	})
+
+	n, err := d.ProcessBatch(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), first.ID.String())
+	assert.Equal(t, 2, n)
+	assert.NotContains(t, repo.outcomes, first.ID)
+	assert.Equal(t, "delivered", repo.outcomes[second.ID].status)
+}
+
+func TestDispatcher_HandlersRunWithinTheLease(t *testing.T) {
+	event := testEvent(t, 0)
+	repo := newFakeOutboxRepo(event)
+	d := outbox.NewDispatcher(repo, testConfig())
+
+	var deadline time.Time
+	d.Register(models.EventUserRegistered, func(ctx context.Context, e models.OutboxEvent) error {
+		deadline, _ = ctx.Deadline()
+		return nil
+	})
+
+	_, err := d.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	assert.False(t, repo.leaseEnd.IsZero())
+	assert.Equal(t, repo.leaseEnd, deadline)
+}
+
+func TestDispatcher_PurgeDeliveredKeepsRetention(t *testing.T) {
+	repo := newFakeOutboxRepo()
+	cfg := testConfig()
+	cfg.Retention = 24 * time.Hour
+	d := outbox.NewDispatcher(repo, cfg)
+
+	_, err := d.PurgeDelivered(context.Background())
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), repo.purgedTo, time.Minute)
+}
@@ -0,0 +1,155 @@
+package pii_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/pii"
+)
+
+// memoryKeyStore mimics the pii_keys table, including its single index key constraint
+type memoryKeyStore struct {
+	keys []models.PIIKey
+}
+
+func (s *memoryKeyStore) ListKeys(context.Context) ([]models.PIIKey, error) {
+	return append([]models.PIIKey(nil), s.keys...), nil
+}
+
+func (s *memoryKeyStore) CreateKey(_ context.Context, key models.PIIKey) error {
+	for _, existing := range s.keys {
+		if key.Purpose == models.PIIKeyPurposeIndex && existing.Purpose == models.PIIKeyPurposeIndex {
+			return nil
+		}
+	}
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func newWrapper(t *testing.T, fill byte) pii.KeyWrapper {
+	w, err := pii.NewLocalKeyWrapper(bytes.Repeat([]byte{fill}, 32))
+	require.NoError(t, err)
+	return w
+}
+
+func newCipher(t *testing.T, store *memoryKeyStore) *pii.Cipher {
+	c, err := pii.NewCipher(context.Background(), newWrapper(t, 1), store)
+	require.NoError(t, err)
+	return c
+}
+
+func TestNewCipher_CreatesKeysOnce(t *testing.T) {
+	store := &memoryKeyStore{}
+	first := newCipher(t, store)
+	require.Len(t, store.keys, 2)
+	for _, key := range store.keys {
+		assert.Equal(t, newWrapper(t, 1).ID(), key.MasterKeyID)
+	}
+
+	// A restart unwraps the same keys
+	second := newCipher(t, store)
+	assert.Len(t, store.keys, 2)
+	assert.Equal(t, first.DataKeyID(), second.DataKeyID())
+
+	id := uuid.New()
+	sealed, err := first.Encrypt(id, "street", "1 Main St")
+	require.NoError(t, err)
+	plain, err := second.Decrypt(second.DataKeyID(), id, "street", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "1 Main St", plain)
+	assert.Equal(t, first.BlindIndex("email", "a@example.com"), second.BlindIndex("email", "a@example.com"))
+}
+
+func TestCipher_EncryptIsRandomized(t *testing.T) {
+	c := newCipher(t, &memoryKeyStore{})
+	id := uuid.New()
+
+	a, err := c.Encrypt(id, "first_name", "John")
+	require.NoError(t, err)
+	b, err := c.Encrypt(id, "first_name", "John")
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b)
+	assert.NotContains(t, a, "John")
+}
+
+func TestCipher_CiphertextBoundToRowAndColumn(t *testing.T) {
+	c := newCipher(t, &memoryKeyStore{})
+	id := uuid.New()
+	sealed, err := c.Encrypt(id, "first_name", "John")
+	require.NoError(t, err)
+
+	_, err = c.Decrypt(c.DataKeyID(), id, "last_name", sealed)
+	assert.Error(t, err, "copied to another column")
+	_, err = c.Decrypt(c.DataKeyID(), uuid.New(), "first_name", sealed)
+	assert.Error(t, err, "copied to another row")
+	_, err = c.Decrypt(uuid.New(), id, "first_name", sealed)
+	assert.Error(t, err, "unknown data key")
+	_, err = c.Decrypt(c.DataKeyID(), id, "first_name", "not base64!")
+	assert.Error(t, err)
+}
+
+func TestCipher_SealBinary(t *testing.T) {
+	c := newCipher(t, &memoryKeyStore{})
+	id := uuid.New()
+	payload := []byte{0x50, 0x4b, 0x03, 0x04, 0x00, 0xff}
+
+	sealed, err := c.Seal(id, "payload", payload)
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(sealed, payload))
+
+	plain, err := c.Open(c.DataKeyID(), id, "payload", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, payload, plain)
+
+	_, err = c.Open(c.DataKeyID(), uuid.New(), "payload", sealed)
+	assert.Error(t, err, "copied to another row")
+}
+
+func TestCipher_BlindIndex(t *testing.T) {
+	c := newCipher(t, &memoryKeyStore{})
+	other := newCipher(t, &memoryKeyStore{})
+
+	index := c.BlindIndex("email", "a@example.com")
+	assert.Len(t, index, 64)
+	assert.Equal(t, index, c.BlindIndex("email", "a@example.com"))
+	assert.NotEqual(t, index, c.BlindIndex("email", "b@example.com"))
+	assert.NotEqual(t, index, c.BlindIndex("phone", "a@example.com"), "columns are separated")
+	assert.NotEqual(t, index, other.BlindIndex("email", "a@example.com"), "indexes depend on the key")
+}
+
+func TestNewCipher_WrongMasterKey(t *testing.T) {
+	store := &memoryKeyStore{}
+	newCipher(t, store)
+
+	_, err := pii.NewCipher(context.Background(), newWrapper(t, 2), store)
+	assert.ErrorContains(t, err, "wrapped by master key")
+}
+
+func TestLoad(t *testing.T) {
+	c, err := pii.Load(context.Background(), "", &memoryKeyStore{})
+	require.NoError(t, err)
+	assert.Nil(t, c, "no key file disables encryption")
+
+	dir := t.TempDir()
+	good := filepath.Join(dir, "master.key")
+	require.NoError(t, os.WriteFile(good, []byte(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))+"\n"), 0o600))
+	c, err = pii.Load(context.Background(), good, &memoryKeyStore{})
+	require.NoError(t, err)
+	assert.NotNil(t, c)
+
+	short := filepath.Join(dir, "short.key")
+	require.NoError(t, os.WriteFile(short, []byte(base64.StdEncoding.EncodeToString([]byte("short"))), 0o600))
+	for _, path := range []string{short, filepath.Join(dir, "missing.key")} {
+		_, err := pii.Load(context.Background(), path, &memoryKeyStore{})
+		assert.Error(t, err, path)
+	}
+}
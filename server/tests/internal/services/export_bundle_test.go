@@ -0,0 +1,93 @@
+package services_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/services"
+)
+
+func testExportBundle() *models.ExportBundle {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	phone := "+12025551234"
+	user := &models.User{
+		ID:            uuid.New(),
+		FirstName:     "John",
+		LastName:      "Doe",
+		Email:         "john@example.com",
+		Phone:         &phone,
+		Username:      "johndoe",
+		PasswordHash:  "$argon2id$v=19$m=64,t=1,p=1$c2FsdA$a2V5",
+		TermsAccepted: true,
+		CreatedAt:     created,
+		UpdatedAt:     created,
+	}
+	sessions := []models.Session{{ID: uuid.New(), UserID: user.ID, UserAgent: "curl/8", IPAddress: "10.0.0.1", CreatedAt: created}}
+	return services.NewExportBundle(user, sessions, nil, created.Add(time.Hour))
+}
+
+func TestNewExportBundle(t *testing.T) {
+	bundle := testExportBundle()
+
+	assert.Equal(t, "johndoe", bundle.User.Username)
+	assert.Equal(t, []models.ExportConsent{
+		{Type: "terms", Granted: true, RecordedAt: bundle.User.CreatedAt},
+		{Type: "newsletter", Granted: false, RecordedAt: bundle.User.CreatedAt},
+	}, bundle.Consents)
+	require.Len(t, bundle.Sessions, 1)
+	assert.Equal(t, "10.0.0.1", bundle.Sessions[0].IPAddress)
+	assert.NotNil(t, bundle.AuditEvents, "empty sections encode as [] rather than null")
+}
+
+func TestEncodeExportBundle_JSON(t *testing.T) {
+	data, err := services.EncodeExportBundle(testExportBundle(), models.ExportFormatJSON)
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(data), "argon2id", "the password hash is never exported")
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	for _, key := range []string{"generated_at", "user", "consents", "sessions", "audit_events"} {
+		assert.Contains(t, decoded, key)
+	}
+	assert.Equal(t, "john@example.com", decoded["user"].(map[string]any)["email"])
+	assert.Equal(t, []any{}, decoded["audit_events"])
+}
+
+func TestEncodeExportBundle_ZIP(t *testing.T) {
+	bundle := testExportBundle()
+	data, err := services.EncodeExportBundle(bundle, models.ExportFormatZIP)
+	require.NoError(t, err)
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		files[f.Name] = content
+	}
+	assert.Len(t, files, 4)
+
+	var user models.ExportUser
+	require.NoError(t, json.Unmarshal(files["user.json"], &user))
+	assert.Equal(t, bundle.User, user)
+	assert.JSONEq(t, "[]", string(files["audit_events.json"]))
+	assert.Contains(t, files, "consents.json")
+	assert.Contains(t, files, "sessions.json")
+}
+
+func TestEncodeExportBundle_UnknownFormat(t *testing.T) {
+	_, err := services.EncodeExportBundle(testExportBundle(), "csv")
+	assert.ErrorIs(t, err, services.ErrUnknownExportFormat)
+}
@@ -0,0 +1,117 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/services"
+	"tyk-registration-server/internal/utils"
+	"tyk-registration-server/internal/validator"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubLookup treats the given usernames as registered and records each batch it's asked about
+type stubLookup struct {
+	taken   map[string]bool
+	batches [][]string
+}
+
+func newStubLookup(taken ...string) *stubLookup {
+	s := &stubLookup{taken: map[string]bool{}}
+	for _, u := range taken {
+		s.taken[utils.CanonicalUsername(u)] = true
+	}
+	return s
+}
+
+func (s *stubLookup) TakenUsernames(ctx context.Context, usernames []string) (map[string]bool, error) {
+	s.batches = append(s.batches, usernames)
+	found := map[string]bool{}
+	for _, u := range usernames {
+		if key := utils.CanonicalUsername(u); s.taken[key] {
+			found[key] = true
+		}
+	}
+	return found, nil
+}
+
+func newUsernameService(t *testing.T, lookup services.UsernameLookup) services.UsernameService {
+	rules, err := validator.NewRuleEngine("", models.RegistrationRequest{})
+	require.NoError(t, err)
+	profanity, err := validator.LoadProfanityList("")
+	require.NoError(t, err)
+	return services.NewUsernameService(lookup, rules, validator.NewUsernamePolicy(profanity, nil))
+}
+
+func TestUsernameService_Available(t *testing.T) {
+	lookup := newStubLookup()
+	resp, err := newUsernameService(t, lookup).Availability(context.Background(), " johndoe ", "", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "johndoe", resp.Username)
+	assert.True(t, resp.Available)
+	assert.Empty(t, resp.Suggestions)
+}
+
+func TestUsernameService_TakenSuggestsAvailableNames(t *testing.T) {
+	lookup := newStubLookup("johndoe", "john.doe", "johndoe1")
+	resp, err := newUsernameService(t, lookup).Availability(context.Background(), "JohnDoe", "John", "Doe")
+	require.NoError(t, err)
+
+	assert.False(t, resp.Available)
+	require.Len(t, resp.Suggestions, services.MaxUsernameSuggestions)
+	// Name combinations rank first, skipping taken ones and ones too short for the rules
+	// ("jdoe", "johnd")
+	assert.Equal(t, []string{"john_doe", "doejohn", "doe.john"}, resp.Suggestions[:3])
+	for _, s := range resp.Suggestions {
+		assert.False(t, lookup.taken[utils.CanonicalUsername(s)], s)
+	}
+
+	// The requested name and all candidates go to the database in one query
+	assert.Len(t, lookup.batches, 1)
+}
+
+func TestUsernameService_SuggestionsPassFieldRules(t *testing.T) {
+	lookup := newStubLookup()
+	// "al" and "bo" are too short on their own, as is the requested name
+	resp, err := newUsernameService(t, lookup).Availability(context.Background(), "albo", "Al", "Bo")
+	require.NoError(t, err)
+
+	assert.False(t, resp.Available)
+	require.NotEmpty(t, resp.Suggestions)
+	for _, s := range resp.Suggestions {
+		assert.GreaterOrEqual(t, len(s), 6, s)
+	}
+	for _, batch := range lookup.batches {
+		assert.NotContains(t, batch, "albo")
+	}
+}
+
+func TestUsernameService_NumbersReplaceTrailingDigits(t *testing.T) {
+	lookup := newStubLookup("johndoe123")
+	resp, err := newUsernameService(t, lookup).Availability(context.Background(), "johndoe123", "", "")
+	require.NoError(t, err)
+
+	assert.False(t, resp.Available)
+	assert.Equal(t, "johndoe1", resp.Suggestions[0])
+	for _, s := range resp.Suggestions {
+		assert.True(t, strings.HasPrefix(s, "johndoe"), s)
+	}
+}
+
+func TestUsernameService_PolicyViolationIsReported(t *testing.T) {
+	lookup := newStubLookup()
+	resp, err := newUsernameService(t, lookup).Availability(context.Background(), "administrator", "", "")
+	require.NoError(t, err)
+
+	assert.False(t, resp.Available)
+	assert.Equal(t, validator.UsernameReservedMessage, resp.Message)
+	// Suggestions built from a reserved name must not be reserved themselves
+	for _, s := range resp.Suggestions {
+		assert.NotEqual(t, "administrator", s)
+	}
+}
@@ -1,6 +1,7 @@
 package testhelpers
 
 import (
+	"os"
 	"testing"
 
 	"tyk-registration-server/internal/config"
@@ -10,6 +11,9 @@ import (
 
 func LoadTestConfig(t *testing.T) *config.Config {
 	_ = godotenv.Load("../../.env")
+	if os.Getenv("JWT_SECRET") == "" {
+		t.Setenv("JWT_SECRET", "test-signing-secret-of-at-least-32-bytes")
+	}
 
 	cfg, err := config.Load()
 	if err != nil {
@@ -7,6 +7,7 @@ import (
 
 	"tyk-registration-server/internal/db"
 	"tyk-registration-server/internal/db/sqlc"
+	"tyk-registration-server/internal/utils"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
@@ -40,16 +41,76 @@ func GetTestDBPool(t *testing.T) *pgxpool.Pool {
 	return pool
 }
 
-// CleanupUsersTable removes all users from the database
+// CleanupUsersTable removes all users and their audit events, which don't cascade, from the database
 func CleanupUsersTable(t *testing.T, pool *pgxpool.Pool) {
 	ctx := context.Background()
 	_, err := pool.Exec(ctx, "DELETE FROM users")
 	if err != nil {
 		t.Fatalf("Failed to cleanup users table: %v", err)
 	}
+	_, err = pool.Exec(ctx, "DELETE FROM audit_events")
+	if err != nil {
+		t.Fatalf("Failed to cleanup audit events table: %v", err)
+	}
+}
+
+// CleanupOutboxTable removes all outbox events from the database
+func CleanupOutboxTable(t *testing.T, pool *pgxpool.Pool) {
+	ctx := context.Background()
+	_, err := pool.Exec(ctx, "DELETE FROM outbox")
+	if err != nil {
+		t.Fatalf("Failed to cleanup outbox table: %v", err)
+	}
 }
 
 // CreateTestQueries creates a sqlc.Queries instance for testing
 func CreateTestQueries(t *testing.T, pool *pgxpool.Pool) *sqlc.Queries {
 	return sqlc.New(pool)
 }
+
+// InsertTestUserToken stores a single-use token for userID so tests can redeem it without reading email
+func InsertTestUserToken(t *testing.T, pool *pgxpool.Pool, userID, purpose, token string) {
+	ctx := context.Background()
+	_, err := pool.Exec(ctx,
+		"INSERT INTO user_tokens (id, user_id, purpose, token_hash, expires_at) VALUES (gen_random_uuid(), $1, $2, $3, NOW() + INTERVAL '1 hour')",
+		userID, purpose, utils.HashToken(token))
+	if err != nil {
+		t.Fatalf("Failed to insert user token: %v", err)
+	}
+}
+
+// CleanupWebhooksTable removes all webhook endpoints and, by cascade, their delivery logs
+func CleanupWebhooksTable(t *testing.T, pool *pgxpool.Pool) {
+	ctx := context.Background()
+	_, err := pool.Exec(ctx, "DELETE FROM webhook_endpoints")
+	if err != nil {
+		t.Fatalf("Failed to cleanup webhooks table: %v", err)
+	}
+}
+
+// CleanupDraftsTable removes all registration drafts
+func CleanupDraftsTable(t *testing.T, pool *pgxpool.Pool) {
+	ctx := context.Background()
+	_, err := pool.Exec(ctx, "DELETE FROM registration_drafts")
+	if err != nil {
+		t.Fatalf("Failed to cleanup registration drafts table: %v", err)
+	}
+}
+
+// CleanupIdempotencyKeysTable removes all stored idempotency keys
+func CleanupIdempotencyKeysTable(t *testing.T, pool *pgxpool.Pool) {
+	ctx := context.Background()
+	_, err := pool.Exec(ctx, "DELETE FROM idempotency_keys")
+	if err != nil {
+		t.Fatalf("Failed to cleanup idempotency keys table: %v", err)
+	}
+}
+
+// CleanupPIIKeysTable removes the stored PII keys; users encrypted with them must be removed first
+func CleanupPIIKeysTable(t *testing.T, pool *pgxpool.Pool) {
+	ctx := context.Background()
+	_, err := pool.Exec(ctx, "DELETE FROM pii_keys")
+	if err != nil {
+		t.Fatalf("Failed to cleanup PII keys table: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package utils_test
+
+import (
+	"testing"
+
+	"tyk-registration-server/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailRules_Canonical(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"john@example.com", "john@example.com"},
+		{"John@Example.COM", "john@example.com"},
+		{"  john@example.com ", "john@example.com"},
+		// Dots and plus tags only fold for providers that ignore them
+		{"j.doe+news@example.com", "j.doe+news@example.com"},
+		{"J.Doe+news@Gmail.com", "jdoe@gmail.com"},
+		{"j.d.o.e@googlemail.com", "jdoe@gmail.com"},
+		{"not-an-email", "not-an-email"},
+	}
+	rules := utils.DefaultEmailRules()
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, rules.Canonical(tt.in), tt.in)
+	}
+}
+
+func TestEmailRules_KeepsLocalCase(t *testing.T) {
+	rules, err := utils.LoadEmailRules("", false)
+	require.NoError(t, err)
+
+	assert.Equal(t, "John@example.com", rules.Canonical("John@Example.COM"))
+	assert.NotEqual(t, rules.Canonical("John@example.com"), rules.Canonical("john@example.com"))
+}
+
+func TestParseEmailRules(t *testing.T) {
+	rules, err := utils.ParseEmailRules([]byte(`
+providers:
+  - domains: [Example.org, example.net]
+    strip_plus_tag: true
+`), true)
+	require.NoError(t, err)
+
+	assert.Equal(t, "j.doe@example.org", rules.Canonical("J.Doe+news@example.net"))
+	// The built-in providers are replaced, not extended
+	assert.Equal(t, "j.doe+news@gmail.com", rules.Canonical("j.doe+news@gmail.com"))
+}
+
+func TestParseEmailRules_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"no domains":       "providers:\n  - ignore_dots: true\n",
+		"duplicate domain": "providers:\n  - domains: [a.com]\n  - domains: [A.com]\n",
+		"not yaml":         "providers: [",
+	} {
+		_, err := utils.ParseEmailRules([]byte(data), true)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestCanonicalUsername(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"johndoe", "johndoe"},
+		{"JohnDoe", "johndoe"},
+		{" johndoe ", "johndoe"},
+		// Full-width letters are compatibility variants of ASCII
+		{"ｊｏｈｎｄｏｅ", "johndoe"},
+		// Ligature
+		{"ﬁnn", "finn"},
+		// Full case folding, not just lowercasing
+		{"Straße", "strasse"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, utils.CanonicalUsername(tt.in), tt.in)
+	}
+}
+
+func TestUsernameSkeleton(t *testing.T) {
+	// Lookalikes share a skeleton
+	same := [][]string{
+		{"paypal", "pаypal", "PAYPAL"}, // Cyrillic а
+		{"moon", "rnoon", "m00n"},
+		{"apple", "аррӏе"},             // All Cyrillic
+		{"olivia", "οlivia", "0livia"}, // Greek ο
+	}
+	for _, group := range same {
+		for _, name := range group[1:] {
+			assert.Equal(t, utils.UsernameSkeleton(group[0]), utils.UsernameSkeleton(name), name)
+		}
+	}
+
+	assert.NotEqual(t, utils.UsernameSkeleton("paypal"), utils.UsernameSkeleton("paypa1x"))
+}
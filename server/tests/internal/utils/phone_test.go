@@ -0,0 +1,49 @@
+package utils_test
+
+import (
+	"testing"
+
+	"tyk-registration-server/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePhone_NormalizesToE164(t *testing.T) {
+	for _, in := range []string{
+		"+44 20 7946 0958",
+		"+442079460958",
+		"442079460958",
+		"+44 (0)20 7946 0958",
+		" +44-20-7946-0958 ",
+	} {
+		p, err := utils.ParsePhone(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, "+442079460958", p.E164, in)
+		assert.Equal(t, "GB", p.Region, in)
+		assert.Equal(t, utils.PhoneTypeFixedLine, p.Type, in)
+	}
+}
+
+func TestParsePhone_DetectsType(t *testing.T) {
+	tests := []struct {
+		in, region, phoneType string
+	}{
+		{"+20 10 01234567", "EG", utils.PhoneTypeMobile},
+		{"+1 (202) 555-1234", "US", utils.PhoneTypeFixedLineOrMobile},
+		{"+1 800 555 0199", "US", utils.PhoneTypeTollFree},
+	}
+	for _, tt := range tests {
+		p, err := utils.ParsePhone(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.region, p.Region, tt.in)
+		assert.Equal(t, tt.phoneType, p.Type, tt.in)
+	}
+}
+
+func TestParsePhone_Invalid(t *testing.T) {
+	for _, in := range []string{"12345", "not a number", ""} {
+		_, err := utils.ParsePhone(in)
+		assert.ErrorIs(t, err, utils.ErrInvalidPhone, in)
+	}
+}
@@ -0,0 +1,32 @@
+package utils_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"tyk-registration-server/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateToken(t *testing.T) {
+	token1, err := utils.GenerateToken(32)
+	require.NoError(t, err)
+	token2, err := utils.GenerateToken(32)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, token1, token2)
+
+	raw, err := base64.RawURLEncoding.DecodeString(token1)
+	require.NoError(t, err)
+	assert.Len(t, raw, 32)
+}
+
+func TestHashToken(t *testing.T) {
+	// Hashing is deterministic so stored hashes can be looked up
+	assert.Equal(t, utils.HashToken("abc"), utils.HashToken("abc"))
+	assert.NotEqual(t, utils.HashToken("abc"), utils.HashToken("abd"))
+	assert.Len(t, utils.HashToken("abc"), 64)
+	assert.NotEqual(t, "abc", utils.HashToken("abc"))
+}
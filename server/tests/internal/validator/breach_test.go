@@ -0,0 +1,128 @@
+package validator_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/validator"
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// writeRangeDir writes HIBP range files for the given passwords and counts
+func writeRangeDir(t *testing.T, counts map[string]int) string {
+	dir := t.TempDir()
+	ranges := map[string][]string{}
+	for password, count := range counts {
+		hash := sha1Hex(password)
+		ranges[hash[:5]] = append(ranges[hash[:5]], fmt.Sprintf("%s:%d", hash[5:], count))
+	}
+	for prefix, lines := range ranges {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte(strings.Join(lines, "\r\n")), 0o644))
+	}
+	return dir
+}
+
+func TestRangeDirectory_Breached(t *testing.T) {
+	dir := writeRangeDir(t, map[string]int{"Password1!": 120, "padding": 0})
+	checker, err := validator.LoadBreachChecker(dir)
+	require.NoError(t, err)
+
+	breached, err := checker.Breached("Password1!")
+	require.NoError(t, err)
+	assert.True(t, breached)
+
+	// HIBP pads ranges with zero-count entries, which aren't breaches
+	breached, err = checker.Breached("padding")
+	require.NoError(t, err)
+	assert.False(t, breached)
+
+	// No range file for the prefix
+	breached, err = checker.Breached("Test123!@#")
+	require.NoError(t, err)
+	assert.False(t, breached)
+}
+
+func TestBloomFilter_RoundTrip(t *testing.T) {
+	filter, err := validator.NewBloomFilter(100, 0.001)
+	require.NoError(t, err)
+	filter.Add(validator.HashBreachPassword("Password1!"))
+	filter.Add(validator.HashBreachPassword("hunter2"))
+
+	var buf bytes.Buffer
+	_, err = filter.WriteTo(&buf)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "breached.bloom")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+	checker, err := validator.LoadBreachChecker(path)
+	require.NoError(t, err)
+
+	for _, password := range []string{"Password1!", "hunter2"} {
+		breached, err := checker.Breached(password)
+		require.NoError(t, err)
+		assert.True(t, breached, password)
+	}
+	breached, err := checker.Breached("Test123!@#")
+	require.NoError(t, err)
+	assert.False(t, breached)
+}
+
+func TestReadBloomFilter_RejectsOtherFiles(t *testing.T) {
+	_, err := validator.ReadBloomFilter(strings.NewReader("21BD1:3\n"))
+	assert.Error(t, err)
+}
+
+func TestLoadBreachChecker_EmptyPathDisables(t *testing.T) {
+	checker, err := validator.LoadBreachChecker("")
+	require.NoError(t, err)
+	assert.Nil(t, checker)
+}
+
+func TestForEachBreachHash_HonorsMinCount(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pwned.txt")
+	content := sha1Hex("common") + ":5000\n" + sha1Hex("rare") + ":2\n"
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+	dir := writeRangeDir(t, map[string]int{"common": 5000, "rare": 2})
+
+	for _, path := range []string{file, dir} {
+		var seen []validator.BreachHash
+		err := validator.ForEachBreachHash(path, 10, func(hash validator.BreachHash) error {
+			seen = append(seen, hash)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []validator.BreachHash{validator.HashBreachPassword("common")}, seen, path)
+	}
+}
+
+func TestFieldValidator_RejectsBreachedPassword(t *testing.T) {
+	checker, err := validator.LoadBreachChecker(writeRangeDir(t, map[string]int{"Password1!": 120}))
+	require.NoError(t, err)
+	stage := validator.NewFieldValidator(newDefaultEngine(t), nil, &validator.PasswordChecker{Breaches: checker})
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	failure, err := stage.Validate(context.Background(), req, validator.AllFields)
+	require.NoError(t, err)
+	assert.Nil(t, failure)
+
+	req.Password, req.ConfirmPassword = "Password1!", "Password1!"
+	failure, err = stage.Validate(context.Background(), req, validator.AllFields)
+	require.NoError(t, err)
+	require.NotNil(t, failure)
+	assert.Equal(t, validator.BreachedPasswordMessage, failure.Fields["password"])
+}
@@ -0,0 +1,276 @@
+package validator_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"tyk-registration-server/internal/metrics"
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/validator"
+	"tyk-registration-server/tests/internal/testhelpers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubValidator returns a fixed outcome and counts its calls
+type stubValidator struct {
+	name    string
+	failure *validator.Failure
+	err     error
+	calls   int
+}
+
+func (v *stubValidator) Name() string { return v.name }
+
+func (v *stubValidator) Validate(ctx context.Context, req *models.RegistrationRequest, scope validator.Scope) (*validator.Failure, error) {
+	v.calls++
+	return v.failure, v.err
+}
+
+func TestPipeline_ShortCircuitStopsAtFirstFailure(t *testing.T) {
+	first := &stubValidator{name: "first", failure: &validator.Failure{Message: "first failed", Fields: map[string]string{"email": "bad"}}}
+	second := &stubValidator{name: "second", failure: &validator.Failure{Message: "second failed", Fields: map[string]string{"username": "bad"}}}
+
+	p, err := validator.NewRegistry(first, second).Pipeline(validator.PipelineConfig{
+		Stages: []string{"first", "second"},
+		Mode:   validator.ModeShortCircuit,
+	})
+	require.NoError(t, err)
+
+	failure, err := p.Run(context.Background(), &models.RegistrationRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "first failed", failure.Message)
+	assert.Equal(t, 0, second.calls)
+}
+
+func TestPipeline_CollectMergesFailures(t *testing.T) {
+	field := &stubValidator{name: "field", failure: &validator.Failure{
+		Message: "There are validation errors",
+		Fields:  map[string]string{"email": "Invalid email address"},
+	}}
+	business := &stubValidator{name: "business", failure: &validator.Failure{
+		Message:  "Business validation failed",
+		Fields:   map[string]string{"email": "Email is already registered", "username": "Username is already taken"},
+		Business: true,
+	}}
+
+	p, err := validator.NewRegistry(field, business).Pipeline(validator.PipelineConfig{
+		Stages: []string{"field", "business"},
+		Mode:   validator.ModeCollect,
+	})
+	require.NoError(t, err)
+
+	failure, err := p.Run(context.Background(), &models.RegistrationRequest{})
+	require.NoError(t, err)
+	require.NotNil(t, failure)
+	assert.False(t, failure.Business)
+	assert.Equal(t, map[string]string{
+		"email":    "Invalid email address",
+		"username": "Username is already taken",
+	}, failure.Fields)
+}
+
+func TestPipeline_OrderAndDisabledStages(t *testing.T) {
+	a := &stubValidator{name: "a"}
+	b := &stubValidator{name: "b"}
+	c := &stubValidator{name: "c"}
+
+	p, err := validator.NewRegistry(a, b, c).Pipeline(validator.PipelineConfig{Stages: []string{"c", "a"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "a"}, p.Stages())
+
+	failure, err := p.Run(context.Background(), &models.RegistrationRequest{})
+	require.NoError(t, err)
+	assert.Nil(t, failure)
+	assert.Equal(t, 0, b.calls)
+}
+
+func TestPipeline_StageErrorStopsRun(t *testing.T) {
+	broken := &stubValidator{name: "broken", err: errors.New("db down")}
+	after := &stubValidator{name: "after"}
+
+	p, err := validator.NewRegistry(broken, after).Pipeline(validator.PipelineConfig{
+		Stages: []string{"broken", "after"},
+		Mode:   validator.ModeCollect,
+	})
+	require.NoError(t, err)
+
+	_, err = p.Run(context.Background(), &models.RegistrationRequest{})
+	assert.Error(t, err)
+	assert.Equal(t, 0, after.calls)
+}
+
+func TestPipeline_InvalidConfig(t *testing.T) {
+	registry := validator.NewRegistry(&stubValidator{name: "field"})
+
+	_, err := registry.Pipeline(validator.PipelineConfig{Stages: []string{"field", "nope"}})
+	assert.Error(t, err)
+
+	_, err = registry.Pipeline(validator.PipelineConfig{Stages: []string{"field", "field"}})
+	assert.Error(t, err)
+
+	_, err = registry.Pipeline(validator.PipelineConfig{Stages: []string{"field"}, Mode: "sometimes"})
+	assert.Error(t, err)
+}
+
+func TestPipeline_RecordsStageMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	pass := &stubValidator{name: "pass"}
+	fail := &stubValidator{name: "fail", failure: &validator.Failure{Fields: map[string]string{"x": "y"}}}
+
+	p, err := validator.NewRegistry(pass, fail).Pipeline(validator.PipelineConfig{
+		Stages:  []string{"pass", "fail"},
+		Mode:    validator.ModeCollect,
+		Metrics: reg,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := p.Run(context.Background(), &models.RegistrationRequest{})
+		require.NoError(t, err)
+	}
+
+	var out strings.Builder
+	require.NoError(t, reg.Write(&out))
+	assert.Contains(t, out.String(), `registration_validation_stage_total{stage="pass",outcome="pass"} 3`)
+	assert.Contains(t, out.String(), `registration_validation_stage_total{stage="fail",outcome="fail"} 3`)
+	assert.Contains(t, out.String(), `registration_validation_stage_duration_seconds_count{stage="pass"} 3`)
+}
+
+func TestParseMode(t *testing.T) {
+	m, err := validator.ParseMode("collect")
+	require.NoError(t, err)
+	assert.Equal(t, validator.ModeCollect, m)
+
+	m, err = validator.ParseMode(" SHORT_CIRCUIT ")
+	require.NoError(t, err)
+	assert.Equal(t, validator.ModeShortCircuit, m)
+
+	_, err = validator.ParseMode("all")
+	assert.Error(t, err)
+}
+
+func TestCrossFieldValidator(t *testing.T) {
+	v := validator.NewCrossFieldValidator()
+
+	failure, err := v.Validate(context.Background(), testhelpers.CreateTestRegistrationRequest(), validator.AllFields)
+	require.NoError(t, err)
+	assert.Nil(t, failure)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	req.ConfirmPassword = "different"
+	req.Email = "john@example.com"
+	failure, err = v.Validate(context.Background(), req, validator.AllFields)
+	require.NoError(t, err)
+	require.NotNil(t, failure)
+	assert.Equal(t, "Cross-field validation failed", failure.Message)
+	assert.Contains(t, failure.Fields, "confirm_password")
+	assert.Contains(t, failure.Fields, "email")
+}
+
+type stubUsers struct {
+	emails, usernames, phones map[string]bool
+}
+
+func (s stubUsers) EmailExists(ctx context.Context, email string) (bool, error) {
+	return s.emails[email], nil
+}
+
+func (s stubUsers) UsernameExists(ctx context.Context, username string) (bool, error) {
+	return s.usernames[username], nil
+}
+
+func (s stubUsers) PhoneExists(ctx context.Context, phone string) (bool, error) {
+	return s.phones[phone], nil
+}
+
+func TestBusinessValidator(t *testing.T) {
+	req := testhelpers.CreateTestRegistrationRequest()
+	v := validator.NewBusinessValidator(stubUsers{
+		emails:    map[string]bool{req.Email: true},
+		usernames: map[string]bool{},
+		phones:    map[string]bool{*req.Phone: true},
+	})
+
+	failure, err := v.Validate(context.Background(), req, validator.AllFields)
+	require.NoError(t, err)
+	require.NotNil(t, failure)
+	assert.True(t, failure.Business)
+	assert.Equal(t, map[string]string{
+		"email": "Email is already registered",
+		"phone": "Phone number is already registered",
+	}, failure.Fields)
+}
+
+func TestStepScope(t *testing.T) {
+	scope, err := validator.StepScope(validator.StepAddress)
+	require.NoError(t, err)
+	assert.True(t, scope.Includes("country_iso"))
+	assert.False(t, scope.Includes("email"))
+
+	_, err = validator.StepScope("payment")
+	assert.Error(t, err)
+
+	assert.True(t, validator.AllFields.Includes("anything"))
+}
+
+func TestPipeline_RunScopedReportsOnlyStepFields(t *testing.T) {
+	engine, err := validator.NewRuleEngine("", models.RegistrationRequest{})
+	require.NoError(t, err)
+	p, err := validator.NewRegistry(
+		validator.NewFieldValidator(engine, nil, nil),
+		validator.NewCrossFieldValidator(),
+	).Pipeline(validator.PipelineConfig{Stages: []string{"field", "cross"}, Mode: validator.ModeCollect})
+	require.NoError(t, err)
+
+	personal, err := validator.StepScope(validator.StepPersonal)
+	require.NoError(t, err)
+
+	// Only the personal step has been filled in; later steps' required fields are not reported
+	req := &models.RegistrationRequest{FirstName: "John", LastName: "Doe", Email: "john.doe@example.us"}
+	failure, err := p.RunScoped(context.Background(), req, personal)
+	require.NoError(t, err)
+	assert.Nil(t, failure)
+
+	req.Email = "bad"
+	failure, err = p.RunScoped(context.Background(), req, personal)
+	require.NoError(t, err)
+	require.NotNil(t, failure)
+	assert.Equal(t, map[string]string{"email": "Invalid email address"}, failure.Fields)
+}
+
+func TestCrossFieldValidator_ScopedRuleNeedsAllInputs(t *testing.T) {
+	v := validator.NewCrossFieldValidator()
+	personal, _ := validator.StepScope(validator.StepPersonal)
+	address, _ := validator.StepScope(validator.StepAddress)
+
+	// The country is chosen on the next step, so the TLD rule can't run yet
+	req := &models.RegistrationRequest{Email: "john@example.com"}
+	failure, err := v.Validate(context.Background(), req, personal)
+	require.NoError(t, err)
+	assert.Nil(t, failure)
+
+	// Once the country is known the address step surfaces the mismatch
+	req.CountryISO = "US"
+	failure, err = v.Validate(context.Background(), req, address)
+	require.NoError(t, err)
+	require.NotNil(t, failure)
+	assert.Contains(t, failure.Fields, "email")
+}
+
+func TestBusinessValidator_Scoped(t *testing.T) {
+	req := testhelpers.CreateTestRegistrationRequest()
+	v := validator.NewBusinessValidator(stubUsers{
+		emails:    map[string]bool{req.Email: true},
+		usernames: map[string]bool{req.Username: true},
+	})
+
+	account, _ := validator.StepScope(validator.StepAccount)
+	failure, err := v.Validate(context.Background(), req, account)
+	require.NoError(t, err)
+	require.NotNil(t, failure)
+	assert.Equal(t, map[string]string{"username": "Username is already taken"}, failure.Fields)
+}
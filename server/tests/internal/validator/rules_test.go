@@ -0,0 +1,209 @@
+package validator_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tyk-registration-server/internal/models"
+	"tyk-registration-server/internal/validator"
+	"tyk-registration-server/tests/internal/testhelpers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDefaultEngine(t *testing.T) *validator.RuleEngine {
+	engine, err := validator.NewRuleEngine("", models.RegistrationRequest{})
+	require.NoError(t, err)
+	return engine
+}
+
+func TestRuleEngine_DefaultRulesAcceptValidRequest(t *testing.T) {
+	engine := newDefaultEngine(t)
+	assert.Empty(t, engine.Validate(testhelpers.CreateTestRegistrationRequest()))
+}
+
+// The embedded rules must keep the messages the hard-coded FieldValidator produced
+func TestRuleEngine_DefaultRulesMatchLegacyMessages(t *testing.T) {
+	engine := newDefaultEngine(t)
+
+	badPhone := "123"
+	fields := engine.Validate(&models.RegistrationRequest{
+		Email:    "not-an-email",
+		Phone:    &badPhone,
+		Username: "abc",
+		Password: "weak",
+	})
+
+	assert.Equal(t, map[string]string{
+		"first_name":     "First name is required",
+		"last_name":      "Last name is required",
+		"email":          "Invalid email address",
+		"phone":          "Invalid phone number",
+		"street":         "Street address is required",
+		"city":           "City is required",
+		"state":          "State/Province is required",
+		"country":        "Country is required",
+		"username":       "Username must be at least 6 characters",
+		"password":       "Password must be at least 8 characters",
+		"terms_accepted": "You must accept the terms and conditions",
+	}, fields)
+}
+
+func TestRuleEngine_OptionalFieldSkippedWhenEmpty(t *testing.T) {
+	engine := newDefaultEngine(t)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	req.Phone = nil
+	assert.NotContains(t, engine.Validate(req), "phone")
+
+	empty := ""
+	req.Phone = &empty
+	assert.NotContains(t, engine.Validate(req), "phone")
+}
+
+func TestParseRules_Checks(t *testing.T) {
+	rules := `
+fields:
+  username:
+    min_length: 3
+    max_length: 5
+    pattern: "^[a-z]+$"
+    messages:
+      min_length: too short
+      max_length: too long
+      pattern: lowercase only
+  country:
+    enum: [US, GB]
+  first_name:
+    required: true
+`
+	rs, err := validator.ParseRules([]byte(rules), models.RegistrationRequest{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		req  models.RegistrationRequest
+		want map[string]string
+	}{
+		{
+			name: "valid",
+			req:  models.RegistrationRequest{FirstName: "Jo", Username: "abcd", Country: "US"},
+			want: map[string]string{},
+		},
+		{
+			name: "too short",
+			req:  models.RegistrationRequest{FirstName: "Jo", Username: "ab"},
+			want: map[string]string{"username": "too short"},
+		},
+		{
+			name: "too long",
+			req:  models.RegistrationRequest{FirstName: "Jo", Username: "abcdef"},
+			want: map[string]string{"username": "too long"},
+		},
+		{
+			name: "pattern",
+			req:  models.RegistrationRequest{FirstName: "Jo", Username: "AbCd"},
+			want: map[string]string{"username": "lowercase only"},
+		},
+		{
+			name: "enum with default message",
+			req:  models.RegistrationRequest{FirstName: "Jo", Country: "FR"},
+			want: map[string]string{"country": "Must be one of: US, GB"},
+		},
+		{
+			name: "required whitespace",
+			req:  models.RegistrationRequest{FirstName: "   "},
+			want: map[string]string{"first_name": "This field is required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rs.Validate(&tt.req))
+		})
+	}
+}
+
+func TestParseRules_CheckOrderIgnoresDocumentOrder(t *testing.T) {
+	rules := `
+fields:
+  username:
+    pattern: "^[a-z]+$"
+    min_length: 3
+    messages:
+      min_length: too short
+      pattern: lowercase only
+`
+	rs, err := validator.ParseRules([]byte(rules), models.RegistrationRequest{})
+	require.NoError(t, err)
+
+	// Fails both; min_length comes first regardless of where the document lists it
+	assert.Equal(t, map[string]string{"username": "too short"},
+		rs.Validate(&models.RegistrationRequest{Username: "A"}))
+}
+
+func TestParseRules_AcceptsJSON(t *testing.T) {
+	rules := `{"fields": {"username": {"min_length": 8, "message": "Pick a longer username"}}}`
+	rs, err := validator.ParseRules([]byte(rules), models.RegistrationRequest{})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"username": "Pick a longer username"},
+		rs.Validate(&models.RegistrationRequest{Username: "short"}))
+}
+
+func TestParseRules_PasswordPolicy(t *testing.T) {
+	rules := `
+fields:
+  password:
+    password_policy: {min_length: 12, digit: true}
+`
+	rs, err := validator.ParseRules([]byte(rules), models.RegistrationRequest{})
+	require.NoError(t, err)
+
+	assert.Contains(t, rs.Validate(&models.RegistrationRequest{Password: "Test123!@#"}), "password")
+	assert.Empty(t, rs.Validate(&models.RegistrationRequest{Password: "correct horse 1"}))
+}
+
+func TestParseRules_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+	}{
+		{name: "unknown field", rules: "fields: {nickname: {required: true}}"},
+		{name: "unknown check", rules: "fields: {username: {min_len: 3}}"},
+		{name: "bad pattern", rules: "fields: {username: {pattern: '('}}"},
+		{name: "unknown format", rules: "fields: {username: {format: ipv4}}"},
+		{name: "length on bool", rules: "fields: {newsletter: {min_length: 1}}"},
+		{name: "min above max", rules: "fields: {username: {min_length: 5, max_length: 2}}"},
+		{name: "message for unknown check", rules: "fields: {username: {messages: {nope: x}}}"},
+		{name: "no fields", rules: "fields: {}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validator.ParseRules([]byte(tt.rules), models.RegistrationRequest{})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRuleEngine_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("fields: {username: {min_length: 6}}"), 0o644))
+
+	engine, err := validator.NewRuleEngine(path, models.RegistrationRequest{})
+	require.NoError(t, err)
+	req := &models.RegistrationRequest{Username: "johndoe"}
+	assert.Empty(t, engine.Validate(req))
+
+	require.NoError(t, os.WriteFile(path, []byte("fields: {username: {min_length: 10}}"), 0o644))
+	require.NoError(t, engine.Reload())
+	assert.Contains(t, engine.Validate(req), "username")
+
+	// A broken file is rejected and the previous rules stay active
+	require.NoError(t, os.WriteFile(path, []byte("fields: {username: {min_length: ["), 0o644))
+	assert.Error(t, engine.Reload())
+	assert.Contains(t, engine.Validate(req), "username")
+}
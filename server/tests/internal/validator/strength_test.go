@@ -0,0 +1,119 @@
+package validator_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/validator"
+	"tyk-registration-server/tests/internal/testhelpers"
+)
+
+func TestEstimateStrength_Scores(t *testing.T) {
+	tests := []struct {
+		password string
+		maxScore int
+		minScore int
+	}{
+		{password: "password", maxScore: 0},
+		{password: "qwertyuiop", maxScore: 0},
+		{password: "P@ssw0rd", maxScore: 0},
+		{password: "Password1!", maxScore: 1},
+		{password: "aaaaaaaaaa", maxScore: 0},
+		{password: "abcabcabcabc", maxScore: 0},
+		{password: "123456789", maxScore: 0},
+		{password: "19901205", maxScore: 1},
+		{password: "12/05/1990", maxScore: 1},
+		{password: "Test123!@#", minScore: 3, maxScore: 4},
+		{password: "correct horse battery staple", minScore: 4, maxScore: 4},
+		{password: "q8Zr!vT2pL", minScore: 3, maxScore: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			s := validator.EstimateStrength(tt.password)
+			assert.GreaterOrEqual(t, s.Score, tt.minScore, "guesses %g", s.Guesses)
+			assert.LessOrEqual(t, s.Score, tt.maxScore, "guesses %g", s.Guesses)
+		})
+	}
+}
+
+func TestEstimateStrength_Feedback(t *testing.T) {
+	tests := []struct {
+		password   string
+		warning    string
+		suggestion string
+	}{
+		{"password", "This is a top-10 common password", ""},
+		{"Password", "This is a top-10 common password", "Capitalization doesn't help very much"},
+		{"p@ssw0rd", "This is similar to a commonly used password", "Predictable substitutions like '@' instead of 'a' don't help very much"},
+		{"drowssap", "This is similar to a commonly used password", "Reversed words aren't much harder to guess"},
+		{"sdfghjkl", "Straight rows of keys are easy to guess", "Use a longer keyboard pattern with more turns"},
+		{"zzzzzzzz", `Repeats like "aaa" are easy to guess`, "Avoid repeated words and characters"},
+		{"abcdefgh", "Sequences like abc or 6543 are easy to guess", "Avoid sequences"},
+		{"1990-12-05", "Dates are often easy to guess", "Avoid dates and years that are associated with you"},
+		{"staple", "A word by itself is easy to guess", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			s := validator.EstimateStrength(tt.password)
+			assert.Equal(t, tt.warning, s.Warning)
+			assert.Contains(t, s.Suggestions, "Add another word or two. Uncommon words are better")
+			if tt.suggestion != "" {
+				assert.Contains(t, s.Suggestions, tt.suggestion)
+			}
+		})
+	}
+
+	strong := validator.EstimateStrength("correct horse battery staple")
+	assert.Empty(t, strong.Warning)
+	assert.Empty(t, strong.Suggestions)
+}
+
+func TestEstimateStrength_PenalizesUserInputs(t *testing.T) {
+	password := "fitzgeraldokonkwo"
+	without := validator.EstimateStrength(password)
+	with := validator.EstimateStrength(password, "Fitzgerald", "Okonkwo", "fokonkwo", "fitz.okonkwo@example.com")
+
+	assert.Less(t, with.Guesses, without.Guesses)
+	assert.Less(t, with.Score, without.Score)
+	assert.Equal(t, "Avoid using your name, username or email address", with.Warning)
+}
+
+func TestStrength_Message(t *testing.T) {
+	s := validator.EstimateStrength("password")
+	assert.Equal(t, "Password is too easy to guess. This is a top-10 common password. Add another word or two. Uncommon words are better.", s.Message())
+}
+
+func TestPasswordChecker_MinScore(t *testing.T) {
+	lenient := &validator.PasswordChecker{MinScore: 0}
+	msg, err := lenient.Check("password")
+	require.NoError(t, err)
+	assert.Empty(t, msg)
+
+	strict := &validator.PasswordChecker{MinScore: 4}
+	msg, err = strict.Check("Test123!@#")
+	require.NoError(t, err)
+	assert.Contains(t, msg, "Password is too easy to guess")
+}
+
+func TestFieldValidator_RejectsWeakPassword(t *testing.T) {
+	stage := validator.NewFieldValidator(newDefaultEngine(t), nil, &validator.PasswordChecker{MinScore: 3})
+
+	// The default fixture password is strong enough
+	req := testhelpers.CreateTestRegistrationRequest()
+	failure, err := stage.Validate(context.Background(), req, validator.AllFields)
+	require.NoError(t, err)
+	assert.Nil(t, failure)
+
+	// Passes the length rule, but built from the user's own name
+	req.Password = req.FirstName + req.LastName + "1"
+	req.ConfirmPassword = req.Password
+	failure, err = stage.Validate(context.Background(), req, validator.AllFields)
+	require.NoError(t, err)
+	require.NotNil(t, failure)
+	assert.Contains(t, failure.Fields["password"], "Avoid using your name, username or email address")
+}
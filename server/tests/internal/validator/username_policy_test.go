@@ -0,0 +1,127 @@
+package validator_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tyk-registration-server/internal/utils"
+	"tyk-registration-server/internal/validator"
+	"tyk-registration-server/tests/internal/testhelpers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubLookalikes treats the given usernames as registered
+type stubLookalikes map[string]string // skeleton -> canonical
+
+func newStubLookalikes(usernames ...string) stubLookalikes {
+	s := stubLookalikes{}
+	for _, u := range usernames {
+		s[utils.UsernameSkeleton(u)] = utils.CanonicalUsername(u)
+	}
+	return s
+}
+
+func (s stubLookalikes) UsernameLookalikeExists(ctx context.Context, username string) (bool, error) {
+	canonical, ok := s[utils.UsernameSkeleton(username)]
+	return ok && canonical != utils.CanonicalUsername(username), nil
+}
+
+func newDefaultPolicy(t *testing.T, lookalikes validator.LookalikeChecker) *validator.UsernamePolicy {
+	profanity, err := validator.LoadProfanityList("")
+	require.NoError(t, err)
+	return validator.NewUsernamePolicy(profanity, lookalikes)
+}
+
+func TestUsernamePolicy_Charset(t *testing.T) {
+	policy := newDefaultPolicy(t, nil)
+
+	for _, ok := range []string{"johndoe", "john.doe", "john_doe-1", "жанна", "josé123"} {
+		assert.Empty(t, policy.Violation(ok), ok)
+	}
+	for _, bad := range []string{"john doe", "john@doe", "_johndoe", ".johndoe", "john!", "johndoe😀"} {
+		assert.Equal(t, validator.UsernameCharsetMessage, policy.Violation(bad), bad)
+	}
+}
+
+func TestUsernamePolicy_Reserved(t *testing.T) {
+	policy := newDefaultPolicy(t, nil)
+
+	// Case, separators, digit swaps and lookalike letters don't dodge the list
+	for _, name := range []string{"administrator", "Administrator", "admin.istrator", "adm1nistrator", "аdministrator", "support"} {
+		assert.Equal(t, validator.UsernameReservedMessage, policy.Violation(name), name)
+	}
+	assert.Empty(t, policy.Violation("supporter"))
+}
+
+func TestUsernamePolicy_Profanity(t *testing.T) {
+	policy := newDefaultPolicy(t, nil)
+
+	for _, name := range []string{"bullshitter", "5h1tposter", "sh.i.t_lord", "john.fuck", "f-u-c-k"} {
+		assert.Equal(t, validator.UsernameProfaneMessage, policy.Violation(name), name)
+	}
+	// A listed word inside a longer word is not a match
+	for _, name := range []string{"shiitake", "Scunthorpe", "scunthorpe_fc"} {
+		assert.Empty(t, policy.Violation(name), name)
+	}
+}
+
+func TestUsernamePolicy_CustomProfanityList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profanity.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# custom\nbanana\n\n"), 0o600))
+
+	words, err := validator.LoadProfanityList(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"banana"}, words)
+
+	policy := validator.NewUsernamePolicy(words, nil)
+	assert.Equal(t, validator.UsernameProfaneMessage, policy.Violation("bananaman"))
+	// The default list no longer applies
+	assert.Empty(t, policy.Violation("bullshitter"))
+
+	_, err = validator.LoadProfanityList(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
+
+func TestUsernamePolicy_Lookalikes(t *testing.T) {
+	policy := newDefaultPolicy(t, newStubLookalikes("paypal_fan"))
+	ctx := context.Background()
+
+	// Cyrillic "а" in place of Latin "a"
+	msg, err := policy.Check(ctx, "pаypal_fan")
+	require.NoError(t, err)
+	assert.Equal(t, validator.UsernameLookalikeMessage, msg)
+
+	// "rn" for "m" and "0" for "o"
+	policy = newDefaultPolicy(t, newStubLookalikes("moonwalker"))
+	msg, err = policy.Check(ctx, "rn00nwalker")
+	require.NoError(t, err)
+	assert.Equal(t, validator.UsernameLookalikeMessage, msg)
+
+	// The registered name itself is a duplicate, which the business stage reports
+	msg, err = policy.Check(ctx, "moonwalker")
+	require.NoError(t, err)
+	assert.Empty(t, msg)
+}
+
+func TestFieldValidator_EnforcesUsernamePolicy(t *testing.T) {
+	engine := newDefaultEngine(t)
+	stage := validator.NewFieldValidator(engine, newDefaultPolicy(t, nil), nil)
+
+	req := testhelpers.CreateTestRegistrationRequest()
+	req.Username = "administrator"
+	failure, err := stage.Validate(context.Background(), req, validator.AllFields)
+	require.NoError(t, err)
+	require.NotNil(t, failure)
+	assert.Equal(t, validator.UsernameReservedMessage, failure.Fields["username"])
+
+	// Out of scope, the policy isn't consulted
+	scope, err := validator.StepScope(validator.StepPersonal)
+	require.NoError(t, err)
+	failure, err = stage.Validate(context.Background(), req, scope)
+	require.NoError(t, err)
+	assert.Nil(t, failure)
+}
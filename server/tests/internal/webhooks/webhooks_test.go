@@ -0,0 +1,109 @@
+package webhooks_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tyk-registration-server/internal/webhooks"
+)
+
+const secret = "whsec_test"
+
+func TestSignVerify_RoundTrip(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"type":"user.registered"}`)
+	sig := webhooks.Sign(secret, now, body)
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	assert.NoError(t, webhooks.Verify(secret, sig, ts, body, now, 5*time.Minute))
+}
+
+func TestVerify_RejectsTamperedBody(t *testing.T) {
+	now := time.Now()
+	sig := webhooks.Sign(secret, now, []byte(`{"newsletter":false}`))
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	err := webhooks.Verify(secret, sig, ts, []byte(`{"newsletter":true}`), now, 5*time.Minute)
+	assert.ErrorIs(t, err, webhooks.ErrInvalidSignature)
+}
+
+func TestVerify_RejectsWrongSecret(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{}`)
+	sig := webhooks.Sign("other-secret", now, body)
+
+	err := webhooks.Verify(secret, sig, strconv.FormatInt(now.Unix(), 10), body, now, 5*time.Minute)
+	assert.ErrorIs(t, err, webhooks.ErrInvalidSignature)
+}
+
+func TestVerify_RejectsReplayedTimestamp(t *testing.T) {
+	sent := time.Now().Add(-10 * time.Minute)
+	body := []byte(`{}`)
+	sig := webhooks.Sign(secret, sent, body)
+
+	err := webhooks.Verify(secret, sig, strconv.FormatInt(sent.Unix(), 10), body, time.Now(), 5*time.Minute)
+	assert.ErrorIs(t, err, webhooks.ErrStaleTimestamp)
+}
+
+func TestVerify_TimestampIsSigned(t *testing.T) {
+	sent := time.Now().Add(-10 * time.Minute)
+	body := []byte(`{}`)
+	sig := webhooks.Sign(secret, sent, body)
+
+	// Swapping in a fresh timestamp must not revalidate an old signature
+	err := webhooks.Verify(secret, sig, strconv.FormatInt(time.Now().Unix(), 10), body, time.Now(), 5*time.Minute)
+	assert.ErrorIs(t, err, webhooks.ErrInvalidSignature)
+}
+
+func TestSender_SendsSignedRequest(t *testing.T) {
+	var got *http.Request
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	body := []byte(`{"id":"evt"}`)
+	status, err := webhooks.NewSender(time.Second).Send(context.Background(), srv.URL, secret, "evt-1", body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	require.NotNil(t, got)
+	assert.Equal(t, http.MethodPost, got.Method)
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, "evt-1", got.Header.Get(webhooks.HeaderID))
+	assert.Equal(t, body, gotBody)
+	assert.NoError(t, webhooks.Verify(secret, got.Header.Get(webhooks.HeaderSignature),
+		got.Header.Get(webhooks.HeaderTimestamp), gotBody, time.Now(), time.Minute))
+}
+
+func TestSender_Non2xxIsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	status, err := webhooks.NewSender(time.Second).Send(context.Background(), srv.URL, secret, "evt-1", []byte(`{}`))
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+}
+
+func TestSender_UnreachableEndpoint(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := srv.URL
+	srv.Close()
+
+	status, err := webhooks.NewSender(time.Second).Send(context.Background(), url, secret, "evt-1", []byte(`{}`))
+	assert.Error(t, err)
+	assert.Equal(t, 0, status)
+}